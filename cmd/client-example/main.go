@@ -64,34 +64,34 @@ func main() {
 
 	// Display license information
 	fmt.Println("\n📋 License Information:")
-	fmt.Printf("   License ID: %s\n", license.ID)
-	fmt.Printf("   Customer: %s\n", license.CustomerID)
-	fmt.Printf("   Product: %s\n", license.ProductID)
-	fmt.Printf("   Serial Number: %s\n", license.SerialNumber)
-	fmt.Printf("   Issued: %s\n", license.IssueDate.Format(time.RFC3339))
-	fmt.Printf("   Expires: %s\n", license.ExpiryDate.Format(time.RFC3339))
+	fmt.Printf("   License ID: %s\n", license.ID())
+	fmt.Printf("   Customer: %s\n", license.CustomerID())
+	fmt.Printf("   Product: %s\n", license.ProductID())
+	fmt.Printf("   Serial Number: %s\n", license.SerialNumber())
+	fmt.Printf("   Issued: %s\n", license.IssueDate().Format(time.RFC3339))
+	fmt.Printf("   Expires: %s\n", license.ExpiryDate().Format(time.RFC3339))
 
 	// Calculate days remaining
-	daysRemaining := int(time.Until(license.ExpiryDate).Hours() / 24)
+	daysRemaining := int(time.Until(license.ExpiryDate()).Hours() / 24)
 	if daysRemaining > 0 {
 		fmt.Printf("   Status: Active (%d days remaining)\n", daysRemaining)
 	} else {
 		fmt.Printf("   Status: Expired\n")
 	}
 
-	fmt.Printf("   Features: %v\n", license.Features)
+	fmt.Printf("   Features: %v\n", license.Features())
 
 	// Show hardware binding information if verbose
 	if *verbose {
 		fmt.Println("\n🖥️  Hardware Binding Information:")
-		if len(license.HardwareIDs.MACAddresses) > 0 {
-			fmt.Printf("   MAC Addresses: %v\n", license.HardwareIDs.MACAddresses)
+		if len(license.HardwareIDs().MACAddresses) > 0 {
+			fmt.Printf("   MAC Addresses: %v\n", license.HardwareIDs().MACAddresses)
 		}
-		if len(license.HardwareIDs.DiskIDs) > 0 {
-			fmt.Printf("   Disk IDs: %v\n", license.HardwareIDs.DiskIDs)
+		if len(license.HardwareIDs().DiskIDs) > 0 {
+			fmt.Printf("   Disk IDs: %v\n", license.HardwareIDs().DiskIDs)
 		}
-		if len(license.HardwareIDs.HostNames) > 0 {
-			fmt.Printf("   Hostnames: %v\n", license.HardwareIDs.HostNames)
+		if len(license.HardwareIDs().HostNames) > 0 {
+			fmt.Printf("   Hostnames: %v\n", license.HardwareIDs().HostNames)
 		}
 
 		// Get current hardware info for debugging
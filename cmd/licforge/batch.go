@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+)
+
+// runBatchGeneration generates one license per manifest row and writes a report
+func runBatchGeneration(manifestPath, outputDir, privateKeyPath, schemeName string, workers int, ids *identityGenerator, ledgerPath string) {
+	fmt.Printf("📦 Batch license generation from: %s\n", manifestPath)
+
+	// Parse signature scheme
+	scheme, err := licformat.ParseSignatureScheme(schemeName)
+	if err != nil {
+		fmt.Printf("❌ Invalid signature scheme: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Read private key
+	privateKeyPEM, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		fmt.Printf("❌ Failed to read private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Parse private key
+	privateKey, err := licgen.ParsePrivateKey(string(privateKeyPEM))
+	if err != nil {
+		fmt.Printf("❌ Failed to parse private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Read manifest
+	orders, err := licgen.ReadManifest(manifestPath)
+	if err != nil {
+		fmt.Printf("❌ Failed to read manifest: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("📋 %d orders found\n", len(orders))
+
+	// Create output directory if it doesn't exist
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		fmt.Printf("❌ Failed to create output directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Generate and sign licenses concurrently
+	generator := &licgen.BatchGenerator{
+		OutputDir:    outputDir,
+		PrivateKey:   privateKey,
+		Workers:      workers,
+		FillIdentity: ids.fill,
+		Options:      append(ledgerOptions(ledgerPath), licgen.WithSignatureScheme(scheme)),
+	}
+	report := generator.Generate(orders)
+	report.Manifest = manifestPath
+
+	reportPath := filepath.Join(outputDir, "batch-report.json")
+	reportData, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Printf("❌ Failed to encode report: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(reportPath, reportData, 0644); err != nil {
+		fmt.Printf("❌ Failed to save report: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Print summary
+	fmt.Println("\n📊 Batch Summary:")
+	fmt.Printf("   Generated: %d\n", report.Generated)
+	fmt.Printf("   Failed: %d\n", report.Failed)
+	for _, result := range report.Results {
+		if result.Error != "" {
+			fmt.Printf("   ❌ Row %d (%s): %s\n", result.Row, result.ID, result.Error)
+		}
+	}
+	fmt.Printf("   Report: %s\n", reportPath)
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("\n✨ Batch completed successfully!")
+}
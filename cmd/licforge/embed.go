@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// embedPublicKey writes a Go source file embedding the public key as
+// shares, for use with go generate, and prints the key pin
+func embedPublicKey(publicKeyPath, outputPath, packageName, varName string, shares int) {
+	fmt.Println("🔑 Embedding public key...")
+
+	publicKeyPEM, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		fmt.Printf("❌ Failed to read public key: %v\n", err)
+		os.Exit(1)
+	}
+
+	key, err := licverify.NewEmbeddedKey(string(publicKeyPEM), shares)
+	if err != nil {
+		fmt.Printf("❌ Failed to embed public key: %v\n", err)
+		os.Exit(1)
+	}
+	pin, err := licverify.PublicKeyPin(string(publicKeyPEM))
+	if err != nil {
+		fmt.Printf("❌ Failed to compute key pin: %v\n", err)
+		os.Exit(1)
+	}
+	source, err := licgen.EmbeddedKeySource(key, packageName, varName)
+	if err != nil {
+		fmt.Printf("❌ Failed to generate source: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(outputPath, source, 0644); err != nil {
+		fmt.Printf("❌ Failed to write source file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ Embedded key written to: %s\n", outputPath)
+	fmt.Printf("📌 Key pin: %s\n", pin)
+	fmt.Printf("   Keep the pin in your own code, not in %s, and create the verifier with\n", outputPath)
+	fmt.Printf("   licverify.NewVerifierFromEmbedded(%s, pin)\n", varName)
+}
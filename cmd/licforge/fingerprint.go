@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// exportFingerprint writes the hardware identifiers of this machine to a
+// file, for the vendor to bind a license to with genlicense -fingerprint
+func exportFingerprint(formatName, publicKeyPath, outputPath string) {
+	fmt.Println("💻 Collecting hardware identifiers...")
+
+	var format licverify.FingerprintFormat
+	switch formatName {
+	case "json":
+		format = licverify.FingerprintJSON
+	case "compact":
+		format = licverify.FingerprintCompact
+	default:
+		fmt.Printf("❌ Invalid fingerprint format %q (json or compact)\n", formatName)
+		os.Exit(1)
+	}
+	if outputPath == "" {
+		outputPath = "fingerprint." + formatName
+	}
+
+	// Compact codes carry a checksum keyed with the product's public key
+	var verifier *licverify.Verifier
+	if format == licverify.FingerprintCompact || publicKeyPath != "" {
+		if publicKeyPath == "" {
+			publicKeyPath = "keys/public.pem"
+		}
+		publicKeyPEM, err := os.ReadFile(publicKeyPath)
+		if err != nil {
+			fmt.Printf("❌ Failed to read public key: %v\n", err)
+			os.Exit(1)
+		}
+		verifier, err = licverify.NewVerifier(string(publicKeyPEM))
+		if err != nil {
+			fmt.Printf("❌ Failed to create verifier: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	data, err := licverify.ExportFingerprint(verifier, format)
+	if err != nil {
+		fmt.Printf("❌ Failed to export fingerprint: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		fmt.Printf("❌ Failed to write fingerprint: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ Fingerprint written to: %s\n", outputPath)
+	if format == licverify.FingerprintCompact {
+		fmt.Printf("\n📋 Fingerprint code (with a checksum against typos, not signed):\n%s", data)
+	}
+	fmt.Println("\n📨 Send the fingerprint to your vendor to receive a license bound to this machine")
+}
+
+// readFingerprint reads a fingerprint exported by the fingerprint command
+func readFingerprint(path string, verifier *licverify.Verifier) *licverify.Fingerprint {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("❌ Failed to read fingerprint: %v\n", err)
+		os.Exit(1)
+	}
+	fingerprint, err := licverify.ParseFingerprint(verifier, data)
+	if err != nil {
+		fmt.Printf("❌ Failed to parse fingerprint: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("⚠️ Fingerprints are not signed; confirm the identifiers with the customer")
+	return fingerprint
+}
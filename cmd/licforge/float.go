@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licfloat"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// serveSeats runs a floating license seat server until interrupted
+func serveSeats(addr, licenseFile, publicKeyPath, seatKeyPath, statePath string, leaseDuration time.Duration) {
+	fmt.Println("🎫 Starting floating license seat server...")
+
+	// Read the vendor public key that signed the pool license
+	publicKeyPEM, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		fmt.Printf("❌ Failed to read public key: %v\n", err)
+		os.Exit(1)
+	}
+	verifier, err := licverify.NewVerifier(string(publicKeyPEM))
+	if err != nil {
+		fmt.Printf("❌ Failed to create verifier: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Read the seat server key that signs seat leases
+	seatKeyPEM, err := os.ReadFile(seatKeyPath)
+	if err != nil {
+		fmt.Printf("❌ Failed to read seat server key: %v\n", err)
+		os.Exit(1)
+	}
+	seatKey, err := licgen.ParsePrivateKey(string(seatKeyPEM))
+	if err != nil {
+		fmt.Printf("❌ Failed to parse seat server key: %v\n", err)
+		os.Exit(1)
+	}
+
+	licenseData, err := os.ReadFile(licenseFile)
+	if err != nil {
+		fmt.Printf("❌ Failed to read license file: %v\n", err)
+		os.Exit(1)
+	}
+
+	if statePath == "" {
+		statePath = strings.TrimSuffix(licenseFile, filepath.Ext(licenseFile)) + ".seats.json"
+	}
+	server, err := licfloat.New(licenseData, verifier, seatKey,
+		licfloat.WithLeaseDuration(leaseDuration), licfloat.WithStateFile(statePath))
+	if err != nil {
+		fmt.Printf("❌ Failed to create seat server: %v\n", err)
+		os.Exit(1)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		server.Close()
+		fmt.Printf("❌ Failed to listen: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	license := server.License()
+	fmt.Printf("📃 Pool license: %s (%s, %s)\n", license.ID(), license.CustomerID(), license.ProductID())
+	fmt.Printf("💺 Seats: %d, leases valid for %s, %d in use\n", license.Seats(), leaseDuration, len(server.Seats()))
+	fmt.Printf("💾 Seat state: %s\n", statePath)
+	fmt.Printf("✅ Listening on %s\n", ln.Addr())
+	err = server.Serve(ctx, ln)
+	server.Close()
+	if err != nil {
+		fmt.Printf("❌ Seat server failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("👋 Seat server stopped")
+}
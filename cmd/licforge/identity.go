@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+)
+
+// identityGenerator fills in missing license IDs and serial numbers
+type identityGenerator struct {
+	autoID   bool
+	idFormat licgen.IDFormat
+	serials  *licgen.SerialGenerator // nil when no serial scheme is configured
+}
+
+// newIdentityGenerator parses the -auto-id, -id-format, -serial-scheme and
+// -serial-counter settings
+func newIdentityGenerator(autoID bool, idFormatName, serialSchemeSpec, counterPath string) (*identityGenerator, error) {
+	idFormat, err := licgen.ParseIDFormat(idFormatName)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := &identityGenerator{autoID: autoID, idFormat: idFormat}
+	if serialSchemeSpec != "" {
+		scheme, err := licgen.ParseSerialScheme(serialSchemeSpec)
+		if err != nil {
+			return nil, err
+		}
+		ids.serials = licgen.NewSerialGenerator(scheme, licgen.NewFileCounter(counterPath))
+	}
+	return ids, nil
+}
+
+// fill generates the license ID and serial number if they are empty and
+// generation is enabled
+func (g *identityGenerator) fill(licenseID, serialNumber *string) error {
+	if *licenseID == "" && g.autoID {
+		id, err := licgen.GenerateID(g.idFormat)
+		if err != nil {
+			return fmt.Errorf("failed to generate license ID: %v", err)
+		}
+		*licenseID = id
+	}
+	if *serialNumber == "" && g.serials != nil {
+		serial, err := g.serials.Next()
+		if err != nil {
+			return fmt.Errorf("failed to generate serial number: %v", err)
+		}
+		*serialNumber = serial
+	}
+	return nil
+}
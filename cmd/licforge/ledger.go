@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licledger"
+)
+
+// defaultLedgerPath is the issuance ledger read by list, show and serve when
+// -ledger is not given. Generating commands only record licenses in a
+// ledger given with -ledger.
+const defaultLedgerPath = "ledger.jsonl"
+
+// ledgerOptions returns the generation options recording licenses in the
+// ledger at path, or none if path is empty
+func ledgerOptions(path string) []licgen.Option {
+	if path == "" {
+		return nil
+	}
+	return []licgen.Option{licgen.WithRecorder(licledger.Open(path))}
+}
+
+// parseLedgerQuery builds a ledger query from the list command flags
+func parseLedgerQuery(customerID, productID, expiresAfter, expiresBefore string, expiringWithin int) (licledger.Query, error) {
+	query := licledger.Query{CustomerID: customerID, ProductID: productID}
+
+	var err error
+	if expiresAfter != "" {
+		if query.ExpiresAfter, err = time.ParseInLocation(time.DateOnly, expiresAfter, time.Local); err != nil {
+			return query, fmt.Errorf("invalid -expires-after date: %v", err)
+		}
+	}
+	if expiresBefore != "" {
+		if query.ExpiresBefore, err = time.ParseInLocation(time.DateOnly, expiresBefore, time.Local); err != nil {
+			return query, fmt.Errorf("invalid -expires-before date: %v", err)
+		}
+	}
+	if expiringWithin > 0 {
+		deadline := time.Now().AddDate(0, 0, expiringWithin)
+		if query.ExpiresBefore.IsZero() || deadline.Before(query.ExpiresBefore) {
+			query.ExpiresBefore = deadline
+		}
+		if query.ExpiresAfter.IsZero() {
+			query.ExpiresAfter = time.Now()
+		}
+	}
+	return query, nil
+}
+
+// listLicenses prints the issued licenses matching the query
+func listLicenses(ledgerPath string, query licledger.Query) {
+	entries, err := licledger.Open(ledgerPath).Search(query)
+	if err != nil {
+		fmt.Printf("❌ Failed to read ledger: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("📒 Ledger: %s\n", ledgerPath)
+	if len(entries) == 0 {
+		fmt.Println("No matching licenses found")
+		return
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCUSTOMER\tPRODUCT\tSERIAL\tISSUED\tEXPIRES\tSTATUS")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.ID,
+			entry.CustomerID,
+			entry.ProductID,
+			entry.SerialNumber,
+			entry.IssueDate.Local().Format(time.DateOnly),
+			entry.ExpiryDate.Local().Format(time.DateOnly),
+			entryStatus(entry),
+		)
+	}
+	w.Flush()
+	fmt.Printf("\nTotal: %d\n", len(entries))
+}
+
+// showLicense prints an issued license and optionally exports its file
+func showLicense(ledgerPath, id, exportPath string) {
+	entry, err := licledger.Open(ledgerPath).Get(id)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("📃 License Information:")
+	fmt.Printf("   License ID: %s\n", entry.ID)
+	if entry.PreviousID != "" {
+		fmt.Printf("   Previous License: %s\n", entry.PreviousID)
+	}
+	fmt.Printf("   Customer ID: %s\n", entry.CustomerID)
+	fmt.Printf("   Product ID: %s\n", entry.ProductID)
+	fmt.Printf("   Serial Number: %s\n", entry.SerialNumber)
+	fmt.Printf("   Issue Date: %s\n", entry.IssueDate.Format(time.RFC3339))
+	fmt.Printf("   Expiry Date: %s\n", entry.ExpiryDate.Format(time.RFC3339))
+	fmt.Printf("   Status: %s\n", entryStatus(entry))
+	if entry.Revoked() {
+		fmt.Printf("   Revoked: %s", entry.Revocation.Time.Format(time.RFC3339))
+		if entry.Revocation.Reason != "" {
+			fmt.Printf(" (%s)", entry.Revocation.Reason)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("   Features: %v\n", entry.Features)
+	if entry.Seats > 0 {
+		fmt.Printf("   Floating Seats: %d\n", entry.Seats)
+		fmt.Printf("   Seat Server Key: %x\n", entry.SeatServerKey)
+	}
+	if entry.TrialDuration > 0 {
+		fmt.Printf("   Trial: %s from first run\n", formatTrialDuration(entry.TrialDuration))
+	}
+	if len(entry.Quotas) > 0 {
+		fmt.Printf("   Usage Quotas: %s\n", formatQuotas(entry.Quotas))
+	}
+	if len(entry.HardwareIDs.MACAddresses) > 0 {
+		fmt.Printf("   MAC Addresses: %v\n", entry.HardwareIDs.MACAddresses)
+	}
+	if len(entry.HardwareIDs.DiskIDs) > 0 {
+		fmt.Printf("   Disk IDs: %v\n", entry.HardwareIDs.DiskIDs)
+	}
+	if len(entry.HardwareIDs.HostNames) > 0 {
+		fmt.Printf("   Hostnames: %v\n", entry.HardwareIDs.HostNames)
+	}
+	if len(entry.HardwareIDs.CustomIDs) > 0 {
+		fmt.Printf("   Custom IDs: %v\n", entry.HardwareIDs.CustomIDs)
+	}
+	if len(entry.HardwareIDs.ContainerIDs) > 0 {
+		fmt.Printf("   Container IDs: %v\n", entry.HardwareIDs.ContainerIDs)
+	}
+	fmt.Printf("   Signature Scheme: %s\n", entry.Scheme)
+	fmt.Printf("   Encrypted: %v\n", entry.Encrypted)
+
+	if exportPath != "" {
+		if err := licgen.SaveLicenseToFile(entry.Data, exportPath); err != nil {
+			fmt.Printf("❌ Failed to export license: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n💾 License file exported to: %s\n", exportPath)
+	}
+}
+
+// entryStatus describes whether a ledger entry is active, expired or revoked
+func entryStatus(entry *licledger.Entry) string {
+	if entry.Revoked() {
+		return "Revoked"
+	}
+	daysRemaining := int(time.Until(entry.ExpiryDate).Hours() / 24)
+	if daysRemaining > 0 {
+		return fmt.Sprintf("Active (%d days remaining)", daysRemaining)
+	}
+	return fmt.Sprintf("Expired (%d days ago)", -daysRemaining)
+}
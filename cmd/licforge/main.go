@@ -2,11 +2,14 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"crypto/rsa"
+	"errors"
 	"flag"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -24,6 +27,7 @@ func main() {
 	keygenKeyDir := keygenCmd.String("dir", "keys", "Directory to store keys")
 	keygenKeySize := keygenCmd.Int("size", 2048, "RSA key size (2048, 3072, or 4096)")
 	keygenForce := keygenCmd.Bool("force", false, "Force overwrite of existing keys")
+	keygenEncryption := keygenCmd.Bool("encryption", false, "Generate an X25519 key pair for license encryption instead")
 
 	genlicenseCmd := flag.NewFlagSet("genlicense", flag.ExitOnError)
 	genlicenseID := genlicenseCmd.String("id", "", "License ID")
@@ -40,10 +44,131 @@ func main() {
 	// Format flag removed in v2.0.0 - binary format is now the only option
 	genlicenseAutoHardware := genlicenseCmd.Bool("auto-hardware", false, "Automatically use current hardware information")
 	genlicenseInteractive := genlicenseCmd.Bool("interactive", false, "Interactive mode")
+	genlicenseScheme := genlicenseCmd.String("scheme", "pkcs1v15", "Signature scheme (pkcs1v15 or pss)")
+	genlicenseEncryptKey := genlicenseCmd.String("encrypt-key", "", "Path to X25519 public key to encrypt the license contents to")
+	genlicenseMachineBound := genlicenseCmd.Bool("machine-bound", false, "Encrypt the license contents to the hardware fingerprint (requires -auto-hardware)")
+	genlicenseAutoID := genlicenseCmd.Bool("auto-id", false, "Generate the license ID when -id is not given")
+	genlicenseIDFormat := genlicenseCmd.String("id-format", "uuidv7", "Format of generated license IDs (uuidv7 or ulid)")
+	genlicenseSerialScheme := genlicenseCmd.String("serial-scheme", "", "Generate the serial number when -serial is not given, e.g. prefix=SN-,width=6,check=luhn")
+	genlicenseSerialCounter := genlicenseCmd.String("serial-counter", "serial-counter.json", "File holding the serial number sequence")
+	genlicenseLedger := genlicenseCmd.String("ledger", "", "Issuance ledger to record the license in (not recorded by default)")
+	genlicenseSeats := genlicenseCmd.Uint("seats", 0, "Make a floating license for this many concurrent users (served by licforge float)")
+	genlicenseSeatServerKey := genlicenseCmd.String("seat-server-key", "", "Public key of the seat server allowed to serve a floating license (required with -seats)")
+	genlicenseQuotas := genlicenseCmd.String("quotas", "", "Comma-separated usage quotas of metered features, e.g. runs=100,documents=5000")
+	genlicenseContainerIDs := genlicenseCmd.String("container-ids", "", "Comma-separated list of container IDs, e.g. k8s-cluster:<uid>,k8s-namespace:<uid>")
+	genlicenseIdentityFile := genlicenseCmd.String("identity-file", "", "Bind the license to containers that mount this identity file")
+	genlicenseFingerprint := genlicenseCmd.String("fingerprint", "", "Bind the license to the machine described by a fingerprint file from licforge fingerprint")
+
+	embedKeyCmd := flag.NewFlagSet("embed-key", flag.ExitOnError)
+	embedKeyPublicKey := embedKeyCmd.String("key", "keys/public.pem", "Path to public key")
+	embedKeyOutput := embedKeyCmd.String("output", "license_key.go", "Output Go source file")
+	embedKeyPackage := embedKeyCmd.String("package", "main", "Package name of the generated file")
+	embedKeyVar := embedKeyCmd.String("var", "licenseKey", "Name of the generated variable")
+	embedKeyShares := embedKeyCmd.Int("shares", 3, "Number of shares the key is split into (at least 2)")
+
+	fingerprintCmd := flag.NewFlagSet("fingerprint", flag.ExitOnError)
+	fingerprintFormat := fingerprintCmd.String("format", "json", "Fingerprint format (json, or compact: a single line code with a checksum, which is not a signature)")
+	fingerprintPublicKey := fingerprintCmd.String("key", "", "Path to the product's public key, which keys the checksum of compact codes (default: keys/public.pem)")
+	fingerprintOutput := fingerprintCmd.String("output", "", "Output file (default: fingerprint.json or fingerprint.compact)")
+
+	trialCmd := flag.NewFlagSet("trial", flag.ExitOnError)
+	trialID := trialCmd.String("id", "", "License ID (default: generated UUIDv7)")
+	trialProductID := trialCmd.String("product", "", "Product ID")
+	trialDays := trialCmd.Int("trial-days", 30, "Length of the trial, counted from the first run")
+	trialAvailableDays := trialCmd.Int("days", 365, "Days after which the trial license expires, even if never run")
+	trialFeatures := trialCmd.String("features", "basic", "Comma-separated list of features")
+	trialPrivateKey := trialCmd.String("key", "keys/private.pem", "Path to private key")
+	trialOutput := trialCmd.String("output", "trial.lic", "Output license file")
+	trialScheme := trialCmd.String("scheme", "pkcs1v15", "Signature scheme (pkcs1v15 or pss)")
+	trialLedger := trialCmd.String("ledger", "", "Issuance ledger to record the license in (not recorded by default)")
+
+	migrateCmd := flag.NewFlagSet("migrate", flag.ExitOnError)
+	migrateLicenseFile := migrateCmd.String("license", "license.lic", "Legacy JSON license file")
+	migratePrivateKey := migrateCmd.String("key", "keys/private.pem", "Path to private key")
+	migrateOutput := migrateCmd.String("output", "", "Output license file (default: the input file with a .migrated.lic extension)")
+	migrateScheme := migrateCmd.String("scheme", "pkcs1v15", "Signature scheme (pkcs1v15 or pss)")
+	migrateLedger := migrateCmd.String("ledger", "", "Issuance ledger to record the license in (not recorded by default)")
+
+	batchCmd := flag.NewFlagSet("batch", flag.ExitOnError)
+	batchManifest := batchCmd.String("manifest", "", "CSV or JSON manifest of licenses to generate")
+	batchOutput := batchCmd.String("out", "licenses", "Output directory for license files and the report")
+	batchPrivateKey := batchCmd.String("key", "keys/private.pem", "Path to private key")
+	batchScheme := batchCmd.String("scheme", "pkcs1v15", "Signature scheme (pkcs1v15 or pss)")
+	batchWorkers := batchCmd.Int("workers", 0, "Number of concurrent workers (default: number of CPUs)")
+	batchAutoID := batchCmd.Bool("auto-id", false, "Generate license IDs for rows without an id")
+	batchIDFormat := batchCmd.String("id-format", "uuidv7", "Format of generated license IDs (uuidv7 or ulid)")
+	batchSerialScheme := batchCmd.String("serial-scheme", "", "Generate serial numbers for rows without a serial, e.g. prefix=SN-,width=6,check=luhn")
+	batchSerialCounter := batchCmd.String("serial-counter", "serial-counter.json", "File holding the serial number sequence")
+	batchLedger := batchCmd.String("ledger", "", "Issuance ledger to record the licenses in (not recorded by default)")
+
+	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
+	listLedger := listCmd.String("ledger", defaultLedgerPath, "Issuance ledger")
+	listCustomer := listCmd.String("customer", "", "Only licenses for this customer")
+	listProduct := listCmd.String("product", "", "Only licenses for this product")
+	listExpiresAfter := listCmd.String("expires-after", "", "Only licenses expiring on or after this date (YYYY-MM-DD)")
+	listExpiresBefore := listCmd.String("expires-before", "", "Only licenses expiring before this date (YYYY-MM-DD)")
+	listExpiringWithin := listCmd.Int("expiring-within", 0, "Only licenses expiring within this many days")
+
+	showCmd := flag.NewFlagSet("show", flag.ExitOnError)
+	showLedger := showCmd.String("ledger", defaultLedgerPath, "Issuance ledger")
+	showExport := showCmd.String("export", "", "Write the recorded license file to this path")
+
+	renewCmd := flag.NewFlagSet("renew", flag.ExitOnError)
+	renewLicenseFile := renewCmd.String("license", "license.lic", "License file to renew")
+	renewID := renewCmd.String("id", "", "ID of the renewed license (default: generated UUIDv7)")
+	renewValidDays := renewCmd.Int("days", 365, "Days added to the current expiry date, or to today if expired")
+	renewPrivateKey := renewCmd.String("key", "keys/private.pem", "Path to private key")
+	renewOutput := renewCmd.String("output", "", "Output license file (default: the input file with a .renewed.lic extension)")
+	renewScheme := renewCmd.String("scheme", "", "Signature scheme (default: keep the scheme of the license)")
+	renewLedger := renewCmd.String("ledger", "", "Issuance ledger to record the license in (not recorded by default)")
+	renewDecryptKey := renewCmd.String("decrypt-key", "", "Path to X25519 private key of an encrypted license, which stays encrypted")
+	renewFingerprint := renewCmd.String("fingerprint", "", "Fingerprint file of the machine a machine-bound license is bound to")
+
+	modifyCmd := flag.NewFlagSet("modify", flag.ExitOnError)
+	modifyLicenseFile := modifyCmd.String("license", "license.lic", "License file to modify")
+	modifyID := modifyCmd.String("id", "", "ID of the modified license (default: generated UUIDv7)")
+	modifyAddFeatures := modifyCmd.String("add-feature", "", "Comma-separated list of features to add")
+	modifyRemoveFeatures := modifyCmd.String("remove-feature", "", "Comma-separated list of features to remove")
+	modifyAddMACs := modifyCmd.String("add-mac", "", "Comma-separated list of MAC addresses to add")
+	modifyRemoveMACs := modifyCmd.String("remove-mac", "", "Comma-separated list of MAC addresses to remove")
+	modifyAddDiskIDs := modifyCmd.String("add-diskid", "", "Comma-separated list of disk IDs to add")
+	modifyRemoveDiskIDs := modifyCmd.String("remove-diskid", "", "Comma-separated list of disk IDs to remove")
+	modifyAddHostnames := modifyCmd.String("add-hostname", "", "Comma-separated list of hostnames to add")
+	modifyRemoveHostnames := modifyCmd.String("remove-hostname", "", "Comma-separated list of hostnames to remove")
+	modifyAddContainerIDs := modifyCmd.String("add-container-id", "", "Comma-separated list of container IDs to add")
+	modifyRemoveContainerIDs := modifyCmd.String("remove-container-id", "", "Comma-separated list of container IDs to remove")
+	modifyExpiry := modifyCmd.String("expiry", "", "New expiry date (YYYY-MM-DD, default: unchanged)")
+	modifyPrivateKey := modifyCmd.String("key", "keys/private.pem", "Path to private key")
+	modifyOutput := modifyCmd.String("output", "", "Output license file (default: the input file with a .modified.lic extension)")
+	modifyScheme := modifyCmd.String("scheme", "", "Signature scheme (default: keep the scheme of the license)")
+	modifyLedger := modifyCmd.String("ledger", "", "Issuance ledger to record the license in (not recorded by default)")
+	modifyDecryptKey := modifyCmd.String("decrypt-key", "", "Path to X25519 private key of an encrypted license, which stays encrypted")
+	modifyFingerprint := modifyCmd.String("fingerprint", "", "Fingerprint file of the machine a machine-bound license is bound to")
+
+	diffCmd := flag.NewFlagSet("diff", flag.ExitOnError)
+
+	floatCmd := flag.NewFlagSet("float", flag.ExitOnError)
+	floatAddr := floatCmd.String("addr", ":7070", "Address to listen on")
+	floatLicenseFile := floatCmd.String("license", "license.lic", "Floating pool license file")
+	floatPublicKey := floatCmd.String("public-key", "keys/public.pem", "Public key that signed the pool license")
+	floatSeatKey := floatCmd.String("key", "seat-keys/private.pem", "Private key of the seat server, signs seat leases")
+	floatLease := floatCmd.Duration("lease", 2*time.Minute, "How long a seat lease is valid without a heartbeat")
+	floatState := floatCmd.String("state", "", "File persisting the seats in use, locked while serving (default: the license file with a .seats.json extension)")
+
+	serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+	serveAddr := serveCmd.String("addr", ":8080", "Address to listen on")
+	servePrivateKey := serveCmd.String("key", "keys/private.pem", "Path to private key")
+	serveLedger := serveCmd.String("ledger", defaultLedgerPath, "Issuance ledger")
+	serveTokenFile := serveCmd.String("token-file", "", "File with one API token per line (tokens are also read from "+apiTokensEnv+")")
+	serveScheme := serveCmd.String("scheme", "pkcs1v15", "Signature scheme (pkcs1v15 or pss)")
+	serveSerialScheme := serveCmd.String("serial-scheme", "", "Generate serial numbers for requests without one, e.g. prefix=SN-,width=6,check=luhn")
+	serveSerialCounter := serveCmd.String("serial-counter", "serial-counter.json", "File holding the serial number sequence")
+	serveCheckInLease := serveCmd.Duration("checkin-lease", 0, "Enable online check-in with leases valid for this long, e.g. 168h (0 disables)")
 
 	infoCmd := flag.NewFlagSet("info", flag.ExitOnError)
 	infoLicenseFile := infoCmd.String("license", "license.lic", "License file")
 	infoPublicKey := infoCmd.String("key", "keys/public.pem", "Path to public key")
+	infoDecryptKey := infoCmd.String("decrypt-key", "", "Path to X25519 private key for encrypted licenses")
 
 	// Print banner
 	printBanner()
@@ -59,13 +184,39 @@ func main() {
 	switch os.Args[1] {
 	case "keygen":
 		keygenCmd.Parse(os.Args[2:])
-		generateAndSaveKeyPair(*keygenKeyDir, *keygenKeySize, *keygenForce)
+		if *keygenEncryption {
+			generateAndSaveEncryptionKeyPair(*keygenKeyDir, *keygenForce)
+		} else {
+			generateAndSaveKeyPair(*keygenKeyDir, *keygenKeySize, *keygenForce)
+		}
 
 	case "genlicense":
 		genlicenseCmd.Parse(os.Args[2:])
+		settings := generationSettings{
+			schemeName:     *genlicenseScheme,
+			encryptKeyPath: *genlicenseEncryptKey,
+			machineBound:   *genlicenseMachineBound,
+			ledgerPath:     *genlicenseLedger,
+			seats:          *genlicenseSeats,
+			seatServerKey:  *genlicenseSeatServerKey,
+			quotas:         *genlicenseQuotas,
+			containerIDs:   *genlicenseContainerIDs,
+			identityFile:   *genlicenseIdentityFile,
+			fingerprint:    *genlicenseFingerprint,
+		}
 		if *genlicenseInteractive {
-			runInteractiveGeneration(*genlicensePrivateKey, *genlicenseOutput)
+			runInteractiveGeneration(*genlicensePrivateKey, *genlicenseOutput, settings)
 		} else {
+			ids, err := newIdentityGenerator(*genlicenseAutoID, *genlicenseIDFormat, *genlicenseSerialScheme, *genlicenseSerialCounter)
+			if err != nil {
+				fmt.Printf("❌ Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := ids.fill(genlicenseID, genlicenseSerialNumber); err != nil {
+				fmt.Printf("❌ Error: %v\n", err)
+				os.Exit(1)
+			}
+
 			if *genlicenseID == "" || *genlicenseCustomerID == "" || *genlicenseProductID == "" || *genlicenseSerialNumber == "" {
 				fmt.Println("❌ Error: License ID, Customer ID, Product ID, and Serial Number are required")
 				fmt.Println("\nCommand options:")
@@ -86,12 +237,113 @@ func main() {
 				*genlicensePrivateKey,
 				*genlicenseOutput,
 				*genlicenseAutoHardware,
+				settings,
 			)
 		}
 
+	case "trial":
+		trialCmd.Parse(os.Args[2:])
+		if *trialProductID == "" {
+			fmt.Println("❌ Error: Product ID is required")
+			fmt.Println("\nCommand options:")
+			trialCmd.PrintDefaults()
+			os.Exit(1)
+		}
+		generateTrialLicense(*trialID, *trialProductID, *trialDays, *trialAvailableDays, *trialFeatures,
+			*trialPrivateKey, *trialOutput, *trialScheme, *trialLedger)
+
+	case "migrate":
+		migrateCmd.Parse(os.Args[2:])
+		migrateLegacyLicense(*migrateLicenseFile, *migratePrivateKey, *migrateOutput, *migrateScheme, *migrateLedger)
+
+	case "batch":
+		batchCmd.Parse(os.Args[2:])
+		if *batchManifest == "" {
+			fmt.Println("❌ Error: Manifest file is required")
+			fmt.Println("\nCommand options:")
+			batchCmd.PrintDefaults()
+			os.Exit(1)
+		}
+		ids, err := newIdentityGenerator(*batchAutoID, *batchIDFormat, *batchSerialScheme, *batchSerialCounter)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		runBatchGeneration(*batchManifest, *batchOutput, *batchPrivateKey, *batchScheme, *batchWorkers, ids, *batchLedger)
+
+	case "renew":
+		renewCmd.Parse(os.Args[2:])
+		renewLicense(*renewLicenseFile, *renewID, *renewValidDays, *renewPrivateKey, *renewOutput, *renewScheme, *renewLedger, *renewDecryptKey, *renewFingerprint)
+
+	case "modify":
+		modifyCmd.Parse(os.Args[2:])
+		expiryDate, err := parseExpiryDate(*modifyExpiry)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		changes := licgen.Changes{
+			AddFeatures:        parseCommaSeparatedList(*modifyAddFeatures),
+			RemoveFeatures:     parseCommaSeparatedList(*modifyRemoveFeatures),
+			AddMACAddresses:    parseCommaSeparatedList(*modifyAddMACs),
+			RemoveMACAddresses: parseCommaSeparatedList(*modifyRemoveMACs),
+			AddDiskIDs:         parseCommaSeparatedList(*modifyAddDiskIDs),
+			RemoveDiskIDs:      parseCommaSeparatedList(*modifyRemoveDiskIDs),
+			AddHostNames:       parseCommaSeparatedList(*modifyAddHostnames),
+			RemoveHostNames:    parseCommaSeparatedList(*modifyRemoveHostnames),
+			AddContainerIDs:    parseCommaSeparatedList(*modifyAddContainerIDs),
+			RemoveContainerIDs: parseCommaSeparatedList(*modifyRemoveContainerIDs),
+			ExpiryDate:         expiryDate,
+		}
+		modifyLicense(*modifyLicenseFile, *modifyID, changes, *modifyPrivateKey, *modifyOutput, *modifyScheme, *modifyLedger, *modifyDecryptKey, *modifyFingerprint)
+
+	case "diff":
+		diffCmd.Parse(os.Args[2:])
+		if diffCmd.NArg() != 2 {
+			fmt.Println("❌ Error: Two license files are required")
+			fmt.Println("\nUsage: licforge diff <a.lic> <b.lic>")
+			os.Exit(1)
+		}
+		diffLicenses(diffCmd.Arg(0), diffCmd.Arg(1))
+
+	case "list":
+		listCmd.Parse(os.Args[2:])
+		query, err := parseLedgerQuery(*listCustomer, *listProduct, *listExpiresAfter, *listExpiresBefore, *listExpiringWithin)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		listLicenses(*listLedger, query)
+
+	case "show":
+		showCmd.Parse(os.Args[2:])
+		if showCmd.NArg() != 1 {
+			fmt.Println("❌ Error: Exactly one license ID is required")
+			fmt.Println("\nUsage: licforge show [options] <license-id>")
+			showCmd.PrintDefaults()
+			os.Exit(1)
+		}
+		showLicense(*showLedger, showCmd.Arg(0), *showExport)
+
+	case "float":
+		floatCmd.Parse(os.Args[2:])
+		serveSeats(*floatAddr, *floatLicenseFile, *floatPublicKey, *floatSeatKey, *floatState, *floatLease)
+
+	case "serve":
+		serveCmd.Parse(os.Args[2:])
+		serveAPI(*serveAddr, *servePrivateKey, *serveLedger, *serveTokenFile, *serveScheme, *serveSerialScheme, *serveSerialCounter, *serveCheckInLease)
+
+	case "embed-key":
+		embedKeyCmd.Parse(os.Args[2:])
+		embedPublicKey(*embedKeyPublicKey, *embedKeyOutput, *embedKeyPackage, *embedKeyVar, *embedKeyShares)
+
+	case "fingerprint":
+		fingerprintCmd.Parse(os.Args[2:])
+		exportFingerprint(*fingerprintFormat, *fingerprintPublicKey, *fingerprintOutput)
+
 	case "info":
 		infoCmd.Parse(os.Args[2:])
-		displayLicenseInfo(*infoLicenseFile, *infoPublicKey)
+		displayLicenseInfo(*infoLicenseFile, *infoPublicKey, *infoDecryptKey)
 
 	case "version":
 		fmt.Printf("licforge version %s\n", version)
@@ -125,6 +377,18 @@ func printUsage() {
 	fmt.Println("\nCommands:")
 	fmt.Println("  keygen      Generate a new RSA key pair")
 	fmt.Println("  genlicense  Generate a license")
+	fmt.Println("  trial       Generate a trial license that starts on first run")
+	fmt.Println("  batch       Generate licenses from a CSV or JSON manifest")
+	fmt.Println("  migrate     Re-sign a legacy JSON license in the binary format")
+	fmt.Println("  renew       Issue a renewal of an existing license")
+	fmt.Println("  modify      Issue a license with added or removed features and hardware")
+	fmt.Println("  diff        Show the differences between two licenses")
+	fmt.Println("  list        List and search issued licenses in the ledger")
+	fmt.Println("  show        Show an issued license from the ledger")
+	fmt.Println("  serve       Run the license issuance REST API")
+	fmt.Println("  float       Serve the seats of a floating license")
+	fmt.Println("  embed-key   Generate a Go source file embedding the public key")
+	fmt.Println("  fingerprint Export this machine's hardware identifiers for license binding")
 	fmt.Println("  info        Display license information")
 	fmt.Println("  version     Display version information")
 	fmt.Println("  help        Display this help message")
@@ -188,6 +452,68 @@ func generateAndSaveKeyPair(keyDir string, keySize int, force bool) {
 	fmt.Println("\n🔐 Key pair generated successfully!")
 }
 
+// generateAndSaveEncryptionKeyPair generates a new X25519 key pair and saves it to files
+func generateAndSaveEncryptionKeyPair(keyDir string, force bool) {
+	fmt.Println("🔑 Generating X25519 encryption key pair...")
+
+	// Check if key files already exist
+	privateKeyPath := filepath.Join(keyDir, "encryption_private.pem")
+	publicKeyPath := filepath.Join(keyDir, "encryption_public.pem")
+
+	if !force {
+		if _, err := os.Stat(privateKeyPath); err == nil {
+			fmt.Println("❌ Encryption private key already exists. Use -force to overwrite.")
+			os.Exit(1)
+		}
+		if _, err := os.Stat(publicKeyPath); err == nil {
+			fmt.Println("❌ Encryption public key already exists. Use -force to overwrite.")
+			os.Exit(1)
+		}
+	}
+
+	privateKeyPEM, publicKeyPEM, err := licgen.GenerateEncryptionKeyPair()
+	if err != nil {
+		fmt.Printf("❌ Failed to generate key pair: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Create key directory if it doesn't exist
+	if err := os.MkdirAll(keyDir, 0755); err != nil {
+		fmt.Printf("❌ Failed to create key directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Save private key
+	if err := os.WriteFile(privateKeyPath, []byte(privateKeyPEM), 0600); err != nil {
+		fmt.Printf("❌ Failed to save private key: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Encryption private key saved to: %s\n", privateKeyPath)
+
+	// Save public key
+	if err := os.WriteFile(publicKeyPath, []byte(publicKeyPEM), 0644); err != nil {
+		fmt.Printf("❌ Failed to save public key: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Encryption public key saved to: %s\n", publicKeyPath)
+
+	fmt.Println("\n🔐 Encryption key pair generated successfully!")
+}
+
+// generationSettings holds optional license generation settings
+type generationSettings struct {
+	schemeName     string
+	encryptKeyPath string
+	machineBound   bool
+	ledgerPath     string
+	seats          uint
+	seatServerKey  string
+	quotas         string
+	containerIDs   string
+	identityFile   string
+	fingerprint    string
+}
+
 // generateAndSaveLicense generates a license and saves it to a file
 func generateAndSaveLicense(
 	licenseID string,
@@ -202,9 +528,30 @@ func generateAndSaveLicense(
 	privateKeyPath string,
 	outputPath string,
 	autoHardware bool,
+	settings generationSettings,
 ) {
 	fmt.Println("📜 Generating license...")
 
+	if settings.machineBound && !autoHardware && settings.fingerprint == "" {
+		fmt.Println("❌ Machine-bound licenses require -auto-hardware or -fingerprint")
+		os.Exit(1)
+	}
+	if autoHardware && settings.fingerprint != "" {
+		fmt.Println("❌ -auto-hardware and -fingerprint cannot be combined")
+		os.Exit(1)
+	}
+	if settings.machineBound && settings.encryptKeyPath != "" {
+		fmt.Println("❌ -machine-bound and -encrypt-key cannot be combined")
+		os.Exit(1)
+	}
+
+	// Parse signature scheme
+	scheme, err := licformat.ParseSignatureScheme(settings.schemeName)
+	if err != nil {
+		fmt.Printf("❌ Invalid signature scheme: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Read private key
 	privateKeyPEM, err := os.ReadFile(privateKeyPath)
 	if err != nil {
@@ -222,6 +569,52 @@ func generateAndSaveLicense(
 	// Parse features
 	features := parseCommaSeparatedList(featuresStr)
 
+	// Optional generation settings
+	opts := []licgen.Option{licgen.WithSignatureScheme(scheme)}
+	opts = append(opts, ledgerOptions(settings.ledgerPath)...)
+	if settings.seats > 0 {
+		if settings.seats > math.MaxUint32 {
+			fmt.Printf("❌ Invalid seat count: %d\n", settings.seats)
+			os.Exit(1)
+		}
+		if settings.seatServerKey == "" {
+			fmt.Println("❌ Floating licenses require -seat-server-key")
+			os.Exit(1)
+		}
+		seatServerKeyPEM, err := os.ReadFile(settings.seatServerKey)
+		if err != nil {
+			fmt.Printf("❌ Failed to read seat server key: %v\n", err)
+			os.Exit(1)
+		}
+		seatServerKey, err := licgen.ParsePublicKey(string(seatServerKeyPEM))
+		if err != nil {
+			fmt.Printf("❌ Failed to parse seat server key: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, licgen.WithSeats(uint32(settings.seats)), licgen.WithSeatServer(seatServerKey))
+	}
+	quotas, err := parseQuotas(settings.quotas)
+	if err != nil {
+		fmt.Printf("❌ Invalid usage quotas: %v\n", err)
+		os.Exit(1)
+	}
+	for feature, limit := range quotas {
+		opts = append(opts, licgen.WithQuota(feature, limit))
+	}
+	if settings.encryptKeyPath != "" {
+		encryptKeyPEM, err := os.ReadFile(settings.encryptKeyPath)
+		if err != nil {
+			fmt.Printf("❌ Failed to read encryption key: %v\n", err)
+			os.Exit(1)
+		}
+		encryptKey, err := licgen.ParseEncryptionPublicKey(string(encryptKeyPEM))
+		if err != nil {
+			fmt.Printf("❌ Failed to parse encryption key: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, licgen.WithEncryption(encryptKey))
+	}
+
 	// Parse hardware binding
 	var hardwareIDs licverify.HardwareBinding
 
@@ -239,6 +632,7 @@ func generateAndSaveLicense(
 			MACAddresses: hwInfo.MACAddresses,
 			DiskIDs:      hwInfo.DiskIDs,
 			HostNames:    []string{hwInfo.Hostname},
+			ContainerIDs: hwInfo.ContainerIDs,
 		}
 
 		fmt.Println("✅ Hardware information detected:")
@@ -251,19 +645,64 @@ func generateAndSaveLicense(
 		if hwInfo.Hostname != "" {
 			fmt.Printf("   Hostname: %s\n", hwInfo.Hostname)
 		}
+		if len(hwInfo.ContainerIDs) > 0 {
+			fmt.Printf("   Container IDs: %v\n", hwInfo.ContainerIDs)
+		}
+
+		if settings.machineBound {
+			opts = append(opts, licgen.WithMachineBinding(hwInfo))
+		}
+	} else if settings.fingerprint != "" {
+		// Use the hardware information of the customer's machine
+		fingerprint := readFingerprint(settings.fingerprint, licverify.NewVerifierFromPublicKey(&privateKey.PublicKey))
+		hardwareIDs = fingerprint.HardwareBinding()
+
+		fmt.Printf("✅ Fingerprint read from %s (created %s):\n", settings.fingerprint, fingerprint.CreatedAt.Format(time.RFC3339))
+		if len(hardwareIDs.MACAddresses) > 0 {
+			fmt.Printf("   MAC Addresses: %v\n", hardwareIDs.MACAddresses)
+		}
+		if len(hardwareIDs.DiskIDs) > 0 {
+			fmt.Printf("   Disk IDs: %v\n", hardwareIDs.DiskIDs)
+		}
+		if len(hardwareIDs.HostNames) > 0 {
+			fmt.Printf("   Hostnames: %v\n", hardwareIDs.HostNames)
+		}
+		if len(hardwareIDs.ContainerIDs) > 0 {
+			fmt.Printf("   Container IDs: %v\n", hardwareIDs.ContainerIDs)
+		}
+
+		if settings.machineBound {
+			opts = append(opts, licgen.WithMachineBinding(fingerprint.HardwareInfo()))
+		}
 	} else {
 		// Use provided hardware information
 		hardwareIDs = licverify.HardwareBinding{
 			MACAddresses: parseCommaSeparatedList(macAddressesStr),
 			DiskIDs:      parseCommaSeparatedList(diskIDsStr),
 			HostNames:    parseCommaSeparatedList(hostnamesStr),
+			ContainerIDs: parseCommaSeparatedList(settings.containerIDs),
+		}
+	}
+	if settings.identityFile != "" {
+		identity, err := os.ReadFile(settings.identityFile)
+		if err != nil {
+			fmt.Printf("❌ Failed to read identity file: %v\n", err)
+			os.Exit(1)
 		}
+		hardwareIDs.ContainerIDs = append(hardwareIDs.ContainerIDs, licverify.IdentityFileID(identity))
 	}
 
 	// Generate license
 	fmt.Println("🔐 Signing license with private key...")
 	// In v2.0.0, binary format is the only option
 	fmt.Println("📦 Using binary format")
+	fmt.Printf("✍️  Signature scheme: %s\n", scheme)
+	if settings.encryptKeyPath != "" {
+		fmt.Println("🔒 Encrypting license contents")
+	}
+	if settings.machineBound {
+		fmt.Println("🔒 Binding license contents to this machine's fingerprint")
+	}
 
 	licenseData, err := licgen.GenerateLicense(
 		licenseID,
@@ -274,6 +713,7 @@ func generateAndSaveLicense(
 		features,
 		hardwareIDs,
 		privateKey,
+		opts...,
 	)
 	if err != nil {
 		fmt.Printf("❌ Failed to generate license: %v\n", err)
@@ -288,70 +728,71 @@ func generateAndSaveLicense(
 	fmt.Printf("✅ License saved to: %s\n", outputPath)
 
 	// Print license information
-	var license licverify.License
-	// Try to decode as binary first, fall back to JSON if that fails
-	licenseBytes := licenseData[:len(licenseData)-256] // Remove signature
-	// In v2.0.0, binary format is the only option for new licenses
-	// JSON format is only supported for reading legacy licenses
-	importedLicense, err := licformat.DecodeLicense(licenseBytes)
+	printLicenseSummary(privateKey, licenseData)
+
+	fmt.Println("\n✨ License generated successfully!")
+}
+
+// printLicenseSummary prints the fields of a newly signed license.
+// Encrypted licenses cannot be read back with the signing key.
+func printLicenseSummary(privateKey *rsa.PrivateKey, licenseData []byte) {
+	if licformat.IsEncrypted(licenseData) {
+		fmt.Println("\n🔒 License contents are encrypted")
+		return
+	}
+	license, err := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey).ParseLicense(licenseData)
 	if err != nil {
-		// Try JSON format as fallback
-		if err := json.Unmarshal(licenseBytes, &license); err != nil {
-			fmt.Printf("❌ Failed to parse license data: %v\n", err)
-			os.Exit(1)
-		}
-	} else {
-		// Convert the imported license to licverify.License format
-		license = licverify.License{
-			ID:           importedLicense.ID,
-			CustomerID:   importedLicense.CustomerID,
-			ProductID:    importedLicense.ProductID,
-			SerialNumber: importedLicense.SerialNumber,
-			IssueDate:    importedLicense.IssueDate,
-			ExpiryDate:   importedLicense.ExpiryDate,
-			Features:     importedLicense.Features,
-			HardwareIDs: licverify.HardwareBinding{
-				MACAddresses: importedLicense.HardwareIDs.MACAddresses,
-				DiskIDs:      importedLicense.HardwareIDs.DiskIDs,
-				HostNames:    importedLicense.HardwareIDs.HostNames,
-				CustomIDs:    importedLicense.HardwareIDs.CustomIDs,
-			},
-		}
+		fmt.Printf("❌ Failed to parse license data: %v\n", err)
+		os.Exit(1)
 	}
 
 	fmt.Println("\n📃 License Information:")
-	fmt.Printf("   License ID: %s\n", license.ID)
-	fmt.Printf("   Customer ID: %s\n", license.CustomerID)
-	fmt.Printf("   Product ID: %s\n", license.ProductID)
-	fmt.Printf("   Serial Number: %s\n", license.SerialNumber)
-	fmt.Printf("   Issue Date: %s\n", license.IssueDate.Format(time.RFC3339))
-	fmt.Printf("   Expiry Date: %s\n", license.ExpiryDate.Format(time.RFC3339))
+	fmt.Printf("   License ID: %s\n", license.ID())
+	if license.PreviousID() != "" {
+		fmt.Printf("   Previous License: %s\n", license.PreviousID())
+	}
+	fmt.Printf("   Customer ID: %s\n", license.CustomerID())
+	fmt.Printf("   Product ID: %s\n", license.ProductID())
+	fmt.Printf("   Serial Number: %s\n", license.SerialNumber())
+	fmt.Printf("   Issue Date: %s\n", license.IssueDate().Format(time.RFC3339))
+	fmt.Printf("   Expiry Date: %s\n", license.ExpiryDate().Format(time.RFC3339))
 
 	// Calculate days until expiry
-	daysUntilExpiry := int(time.Until(license.ExpiryDate).Hours() / 24)
+	daysUntilExpiry := int(time.Until(license.ExpiryDate()).Hours() / 24)
 	if daysUntilExpiry > 0 {
 		fmt.Printf("   Validity: %d days\n", daysUntilExpiry)
 	} else {
 		fmt.Printf("   Validity: Expired\n")
 	}
 
-	fmt.Printf("   Features: %v\n", license.Features)
-
-	if len(license.HardwareIDs.MACAddresses) > 0 {
-		fmt.Printf("   MAC Addresses: %v\n", license.HardwareIDs.MACAddresses)
+	fmt.Printf("   Features: %v\n", license.Features())
+	if license.Seats() > 0 {
+		fmt.Printf("   Floating Seats: %d\n", license.Seats())
+		fmt.Printf("   Seat Server Key: %x\n", license.SeatServerKey())
 	}
-	if len(license.HardwareIDs.DiskIDs) > 0 {
-		fmt.Printf("   Disk IDs: %v\n", license.HardwareIDs.DiskIDs)
+	if license.IsTrial() {
+		fmt.Printf("   Trial: %s from first run\n", formatTrialDuration(license.TrialDuration()))
 	}
-	if len(license.HardwareIDs.HostNames) > 0 {
-		fmt.Printf("   Hostnames: %v\n", license.HardwareIDs.HostNames)
+	if len(license.Quotas()) > 0 {
+		fmt.Printf("   Usage Quotas: %s\n", formatQuotas(license.Quotas()))
 	}
 
-	fmt.Println("\n✨ License generated successfully!")
+	if len(license.HardwareIDs().MACAddresses) > 0 {
+		fmt.Printf("   MAC Addresses: %v\n", license.HardwareIDs().MACAddresses)
+	}
+	if len(license.HardwareIDs().DiskIDs) > 0 {
+		fmt.Printf("   Disk IDs: %v\n", license.HardwareIDs().DiskIDs)
+	}
+	if len(license.HardwareIDs().HostNames) > 0 {
+		fmt.Printf("   Hostnames: %v\n", license.HardwareIDs().HostNames)
+	}
+	if len(license.HardwareIDs().ContainerIDs) > 0 {
+		fmt.Printf("   Container IDs: %v\n", license.HardwareIDs().ContainerIDs)
+	}
 }
 
 // runInteractiveGeneration generates a license interactively
-func runInteractiveGeneration(privateKeyPath, outputPath string) {
+func runInteractiveGeneration(privateKeyPath, outputPath string, settings generationSettings) {
 	fmt.Println("💬 Interactive License Generation")
 
 	// In v2.0.0, binary is the only format
@@ -399,11 +840,71 @@ func runInteractiveGeneration(privateKeyPath, outputPath string) {
 		privateKeyPath,
 		outputPath,
 		autoHardware,
+		settings,
 	)
 }
 
+// migrateLegacyLicense converts a legacy JSON license to the binary format
+func migrateLegacyLicense(licenseFile, privateKeyPath, outputPath, schemeName, ledgerPath string) {
+	fmt.Printf("🔄 Migrating legacy license: %s\n", licenseFile)
+
+	// Keep the legacy license, which the migrated one replaces
+	if outputPath == "" {
+		outputPath = siblingPath(licenseFile, ".migrated.lic")
+	}
+	if outputPath == licenseFile {
+		fmt.Println("❌ The output file must differ from the legacy license file")
+		os.Exit(1)
+	}
+
+	// Parse signature scheme
+	scheme, err := licformat.ParseSignatureScheme(schemeName)
+	if err != nil {
+		fmt.Printf("❌ Invalid signature scheme: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Read private key
+	privateKeyPEM, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		fmt.Printf("❌ Failed to read private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Parse private key
+	privateKey, err := licgen.ParsePrivateKey(string(privateKeyPEM))
+	if err != nil {
+		fmt.Printf("❌ Failed to parse private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Read legacy license
+	legacyData, err := os.ReadFile(licenseFile)
+	if err != nil {
+		fmt.Printf("❌ Failed to read license file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Verify and re-sign the license
+	fmt.Println("🔐 Verifying legacy signature and re-signing license...")
+	licenseData, err := licgen.MigrateLegacyLicense(legacyData, privateKey, append(ledgerOptions(ledgerPath), licgen.WithSignatureScheme(scheme))...)
+	if err != nil {
+		fmt.Printf("❌ Failed to migrate license: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Save license
+	if err := licgen.SaveLicenseToFile(licenseData, outputPath); err != nil {
+		fmt.Printf("❌ Failed to save license: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Migrated license saved to: %s\n", outputPath)
+
+	fmt.Println("\n✨ License migrated successfully!")
+}
+
 // displayLicenseInfo displays information about a license
-func displayLicenseInfo(licenseFile, publicKeyFile string) {
+func displayLicenseInfo(licenseFile, publicKeyFile, decryptKeyFile string) {
 	fmt.Printf("🔍 Examining license file: %s\n", licenseFile)
 
 	// Read public key
@@ -413,8 +914,22 @@ func displayLicenseInfo(licenseFile, publicKeyFile string) {
 		os.Exit(1)
 	}
 
-	// Create verifier
-	verifier, err := licverify.NewVerifier(string(publicKeyPEM))
+	// Create verifier, accepting legacy JSON licenses so they can be inspected
+	opts := []licverify.Option{licverify.WithLegacyJSON()}
+	if decryptKeyFile != "" {
+		decryptKeyPEM, err := os.ReadFile(decryptKeyFile)
+		if err != nil {
+			fmt.Printf("❌ Failed to read decryption key: %v\n", err)
+			os.Exit(1)
+		}
+		decryptKey, err := licverify.ParseDecryptionKey(string(decryptKeyPEM))
+		if err != nil {
+			fmt.Printf("❌ Failed to parse decryption key: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, licverify.WithDecryptionKey(decryptKey))
+	}
+	verifier, err := licverify.NewVerifier(string(publicKeyPEM), opts...)
 	if err != nil {
 		fmt.Printf("❌ Failed to create verifier: %v\n", err)
 		os.Exit(1)
@@ -422,24 +937,31 @@ func displayLicenseInfo(licenseFile, publicKeyFile string) {
 
 	// Load license
 	license, err := verifier.LoadLicense(licenseFile)
+	if errors.Is(err, licverify.ErrMachineBinding) {
+		fmt.Println("🔒 License contents are bound to a different machine and cannot be decrypted here")
+		os.Exit(1)
+	}
+	if errors.Is(err, licformat.ErrEncrypted) {
+		fmt.Println("🔒 License contents are encrypted")
+		fmt.Println("   Use -decrypt-key with the X25519 private key to display them")
+		os.Exit(1)
+	}
 	if err != nil {
 		fmt.Printf("❌ Failed to load license: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Detect license format (binary or legacy JSON)
-	var formatType string
-	// Check if we can decode it as binary
-	licenseData, err := os.ReadFile(licenseFile)
-	if err == nil && len(licenseData) > 256 {
-		_, err := licformat.DecodeLicense(licenseData[:len(licenseData)-256])
-		if err == nil {
-			formatType = "binary"
-		} else {
-			formatType = "json (legacy)"
-		}
+	// Report license format (binary or legacy JSON)
+	if license.IsLegacyJSON() {
+		fmt.Println("📦 License format: json (legacy)")
+		fmt.Println("⚠️ Legacy JSON licenses are only accepted with WithLegacyJSON, run 'licforge migrate' to convert it")
+	} else {
+		fmt.Println("📦 License format: binary")
+	}
+	fmt.Printf("✍️  Signature scheme: %s\n", license.SignatureScheme())
+	if license.IsEncrypted() {
+		fmt.Println("🔒 License contents are encrypted")
 	}
-	fmt.Printf("📦 License format: %s\n", formatType)
 
 	// Verify license
 	fmt.Println("🔐 Verifying license signature...")
@@ -458,11 +980,15 @@ func displayLicenseInfo(licenseFile, publicKeyFile string) {
 	} else {
 		fmt.Println("✅ License is not expired")
 	}
+	if license.IsTrial() {
+		fmt.Println("⚠️ Trial license: applications accept it only through licverify.TrialTracker, which enforces the trial period from the first run")
+	}
 
 	// Check hardware binding
-	if len(license.HardwareIDs.MACAddresses) > 0 ||
-		len(license.HardwareIDs.DiskIDs) > 0 ||
-		len(license.HardwareIDs.HostNames) > 0 {
+	if len(license.HardwareIDs().MACAddresses) > 0 ||
+		len(license.HardwareIDs().DiskIDs) > 0 ||
+		len(license.HardwareIDs().HostNames) > 0 ||
+		len(license.HardwareIDs().ContainerIDs) > 0 {
 		fmt.Println("💻 Checking hardware binding...")
 		err = verifier.VerifyHardwareBinding(license)
 		if err != nil {
@@ -476,32 +1002,87 @@ func displayLicenseInfo(licenseFile, publicKeyFile string) {
 
 	// Print license information
 	fmt.Println("\n📃 License Information:")
-	fmt.Printf("   License ID: %s\n", license.ID)
-	fmt.Printf("   Customer ID: %s\n", license.CustomerID)
-	fmt.Printf("   Product ID: %s\n", license.ProductID)
-	fmt.Printf("   Serial Number: %s\n", license.SerialNumber)
-	fmt.Printf("   Issue Date: %s\n", license.IssueDate.Format(time.RFC3339))
-	fmt.Printf("   Expiry Date: %s\n", license.ExpiryDate.Format(time.RFC3339))
+	fmt.Printf("   License ID: %s\n", license.ID())
+	if license.PreviousID() != "" {
+		fmt.Printf("   Previous License: %s\n", license.PreviousID())
+	}
+	fmt.Printf("   Customer ID: %s\n", license.CustomerID())
+	fmt.Printf("   Product ID: %s\n", license.ProductID())
+	fmt.Printf("   Serial Number: %s\n", license.SerialNumber())
+	fmt.Printf("   Issue Date: %s\n", license.IssueDate().Format(time.RFC3339))
+	fmt.Printf("   Expiry Date: %s\n", license.ExpiryDate().Format(time.RFC3339))
 
 	// Calculate days remaining
-	daysRemaining := int(time.Until(license.ExpiryDate).Hours() / 24)
+	daysRemaining := int(time.Until(license.ExpiryDate()).Hours() / 24)
 	if daysRemaining > 0 {
 		fmt.Printf("   Status: Active (%d days remaining)\n", daysRemaining)
 	} else {
 		fmt.Printf("   Status: Expired (%d days ago)\n", -daysRemaining)
 	}
 
-	fmt.Printf("   Features: %v\n", license.Features)
+	fmt.Printf("   Features: %v\n", license.Features())
+	if license.Seats() > 0 {
+		fmt.Printf("   Floating Seats: %d\n", license.Seats())
+		fmt.Printf("   Seat Server Key: %x\n", license.SeatServerKey())
+	}
+	if license.IsTrial() {
+		fmt.Printf("   Trial: %s from first run\n", formatTrialDuration(license.TrialDuration()))
+	}
+	if len(license.Quotas()) > 0 {
+		fmt.Printf("   Usage Quotas: %s\n", formatQuotas(license.Quotas()))
+	}
+
+	if len(license.HardwareIDs().MACAddresses) > 0 {
+		fmt.Printf("   MAC Addresses: %v\n", license.HardwareIDs().MACAddresses)
+	}
+	if len(license.HardwareIDs().DiskIDs) > 0 {
+		fmt.Printf("   Disk IDs: %v\n", license.HardwareIDs().DiskIDs)
+	}
+	if len(license.HardwareIDs().HostNames) > 0 {
+		fmt.Printf("   Hostnames: %v\n", license.HardwareIDs().HostNames)
+	}
+	if len(license.HardwareIDs().ContainerIDs) > 0 {
+		fmt.Printf("   Container IDs: %v\n", license.HardwareIDs().ContainerIDs)
+	}
+}
 
-	if len(license.HardwareIDs.MACAddresses) > 0 {
-		fmt.Printf("   MAC Addresses: %v\n", license.HardwareIDs.MACAddresses)
+// parseQuotas parses comma-separated feature=limit usage quotas
+func parseQuotas(value string) (map[string]uint64, error) {
+	quotas := make(map[string]uint64)
+	for _, entry := range parseCommaSeparatedList(value) {
+		feature, limitStr, ok := strings.Cut(entry, "=")
+		feature = strings.TrimSpace(feature)
+		if !ok || feature == "" {
+			return nil, fmt.Errorf("%q is not feature=limit", entry)
+		}
+		limit, err := strconv.ParseUint(strings.TrimSpace(limitStr), 10, 64)
+		if err != nil || limit == 0 {
+			return nil, fmt.Errorf("limit of %s must be a positive number", feature)
+		}
+		if _, exists := quotas[feature]; exists {
+			return nil, fmt.Errorf("%s has more than one quota", feature)
+		}
+		quotas[feature] = limit
 	}
-	if len(license.HardwareIDs.DiskIDs) > 0 {
-		fmt.Printf("   Disk IDs: %v\n", license.HardwareIDs.DiskIDs)
+	return quotas, nil
+}
+
+// formatQuotas formats usage quotas as sorted feature=limit entries
+func formatQuotas(quotas map[string]uint64) string {
+	entries := make([]string, 0, len(quotas))
+	for feature, limit := range quotas {
+		entries = append(entries, fmt.Sprintf("%s=%d", feature, limit))
 	}
-	if len(license.HardwareIDs.HostNames) > 0 {
-		fmt.Printf("   Hostnames: %v\n", license.HardwareIDs.HostNames)
+	slices.Sort(entries)
+	return strings.Join(entries, ", ")
+}
+
+// formatTrialDuration formats a trial duration in days when it is a whole number of days
+func formatTrialDuration(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d days", d/(24*time.Hour))
 	}
+	return d.String()
 }
 
 // promptForInput prompts the user for input with an optional default value
@@ -529,6 +1110,11 @@ func promptForInput(prompt string, defaultValue ...string) string {
 	return input
 }
 
+// siblingPath returns path with its extension replaced by suffix
+func siblingPath(path, suffix string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + suffix
+}
+
 // parseCommaSeparatedList parses a comma-separated list into a slice
 func parseCommaSeparatedList(list string) []string {
 	if list == "" {
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+)
+
+// modifyLicense issues a license derived from an existing one with changes applied
+func modifyLicense(licenseFile, newID string, changes licgen.Changes, privateKeyPath, outputPath, schemeName, ledgerPath, decryptKeyPath, fingerprintPath string) {
+	fmt.Printf("🛠️  Modifying license: %s\n", licenseFile)
+
+	// Keep the previous license, which the new one records as its predecessor
+	if outputPath == "" {
+		outputPath = siblingPath(licenseFile, ".modified.lic")
+	}
+	if outputPath == licenseFile {
+		fmt.Println("❌ The output file must differ from the previous license file")
+		os.Exit(1)
+	}
+
+	// Keep the scheme of the previous license unless one is given
+	opts := ledgerOptions(ledgerPath)
+	if schemeName != "" {
+		scheme, err := licformat.ParseSignatureScheme(schemeName)
+		if err != nil {
+			fmt.Printf("❌ Invalid signature scheme: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, licgen.WithSignatureScheme(scheme))
+	}
+
+	// Read private key
+	privateKeyPEM, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		fmt.Printf("❌ Failed to read private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Parse private key
+	privateKey, err := licgen.ParsePrivateKey(string(privateKeyPEM))
+	if err != nil {
+		fmt.Printf("❌ Failed to parse private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts = append(opts, reissueOptions(privateKey, decryptKeyPath, fingerprintPath)...)
+
+	// Read the previous license
+	previousData, err := os.ReadFile(licenseFile)
+	if err != nil {
+		fmt.Printf("❌ Failed to read license file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Verify and modify the license
+	fmt.Println("🔐 Verifying license signature and signing modified license...")
+	licenseData, err := licgen.Modify(previousData, newID, changes, privateKey, opts...)
+	if err != nil {
+		fmt.Printf("❌ Failed to modify license: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Save license
+	if err := licgen.SaveLicenseToFile(licenseData, outputPath); err != nil {
+		fmt.Printf("❌ Failed to save license: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Modified license saved to: %s\n", outputPath)
+
+	// Show what changed
+	if before, err := licformat.DecodeLicenseFile(previousData); err == nil {
+		if after, err := licformat.DecodeLicenseFile(licenseData); err == nil {
+			fmt.Println("\n📝 Changes:")
+			printDiff(licformat.Diff(before, after))
+		}
+	}
+
+	fmt.Println("\n✨ License modified successfully!")
+}
+
+// diffLicenses prints the field-level differences between two license files
+func diffLicenses(fileA, fileB string) {
+	fmt.Printf("🔍 Comparing %s and %s\n", fileA, fileB)
+	fmt.Println("⚠️ Signatures are not verified, use 'licforge info' to verify a license")
+
+	a := readLicenseFileForDiff(fileA)
+	b := readLicenseFileForDiff(fileB)
+
+	diffs := licformat.Diff(a, b)
+	if len(diffs) == 0 {
+		fmt.Println("\n✅ Licenses have no differences")
+		return
+	}
+
+	fmt.Printf("\n📝 %d fields differ:\n", len(diffs))
+	printDiff(diffs)
+}
+
+// readLicenseFileForDiff reads and decodes a license file without verifying it
+func readLicenseFileForDiff(path string) *licformat.License {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("❌ Failed to read license file: %v\n", err)
+		os.Exit(1)
+	}
+	license, err := licformat.DecodeLicenseFile(data)
+	if errors.Is(err, licformat.ErrEncrypted) {
+		fmt.Printf("❌ %s: license contents are encrypted\n", path)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("❌ Failed to decode %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	return license
+}
+
+// printDiff prints field differences, one field per line
+func printDiff(diffs []licformat.FieldDiff) {
+	for _, diff := range diffs {
+		if diff.Added != nil || diff.Removed != nil {
+			var changes []string
+			for _, item := range diff.Added {
+				changes = append(changes, "+"+item)
+			}
+			for _, item := range diff.Removed {
+				changes = append(changes, "-"+item)
+			}
+			fmt.Printf("   %s: %s\n", diff.Field, strings.Join(changes, " "))
+			continue
+		}
+		fmt.Printf("   %s: %s → %s\n", diff.Field, orNone(diff.Old), orNone(diff.New))
+	}
+}
+
+// orNone returns a placeholder for empty values
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// parseExpiryDate parses an optional YYYY-MM-DD expiry date
+func parseExpiryDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry date: %v", err)
+	}
+	return date, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// renewLicense issues a renewal of an existing license
+func renewLicense(licenseFile, newID string, validDays int, privateKeyPath, outputPath, schemeName, ledgerPath, decryptKeyPath, fingerprintPath string) {
+	fmt.Printf("🔁 Renewing license: %s\n", licenseFile)
+
+	// Keep the previous license, which the new one records as its predecessor
+	if outputPath == "" {
+		outputPath = siblingPath(licenseFile, ".renewed.lic")
+	}
+	if outputPath == licenseFile {
+		fmt.Println("❌ The output file must differ from the previous license file")
+		os.Exit(1)
+	}
+	if validDays <= 0 {
+		fmt.Println("❌ Validity must be at least one day")
+		os.Exit(1)
+	}
+
+	// Keep the scheme of the previous license unless one is given
+	opts := ledgerOptions(ledgerPath)
+	if schemeName != "" {
+		scheme, err := licformat.ParseSignatureScheme(schemeName)
+		if err != nil {
+			fmt.Printf("❌ Invalid signature scheme: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, licgen.WithSignatureScheme(scheme))
+	}
+
+	// Read private key
+	privateKeyPEM, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		fmt.Printf("❌ Failed to read private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Parse private key
+	privateKey, err := licgen.ParsePrivateKey(string(privateKeyPEM))
+	if err != nil {
+		fmt.Printf("❌ Failed to parse private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts = append(opts, reissueOptions(privateKey, decryptKeyPath, fingerprintPath)...)
+
+	// Read the previous license
+	previousData, err := os.ReadFile(licenseFile)
+	if err != nil {
+		fmt.Printf("❌ Failed to read license file: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Verify and renew the license
+	fmt.Println("🔐 Verifying license signature and signing renewal...")
+	licenseData, err := licgen.Renew(previousData, newID, time.Duration(validDays)*24*time.Hour, privateKey, opts...)
+	if err != nil {
+		fmt.Printf("❌ Failed to renew license: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Save license
+	if err := licgen.SaveLicenseToFile(licenseData, outputPath); err != nil {
+		fmt.Printf("❌ Failed to save license: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Renewed license saved to: %s\n", outputPath)
+
+	// Print the renewed license
+	printLicenseSummary(privateKey, licenseData)
+
+	fmt.Println("\n✨ License renewed successfully!")
+}
+
+// reissueOptions returns the options that decode an encrypted or
+// machine-bound previous license and encrypt or bind its replacement the
+// same way. Paths that are empty are skipped.
+func reissueOptions(privateKey *rsa.PrivateKey, decryptKeyPath, fingerprintPath string) []licgen.Option {
+	var opts []licgen.Option
+	if decryptKeyPath != "" {
+		decryptKeyPEM, err := os.ReadFile(decryptKeyPath)
+		if err != nil {
+			fmt.Printf("❌ Failed to read decryption key: %v\n", err)
+			os.Exit(1)
+		}
+		decryptKey, err := licverify.ParseDecryptionKey(string(decryptKeyPEM))
+		if err != nil {
+			fmt.Printf("❌ Failed to parse decryption key: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, licgen.WithDecryptionKey(decryptKey), licgen.WithEncryption(decryptKey.PublicKey()))
+	}
+	if fingerprintPath != "" {
+		verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+		fingerprint := readFingerprint(fingerprintPath, verifier)
+		opts = append(opts, licgen.WithMachineBinding(fingerprint.HardwareInfo()))
+	}
+	return opts
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licledger"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licserver"
+)
+
+// apiTokensEnv holds comma-separated API tokens for the serve command
+const apiTokensEnv = "LICFORGE_API_TOKENS"
+
+// serveAPI runs the license issuance REST API until interrupted
+func serveAPI(addr, privateKeyPath, ledgerPath, tokenFile, schemeName, serialSchemeSpec, serialCounterPath string, checkInLease time.Duration) {
+	fmt.Println("🌐 Starting license issuance server...")
+
+	if ledgerPath == "" {
+		fmt.Println("❌ The server requires an issuance ledger")
+		os.Exit(1)
+	}
+
+	// Parse signature scheme
+	scheme, err := licformat.ParseSignatureScheme(schemeName)
+	if err != nil {
+		fmt.Printf("❌ Invalid signature scheme: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Read private key
+	privateKeyPEM, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		fmt.Printf("❌ Failed to read private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Parse private key
+	privateKey, err := licgen.ParsePrivateKey(string(privateKeyPEM))
+	if err != nil {
+		fmt.Printf("❌ Failed to parse private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Read API tokens
+	tokens, err := readAPITokens(tokenFile)
+	if err != nil {
+		fmt.Printf("❌ Failed to read API tokens: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := []licserver.Option{licserver.WithSignatureScheme(scheme)}
+	if serialSchemeSpec != "" {
+		serialScheme, err := licgen.ParseSerialScheme(serialSchemeSpec)
+		if err != nil {
+			fmt.Printf("❌ Invalid serial scheme: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, licserver.WithSerialGenerator(
+			licgen.NewSerialGenerator(serialScheme, licgen.NewFileCounter(serialCounterPath))))
+	}
+
+	if checkInLease > 0 {
+		opts = append(opts, licserver.WithCheckIn(checkInLease))
+	}
+
+	handler, err := licserver.New(privateKey, licledger.Open(ledgerPath), tokens, opts...)
+	if err != nil {
+		fmt.Printf("❌ Failed to create server: %v\n", err)
+		os.Exit(1)
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	// Shut down gracefully on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("📒 Ledger: %s\n", ledgerPath)
+	fmt.Printf("🔑 %d API tokens loaded\n", len(tokens))
+	if checkInLease > 0 {
+		fmt.Printf("📡 Check-in enabled, leases valid for %s\n", checkInLease)
+	}
+	fmt.Printf("✅ Listening on %s\n", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("❌ Server failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("\n👋 Server stopped")
+}
+
+// readAPITokens reads API tokens from a file with one token per line and
+// from the LICFORGE_API_TOKENS environment variable
+func readAPITokens(tokenFile string) ([]string, error) {
+	var tokens []string
+	if tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				tokens = append(tokens, line)
+			}
+		}
+	}
+	tokens = append(tokens, parseCommaSeparatedList(os.Getenv(apiTokensEnv))...)
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no API tokens configured, use -token-file or %s", apiTokensEnv)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+)
+
+// generateTrialLicense generates a trial license that starts on first run
+func generateTrialLicense(licenseID, productID string, trialDays, availableDays int, featuresStr, privateKeyPath, outputPath, schemeName, ledgerPath string) {
+	fmt.Println("🧪 Generating trial license...")
+
+	if trialDays <= 0 {
+		fmt.Println("❌ Trial period must be at least one day")
+		os.Exit(1)
+	}
+	if availableDays < trialDays {
+		fmt.Println("❌ The license must be available for at least the trial period")
+		os.Exit(1)
+	}
+
+	// Parse signature scheme
+	scheme, err := licformat.ParseSignatureScheme(schemeName)
+	if err != nil {
+		fmt.Printf("❌ Invalid signature scheme: %v\n", err)
+		os.Exit(1)
+	}
+
+	if licenseID == "" {
+		if licenseID, err = licgen.GenerateID(licgen.IDFormatUUIDv7); err != nil {
+			fmt.Printf("❌ Failed to generate license ID: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Read private key
+	privateKeyPEM, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		fmt.Printf("❌ Failed to read private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Parse private key
+	privateKey, err := licgen.ParsePrivateKey(string(privateKeyPEM))
+	if err != nil {
+		fmt.Printf("❌ Failed to parse private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := append([]licgen.Option{licgen.WithSignatureScheme(scheme)}, ledgerOptions(ledgerPath)...)
+	licenseData, err := licgen.GenerateTrialLicense(
+		licenseID,
+		productID,
+		time.Duration(trialDays)*24*time.Hour,
+		time.Duration(availableDays)*24*time.Hour,
+		parseCommaSeparatedList(featuresStr),
+		privateKey,
+		opts...,
+	)
+	if err != nil {
+		fmt.Printf("❌ Failed to generate trial license: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Save license
+	if err := licgen.SaveLicenseToFile(licenseData, outputPath); err != nil {
+		fmt.Printf("❌ Failed to save license: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Trial license saved to: %s\n", outputPath)
+
+	// Print license information
+	printLicenseSummary(privateKey, licenseData)
+
+	fmt.Println("\n✨ Trial license generated successfully!")
+}
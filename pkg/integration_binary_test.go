@@ -1,9 +1,11 @@
 package pkg
 
 import (
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
 	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
 	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
 )
@@ -70,17 +72,17 @@ func TestBinaryLicenseIntegration(t *testing.T) {
 	}
 
 	// Verify the license fields
-	if license.ID != licenseID {
-		t.Errorf("License ID mismatch: expected %s, got %s", licenseID, license.ID)
+	if license.ID() != licenseID {
+		t.Errorf("License ID mismatch: expected %s, got %s", licenseID, license.ID())
 	}
-	if license.CustomerID != customerID {
-		t.Errorf("Customer ID mismatch: expected %s, got %s", customerID, license.CustomerID)
+	if license.CustomerID() != customerID {
+		t.Errorf("Customer ID mismatch: expected %s, got %s", customerID, license.CustomerID())
 	}
-	if license.ProductID != productID {
-		t.Errorf("Product ID mismatch: expected %s, got %s", productID, license.ProductID)
+	if license.ProductID() != productID {
+		t.Errorf("Product ID mismatch: expected %s, got %s", productID, license.ProductID())
 	}
-	if license.SerialNumber != serialNumber {
-		t.Errorf("Serial number mismatch: expected %s, got %s", serialNumber, license.SerialNumber)
+	if license.SerialNumber() != serialNumber {
+		t.Errorf("Serial number mismatch: expected %s, got %s", serialNumber, license.SerialNumber())
 	}
 
 	// Verify the license signature
@@ -177,3 +179,202 @@ func TestBinaryLicenseTampering(t *testing.T) {
 		}
 	}
 }
+
+func TestPSSLicenseIntegration(t *testing.T) {
+	// Generate a key pair for testing
+	privateKeyPEM, publicKeyPEM, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+
+	// Parse the private key
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	// Create a license verifier
+	verifier, err := licverify.NewVerifier(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+
+	// Generate a PSS-signed license
+	licenseData, err := licgen.GenerateLicense(
+		"test-license-pss-123",
+		"customer-456",
+		"product-789",
+		"SN-PSS",
+		365*24*time.Hour,
+		[]string{"feature1"},
+		licverify.HardwareBinding{},
+		privateKey,
+		licgen.WithSignatureScheme(licformat.SchemePSS),
+	)
+	if err != nil {
+		t.Fatalf("Failed to generate license: %v", err)
+	}
+
+	tempFile := t.TempDir() + "/test-license-pss.bin"
+	if err := licgen.SaveLicenseToFile(licenseData, tempFile); err != nil {
+		t.Fatalf("Failed to save license to file: %v", err)
+	}
+
+	license, err := verifier.LoadLicense(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to load license: %v", err)
+	}
+	if license.SignatureScheme() != licformat.SchemePSS {
+		t.Errorf("Signature scheme mismatch: expected %v, got %v", licformat.SchemePSS, license.SignatureScheme())
+	}
+	if err := verifier.VerifySignature(license); err != nil {
+		t.Errorf("Signature verification failed: %v", err)
+	}
+
+	// Declaring a different scheme in the header must not verify
+	licenseData[1] = byte(licformat.SchemePKCS1v15)
+	license, err = verifier.ParseLicense(licenseData)
+	if err != nil {
+		t.Fatalf("Failed to parse license: %v", err)
+	}
+	if err := verifier.VerifySignature(license); err == nil {
+		t.Errorf("Expected signature verification to fail with the wrong scheme")
+	}
+}
+
+func TestEncryptedLicenseIntegration(t *testing.T) {
+	// Generate the signing and encryption key pairs
+	privateKeyPEM, publicKeyPEM, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	decryptionKeyPEM, encryptionKeyPEM, err := licgen.GenerateEncryptionKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate encryption key pair: %v", err)
+	}
+	encryptionKey, err := licgen.ParseEncryptionPublicKey(encryptionKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse encryption key: %v", err)
+	}
+	decryptionKey, err := licverify.ParseDecryptionKey(decryptionKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse decryption key: %v", err)
+	}
+
+	// Generate an encrypted license
+	licenseData, err := licgen.GenerateLicense(
+		"test-license-encrypted-123",
+		"confidential-customer",
+		"product-789",
+		"SN-ENC",
+		365*24*time.Hour,
+		[]string{"feature1"},
+		licverify.HardwareBinding{},
+		privateKey,
+		licgen.WithEncryption(encryptionKey),
+		licgen.WithSignatureScheme(licformat.SchemePSS),
+	)
+	if err != nil {
+		t.Fatalf("Failed to generate license: %v", err)
+	}
+
+	// Without the decryption key the contents cannot be read
+	verifier, err := licverify.NewVerifier(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	if _, err := verifier.ParseLicense(licenseData); !errors.Is(err, licformat.ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted, got %v", err)
+	}
+
+	// With the decryption key the license loads and verifies
+	verifier, err = licverify.NewVerifier(publicKeyPEM, licverify.WithDecryptionKey(decryptionKey))
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	license, err := verifier.ParseLicense(licenseData)
+	if err != nil {
+		t.Fatalf("Failed to parse license: %v", err)
+	}
+	if !license.IsEncrypted() {
+		t.Errorf("Expected license to be reported as encrypted")
+	}
+	if license.CustomerID() != "confidential-customer" {
+		t.Errorf("Customer ID mismatch: expected confidential-customer, got %s", license.CustomerID())
+	}
+	if err := verifier.VerifySignature(license); err != nil {
+		t.Errorf("Signature verification failed: %v", err)
+	}
+}
+
+func TestMachineBoundLicenseIntegration(t *testing.T) {
+	privateKeyPEM, publicKeyPEM, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	verifier, err := licverify.NewVerifier(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+
+	// The activation hardware is the current machine
+	hwInfo, err := licverify.GetHardwareInfo()
+	if err != nil {
+		t.Fatalf("Failed to get hardware info: %v", err)
+	}
+
+	generate := func(hw *licverify.HardwareInfo) []byte {
+		licenseData, err := licgen.GenerateLicense(
+			"test-license-bound-123",
+			"customer-456",
+			"product-789",
+			"SN-BOUND",
+			365*24*time.Hour,
+			[]string{"feature1"},
+			licverify.HardwareBinding{},
+			privateKey,
+			licgen.WithMachineBinding(hw),
+		)
+		if err != nil {
+			t.Fatalf("Failed to generate license: %v", err)
+		}
+		return licenseData
+	}
+
+	// A license bound to this machine can be read here
+	license, err := verifier.ParseLicense(generate(hwInfo))
+	if err != nil {
+		t.Fatalf("Failed to parse machine-bound license: %v", err)
+	}
+	if !license.IsEncrypted() || license.CustomerID() != "customer-456" {
+		t.Errorf("Unexpected machine-bound license: encrypted=%v customer=%s", license.IsEncrypted(), license.CustomerID())
+	}
+	if err := verifier.VerifySignature(license); err != nil {
+		t.Errorf("Signature verification failed: %v", err)
+	}
+
+	// A license bound to another machine cannot be decoded
+	otherMachine := &licverify.HardwareInfo{
+		MACAddresses: []string{"00:11:22:33:44:55"},
+		DiskIDs:      []string{"other-disk"},
+		Hostname:     "other-host",
+	}
+	if _, err := verifier.ParseLicense(generate(otherMachine)); !errors.Is(err, licverify.ErrMachineBinding) {
+		t.Errorf("Expected ErrMachineBinding, got %v", err)
+	}
+
+	// Binding without hardware info is an error, not a panic
+	_, err = licgen.GenerateLicense("test-license-bound", "customer-456", "product-789", "SN-BOUND",
+		30*24*time.Hour, []string{"feature1"}, licverify.HardwareBinding{}, privateKey, licgen.WithMachineBinding(nil))
+	if err == nil {
+		t.Error("Expected machine binding without hardware info to fail")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
 	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
 	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
 )
@@ -92,31 +93,42 @@ func TestFullLicenseFlow(t *testing.T) {
 	}
 
 	// 11. Verify license data
-	if license.ID != licenseID {
-		t.Errorf("License ID mismatch: expected %s, got %s", licenseID, license.ID)
+	if license.ID() != licenseID {
+		t.Errorf("License ID mismatch: expected %s, got %s", licenseID, license.ID())
 	}
-	if license.CustomerID != customerID {
-		t.Errorf("Customer ID mismatch: expected %s, got %s", customerID, license.CustomerID)
+	if license.CustomerID() != customerID {
+		t.Errorf("Customer ID mismatch: expected %s, got %s", customerID, license.CustomerID())
 	}
-	if license.ProductID != productID {
-		t.Errorf("Product ID mismatch: expected %s, got %s", productID, license.ProductID)
+	if license.ProductID() != productID {
+		t.Errorf("Product ID mismatch: expected %s, got %s", productID, license.ProductID())
 	}
 
 	// 12. Verify features
-	if len(license.Features) != len(features) {
-		t.Errorf("Feature count mismatch: expected %d, got %d", len(features), len(license.Features))
+	if len(license.Features()) != len(features) {
+		t.Errorf("Feature count mismatch: expected %d, got %d", len(features), len(license.Features()))
 	}
 	for i, feature := range features {
-		if i < len(license.Features) && license.Features[i] != feature {
-			t.Errorf("Feature mismatch at index %d: expected %s, got %s", i, feature, license.Features[i])
+		if i < len(license.Features()) && license.Features()[i] != feature {
+			t.Errorf("Feature mismatch at index %d: expected %s, got %s", i, feature, license.Features()[i])
 		}
 	}
 
 	// 13. Test tampering detection
 	t.Run("TamperingDetection", func(t *testing.T) {
-		// Create a copy of the license with a modified expiry date
-		tamperedLicense := *license
-		tamperedLicense.ExpiryDate = time.Now().AddDate(10, 0, 0) // 10 years in the future
+		// Re-encode the license data with a modified expiry date, keeping the original signature
+		signedData, err := licformat.DecodeLicense(license.SignedData())
+		if err != nil {
+			t.Fatalf("Failed to decode license data: %v", err)
+		}
+		signedData.ExpiryDate = time.Now().AddDate(10, 0, 0) // 10 years in the future
+		tamperedData, err := licformat.EncodeLicense(signedData)
+		if err != nil {
+			t.Fatalf("Failed to encode tampered license: %v", err)
+		}
+		tamperedLicense, err := verifier.ParseLicense(append(tamperedData, license.Signature()...))
+		if err != nil {
+			t.Fatalf("Failed to parse tampered license: %v", err)
+		}
 
 		// Verify the tampered license (should fail)
 		err = tamperedLicense.IsValid(verifier)
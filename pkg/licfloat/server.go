@@ -0,0 +1,364 @@
+// Package licfloat serves floating licenses to a limited number of
+// concurrent users.
+//
+// The seat server holds a pool license generated with licgen.WithSeats and
+// hands out time-limited seat leases, signed with the server's own RSA key,
+// to licverify.SeatClient instances. Leases that are not renewed by a
+// heartbeat expire and their seats return to the pool.
+//
+// The pool license binds the server's public key (licgen.WithSeatServer),
+// so servers started with another key cannot serve it. Seats are persisted
+// to a state file (WithStateFile) that is locked while the server runs, so
+// a restart keeps the seats in use and a second server for the same state
+// fails to start. Bind the pool license to the server's machine to keep
+// copies of the license and key from being served elsewhere.
+//
+// The protocol is line-delimited JSON over TCP: each connection carries one
+// licverify.SeatRequest and one licverify.SeatResponse.
+package licfloat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// maxClientLength bounds the client description of requests
+const maxClientLength = 256
+
+// Seat describes a seat in use
+type Seat struct {
+	ID        string    `json:"id"`
+	Client    string    `json:"client"`
+	Acquired  time.Time `json:"acquired"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Server hands out the seats of a floating license. It is safe for concurrent use.
+type Server struct {
+	license       *licverify.License
+	licenseData   []byte
+	privateKey    *rsa.PrivateKey
+	leaseDuration time.Duration
+	timeout       time.Duration
+	statePath     string
+	unlock        func()
+
+	mu    sync.Mutex
+	seats map[string]*Seat
+	conns map[net.Conn]struct{}
+}
+
+// Option configures optional behaviour of the server
+type Option func(*Server)
+
+// WithLeaseDuration sets how long a seat lease is valid without a heartbeat
+// (default 2 minutes)
+func WithLeaseDuration(d time.Duration) Option {
+	return func(s *Server) {
+		s.leaseDuration = d
+	}
+}
+
+// WithConnectionTimeout bounds how long a client may take to send its
+// request and read the response (default 30 seconds)
+func WithConnectionTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.timeout = d
+	}
+}
+
+// WithStateFile persists the seats in use to path, so that they survive a
+// restart. The state file is locked until Close; a server for a state file
+// that is already locked fails to start, unless the process that holds the
+// lock has exited.
+func WithStateFile(path string) Option {
+	return func(s *Server) {
+		s.statePath = path
+	}
+}
+
+// New creates a seat server for the pool license in licenseData, which must
+// pass all checks of verifier on this machine and be bound to the public key
+// of privateKey. Seat leases are signed with privateKey.
+func New(licenseData []byte, verifier *licverify.Verifier, privateKey *rsa.PrivateKey, opts ...Option) (*Server, error) {
+	if verifier == nil || privateKey == nil {
+		return nil, errors.New("verifier and private key are required")
+	}
+
+	license, err := verifier.ParseLicense(licenseData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pool license: %w", err)
+	}
+	if err := license.IsValid(verifier); err != nil {
+		return nil, fmt.Errorf("invalid pool license: %w", err)
+	}
+	if license.Seats() == 0 {
+		return nil, errors.New("invalid pool license: not a floating license")
+	}
+	serverID, err := licverify.SeatServerKeyID(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(license.SeatServerKey(), serverID) {
+		return nil, errors.New("invalid pool license: not bound to this seat server key")
+	}
+
+	s := &Server{
+		license:       license,
+		licenseData:   append([]byte(nil), licenseData...),
+		privateKey:    privateKey,
+		leaseDuration: 2 * time.Minute,
+		timeout:       30 * time.Second,
+		seats:         make(map[string]*Seat),
+		conns:         make(map[net.Conn]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.leaseDuration <= 0 {
+		return nil, errors.New("lease duration must be positive")
+	}
+	if s.statePath != "" {
+		if err := s.lockState(); err != nil {
+			return nil, err
+		}
+		if err := s.loadState(time.Now()); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Close releases the state file lock. The seats stay in the state file.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.unlock != nil {
+		s.unlock()
+		s.unlock = nil
+	}
+	return nil
+}
+
+// License returns the pool license
+func (s *Server) License() *licverify.License {
+	return s.license
+}
+
+// Seats returns the seats in use, oldest first
+func (s *Server) Seats() []Seat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireSeats(time.Now())
+	seats := make([]Seat, 0, len(s.seats))
+	for _, seat := range s.seats {
+		seats = append(seats, *seat)
+	}
+	sort.Slice(seats, func(i, j int) bool {
+		return seats[i].Acquired.Before(seats[j].Acquired)
+	})
+	return seats
+}
+
+// Serve accepts connections on ln until ctx is cancelled. It closes ln and
+// open connections before returning.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	stop := context.AfterFunc(ctx, func() {
+		ln.Close()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// serveConn answers the request on one connection
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	var resp *licverify.SeatResponse
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), licverify.MaxSeatMessageSize)
+	if !scanner.Scan() {
+		if scanner.Err() == nil {
+			return
+		}
+		resp = errorResponse(licverify.SeatErrorInvalid, "request too large or unreadable")
+	} else {
+		var req licverify.SeatRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp = errorResponse(licverify.SeatErrorInvalid, fmt.Sprintf("invalid request: %v", err))
+		} else {
+			resp = s.Handle(&req)
+		}
+	}
+
+	line, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	conn.Write(append(line, '\n'))
+}
+
+// Handle answers a seat request
+func (s *Server) Handle(req *licverify.SeatRequest) *licverify.SeatResponse {
+	if len(req.Client) > maxClientLength || len(req.SeatID) > maxClientLength || len(req.Nonce) > maxClientLength {
+		return errorResponse(licverify.SeatErrorInvalid, "request field too long")
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireSeats(now)
+
+	switch req.Op {
+	case licverify.SeatAcquire:
+		if !now.Before(s.license.ExpiryDate()) {
+			return errorResponse(licverify.SeatErrorUnavailable, "pool license has expired")
+		}
+		if len(s.seats) >= int(s.license.Seats()) {
+			return errorResponse(licverify.SeatErrorNoSeats,
+				fmt.Sprintf("all %d seats are in use", s.license.Seats()))
+		}
+		id, err := newSeatID()
+		if err != nil {
+			return errorResponse(licverify.SeatErrorUnavailable, err.Error())
+		}
+		seat := &Seat{ID: id, Client: req.Client, Acquired: now}
+		resp := s.lease(seat, req.Nonce, now)
+		if resp.ErrorCode != "" {
+			return resp
+		}
+		s.seats[id] = seat
+		if err := s.saveState(); err != nil {
+			delete(s.seats, id)
+			return errorResponse(licverify.SeatErrorUnavailable, err.Error())
+		}
+		resp.License = s.licenseData
+		return resp
+
+	case licverify.SeatHeartbeat:
+		seat, ok := s.seats[req.SeatID]
+		if !ok {
+			return errorResponse(licverify.SeatErrorUnknownSeat, "seat expired or was released")
+		}
+		resp := s.lease(seat, req.Nonce, now)
+		if resp.ErrorCode != "" {
+			return resp
+		}
+		if err := s.saveState(); err != nil {
+			return errorResponse(licverify.SeatErrorUnavailable, err.Error())
+		}
+		return resp
+
+	case licverify.SeatRelease:
+		if _, ok := s.seats[req.SeatID]; !ok {
+			return errorResponse(licverify.SeatErrorUnknownSeat, "seat expired or was released")
+		}
+		delete(s.seats, req.SeatID)
+		if err := s.saveState(); err != nil {
+			return errorResponse(licverify.SeatErrorUnavailable, err.Error())
+		}
+		return &licverify.SeatResponse{}
+
+	default:
+		return errorResponse(licverify.SeatErrorInvalid, fmt.Sprintf("unknown operation %q", req.Op))
+	}
+}
+
+// lease signs a lease for the seat and extends its expiry. The lease never
+// outlives the pool license.
+func (s *Server) lease(seat *Seat, nonce string, now time.Time) *licverify.SeatResponse {
+	expires := now.Add(s.leaseDuration)
+	if expires.After(s.license.ExpiryDate()) {
+		expires = s.license.ExpiryDate()
+	}
+
+	signed, err := licgen.SignSeatLease(&licverify.SeatLease{
+		LicenseID: s.license.ID(),
+		SeatID:    seat.ID,
+		Client:    seat.Client,
+		Nonce:     nonce,
+		IssuedAt:  now,
+		ExpiresAt: expires,
+	}, s.privateKey)
+	if err != nil {
+		return errorResponse(licverify.SeatErrorUnavailable, err.Error())
+	}
+
+	seat.ExpiresAt = expires
+	return &licverify.SeatResponse{Lease: signed}
+}
+
+// expireSeats frees seats whose lease has expired
+func (s *Server) expireSeats(now time.Time) {
+	for id, seat := range s.seats {
+		if !now.Before(seat.ExpiresAt) {
+			delete(s.seats, id)
+		}
+	}
+}
+
+func errorResponse(code, message string) *licverify.SeatResponse {
+	return &licverify.SeatResponse{Error: message, ErrorCode: code}
+}
+
+// newSeatID returns a random seat ID
+func newSeatID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate seat ID: %v", err)
+	}
+	return hex.EncodeToString(id), nil
+}
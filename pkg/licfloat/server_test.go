@@ -0,0 +1,366 @@
+package licfloat_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licfloat"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// newKey generates an RSA key for tests
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	return privateKey
+}
+
+// newPool generates a pool license with the given seats bound to serverKey
+func newPool(t *testing.T, vendorKey, serverKey *rsa.PrivateKey, seats uint32) []byte {
+	t.Helper()
+
+	pool, err := licgen.GenerateLicense("POOL-1", "ACME", "CAD", "SN-1", 30*24*time.Hour,
+		[]string{"modeling"}, licverify.HardwareBinding{}, vendorKey,
+		licgen.WithSeats(seats), licgen.WithSeatServer(&serverKey.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to generate pool license: %v", err)
+	}
+	return pool
+}
+
+// startServer runs a seat server for a pool license with the given seats
+func startServer(t *testing.T, vendorKey, serverKey *rsa.PrivateKey, seats uint32, opts ...licfloat.Option) (*licfloat.Server, string) {
+	t.Helper()
+
+	pool := newPool(t, vendorKey, serverKey, seats)
+	server, err := licfloat.New(pool, licverify.NewVerifierFromPublicKey(&vendorKey.PublicKey), serverKey, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create seat server: %v", err)
+	}
+	return server, serve(t, server)
+}
+
+// serve runs server until the test ends and returns its address
+func serve(t *testing.T, server *licfloat.Server) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve failed: %v", err)
+		}
+		server.Close()
+	})
+	return ln.Addr().String()
+}
+
+func TestSeatServer(t *testing.T) {
+	vendorKey, serverKey := newKey(t), newKey(t)
+	server, addr := startServer(t, vendorKey, serverKey, 2, licfloat.WithLeaseDuration(time.Minute))
+	verifier := licverify.NewVerifierFromPublicKey(&vendorKey.PublicKey)
+	ctx := context.Background()
+
+	newClient := func(name string) *licverify.SeatClient {
+		t.Helper()
+		client, err := licverify.NewSeatClient(verifier, addr, &serverKey.PublicKey, licverify.WithClientName(name))
+		if err != nil {
+			t.Fatalf("Failed to create seat client: %v", err)
+		}
+		return client
+	}
+
+	alice, bob, carol := newClient("alice"), newClient("bob"), newClient("carol")
+	for _, client := range []*licverify.SeatClient{alice, bob} {
+		if _, err := client.Acquire(ctx); err != nil {
+			t.Fatalf("Failed to acquire seat: %v", err)
+		}
+	}
+	if license := alice.License(); license == nil || license.ID() != "POOL-1" || license.Seats() != 2 || !license.HasFeature("modeling") {
+		t.Errorf("Unexpected pool license: %+v", license)
+	}
+	if _, err := alice.Acquire(ctx); err == nil {
+		t.Error("Expected error when acquiring a second seat with one client")
+	}
+
+	// All seats are in use
+	if _, err := carol.Acquire(ctx); !errors.Is(err, licverify.ErrNoSeats) {
+		t.Errorf("Expected ErrNoSeats, got %v", err)
+	}
+	seats := server.Seats()
+	if len(seats) != 2 || seats[0].Client != "alice" || seats[1].Client != "bob" {
+		t.Errorf("Unexpected seats: %+v", seats)
+	}
+
+	// Heartbeats extend the lease of the same seat
+	before := bob.Lease()
+	after, err := bob.Heartbeat(ctx)
+	if err != nil {
+		t.Fatalf("Heartbeat failed: %v", err)
+	}
+	if after.SeatID != before.SeatID || after.ExpiresAt.Before(before.ExpiresAt) {
+		t.Errorf("Unexpected heartbeat lease: %+v after %+v", after, before)
+	}
+
+	// A released seat can be acquired by another client
+	if err := alice.Release(ctx); err != nil {
+		t.Fatalf("Failed to release seat: %v", err)
+	}
+	if _, err := alice.Heartbeat(ctx); !errors.Is(err, licverify.ErrNoSeat) {
+		t.Errorf("Expected ErrNoSeat after release, got %v", err)
+	}
+	if _, err := carol.Acquire(ctx); err != nil {
+		t.Errorf("Failed to acquire released seat: %v", err)
+	}
+
+	// Servers with another key are rejected
+	impostor, err := licverify.NewSeatClient(verifier, addr, &newKey(t).PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to create seat client: %v", err)
+	}
+	bob.Release(ctx)
+	if _, err := impostor.Acquire(ctx); err == nil || !strings.Contains(err.Error(), "not bound to this seat server key") {
+		t.Errorf("Expected error for a pool license bound to another key, got %v", err)
+	}
+}
+
+// TestSeatClientConcurrentAcquire tests that concurrent Acquire calls of
+// one client take a single seat
+func TestSeatClientConcurrentAcquire(t *testing.T) {
+	vendorKey, serverKey := newKey(t), newKey(t)
+	server, addr := startServer(t, vendorKey, serverKey, 5)
+	client, err := licverify.NewSeatClient(licverify.NewVerifierFromPublicKey(&vendorKey.PublicKey), addr, &serverKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to create seat client: %v", err)
+	}
+
+	const calls = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	acquired := 0
+	for range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Acquire(context.Background()); err == nil {
+				mu.Lock()
+				acquired++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if acquired != 1 {
+		t.Errorf("Expected one successful Acquire, got %d", acquired)
+	}
+	if seats := server.Seats(); len(seats) != 1 {
+		t.Errorf("Expected one seat in use, got %d", len(seats))
+	}
+}
+
+func TestSeatLeaseExpiry(t *testing.T) {
+	vendorKey, serverKey := newKey(t), newKey(t)
+	server, addr := startServer(t, vendorKey, serverKey, 1, licfloat.WithLeaseDuration(300*time.Millisecond))
+	verifier := licverify.NewVerifierFromPublicKey(&vendorKey.PublicKey)
+	ctx := context.Background()
+
+	client, err := licverify.NewSeatClient(verifier, addr, &serverKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to create seat client: %v", err)
+	}
+	other, err := licverify.NewSeatClient(verifier, addr, &serverKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to create seat client: %v", err)
+	}
+
+	// A seat without heartbeats returns to the pool
+	if _, err := client.Acquire(ctx); err != nil {
+		t.Fatalf("Failed to acquire seat: %v", err)
+	}
+	time.Sleep(400 * time.Millisecond)
+	if len(server.Seats()) != 0 {
+		t.Error("Expired seat is still in use")
+	}
+	if _, err := client.Heartbeat(ctx); !errors.Is(err, licverify.ErrSeatLost) {
+		t.Errorf("Expected ErrSeatLost, got %v", err)
+	}
+	if client.Lease() != nil {
+		t.Error("Lost seat was kept")
+	}
+
+	// Hold keeps the seat beyond the lease duration and releases it when cancelled
+	if _, err := client.Acquire(ctx); err != nil {
+		t.Fatalf("Failed to acquire seat: %v", err)
+	}
+	holdCtx, cancel := context.WithCancel(ctx)
+	held := make(chan error, 1)
+	go func() { held <- client.Hold(holdCtx) }()
+
+	time.Sleep(700 * time.Millisecond)
+	if _, err := other.Acquire(ctx); !errors.Is(err, licverify.ErrNoSeats) {
+		t.Errorf("Expected held seat to stay in use, got %v", err)
+	}
+	cancel()
+	if err := <-held; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled from Hold, got %v", err)
+	}
+	if len(server.Seats()) != 0 {
+		t.Error("Hold did not release the seat")
+	}
+}
+
+func TestSeatServerRequiresFloatingLicense(t *testing.T) {
+	vendorKey := newKey(t)
+	license, err := licgen.GenerateLicense("LIC-1", "ACME", "CAD", "SN-1", 30*24*time.Hour,
+		nil, licverify.HardwareBinding{}, vendorKey)
+	if err != nil {
+		t.Fatalf("Failed to generate license: %v", err)
+	}
+
+	verifier := licverify.NewVerifierFromPublicKey(&vendorKey.PublicKey)
+	if _, err := licfloat.New(license, verifier, vendorKey); err == nil {
+		t.Error("Expected error for a license without seats")
+	}
+}
+
+func TestSeatServerKeyBinding(t *testing.T) {
+	vendorKey, serverKey := newKey(t), newKey(t)
+	verifier := licverify.NewVerifierFromPublicKey(&vendorKey.PublicKey)
+
+	// Floating licenses must bind a seat server key
+	if _, err := licgen.GenerateLicense("POOL-1", "ACME", "CAD", "SN-1", 30*24*time.Hour,
+		nil, licverify.HardwareBinding{}, vendorKey, licgen.WithSeats(5)); err == nil {
+		t.Error("Expected error for a floating license without a seat server key")
+	}
+
+	// A server started with another key cannot serve the pool license
+	pool := newPool(t, vendorKey, serverKey, 5)
+	if _, err := licfloat.New(pool, verifier, newKey(t)); err == nil {
+		t.Error("Expected error for a seat server key the pool license does not bind")
+	}
+	server, err := licfloat.New(pool, verifier, serverKey)
+	if err != nil {
+		t.Fatalf("Failed to create seat server: %v", err)
+	}
+	server.Close()
+}
+
+func TestSeatServerState(t *testing.T) {
+	vendorKey, serverKey := newKey(t), newKey(t)
+	verifier := licverify.NewVerifierFromPublicKey(&vendorKey.PublicKey)
+	pool := newPool(t, vendorKey, serverKey, 1)
+	statePath := filepath.Join(t.TempDir(), "seats.json")
+
+	first, err := licfloat.New(pool, verifier, serverKey, licfloat.WithStateFile(statePath))
+	if err != nil {
+		t.Fatalf("Failed to create seat server: %v", err)
+	}
+
+	// A second server for the same state does not start
+	if _, err := licfloat.New(pool, verifier, serverKey, licfloat.WithStateFile(statePath)); err == nil {
+		t.Fatal("Expected error for a locked seat state")
+	}
+
+	resp := first.Handle(&licverify.SeatRequest{Op: licverify.SeatAcquire, Client: "alice"})
+	if resp.ErrorCode != "" {
+		t.Fatalf("Failed to acquire seat: %s", resp.Error)
+	}
+	lease, err := licverify.VerifySeatLease(resp.Lease, &serverKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Invalid seat lease: %v", err)
+	}
+	first.Close()
+
+	// A restarted server keeps the seat in use
+	second, err := licfloat.New(pool, verifier, serverKey, licfloat.WithStateFile(statePath))
+	if err != nil {
+		t.Fatalf("Failed to restart seat server: %v", err)
+	}
+	defer second.Close()
+	seats := second.Seats()
+	if len(seats) != 1 || seats[0].ID != lease.SeatID || seats[0].Client != "alice" || !seats[0].ExpiresAt.Equal(lease.ExpiresAt) {
+		t.Fatalf("Unexpected seats after restart: %+v", seats)
+	}
+	if resp := second.Handle(&licverify.SeatRequest{Op: licverify.SeatAcquire, Client: "bob"}); resp.ErrorCode != licverify.SeatErrorNoSeats {
+		t.Errorf("Expected no seats after restart, got %+v", resp)
+	}
+	if resp := second.Handle(&licverify.SeatRequest{Op: licverify.SeatHeartbeat, SeatID: lease.SeatID}); resp.ErrorCode != "" {
+		t.Errorf("Heartbeat after restart failed: %s", resp.Error)
+	}
+
+	// A released seat is released for good
+	if resp := second.Handle(&licverify.SeatRequest{Op: licverify.SeatRelease, SeatID: lease.SeatID}); resp.ErrorCode != "" {
+		t.Fatalf("Failed to release seat: %s", resp.Error)
+	}
+	second.Close()
+	third, err := licfloat.New(pool, verifier, serverKey, licfloat.WithStateFile(statePath))
+	if err != nil {
+		t.Fatalf("Failed to restart seat server: %v", err)
+	}
+	defer third.Close()
+	if seats := third.Seats(); len(seats) != 0 {
+		t.Errorf("Released seat was restored: %+v", seats)
+	}
+}
+
+// TestSeatServerStaleLock tests that the lock of a seat server that was
+// killed is taken over, while the lock of a running process is kept
+func TestSeatServerStaleLock(t *testing.T) {
+	vendorKey, serverKey := newKey(t), newKey(t)
+	verifier := licverify.NewVerifierFromPublicKey(&vendorKey.PublicKey)
+	pool := newPool(t, vendorKey, serverKey, 1)
+	statePath := filepath.Join(t.TempDir(), "seats.json")
+	lockPath := statePath + ".lock"
+
+	// A process that has exited, like a seat server killed with SIGKILL
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	if err := exited.Run(); err != nil {
+		t.Fatalf("Failed to run process: %v", err)
+	}
+	for owner, running := range map[int]bool{exited.Process.Pid: false, os.Getpid(): true} {
+		if err := os.WriteFile(lockPath, []byte(strconv.Itoa(owner)+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		server, err := licfloat.New(pool, verifier, serverKey, licfloat.WithStateFile(statePath))
+		if running {
+			if err == nil {
+				server.Close()
+				t.Error("Expected error for a lock held by a running process")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to take over a stale lock: %v", err)
+		}
+		if data, err := os.ReadFile(lockPath); err != nil || strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+			t.Errorf("Lock file does not hold the server's process ID: %q, %v", data, err)
+		}
+		server.Close()
+	}
+}
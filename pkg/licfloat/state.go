@@ -0,0 +1,155 @@
+package licfloat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// state is the content of the state file
+type state struct {
+	LicenseID string  `json:"license_id"`
+	Seats     []*Seat `json:"seats"`
+}
+
+// lockState creates the lock file next to the state file. Unlike the serial
+// counter lock it is held for the lifetime of the server. The lock file
+// holds the process ID of the server, and a lock left behind by a server
+// that crashed or was killed is taken over once that process has exited.
+func (s *Server) lockState() error {
+	if dir := filepath.Dir(s.statePath); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create seat state directory: %v", err)
+		}
+	}
+
+	lockPath := s.statePath + ".lock"
+	for attempt := 0; ; attempt++ {
+		file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = file.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(lockPath)
+				return fmt.Errorf("failed to lock seat state: %v", err)
+			}
+			s.unlock = func() { os.Remove(lockPath) }
+			return nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("failed to lock seat state: %v", err)
+		}
+
+		owner, stale := staleLock(lockPath)
+		if !stale || attempt > 0 {
+			return fmt.Errorf("seat state %s is locked by seat server process %s (remove %s if it is not a seat server)", s.statePath, owner, lockPath)
+		}
+		// Check again right before taking over, in case another server
+		// took over the lock in the meantime
+		if current, _ := os.ReadFile(lockPath); strings.TrimSpace(string(current)) != owner {
+			continue
+		}
+		if err := os.Remove(lockPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to take over stale seat state lock: %v", err)
+		}
+	}
+}
+
+// staleLock returns the process ID recorded in a lock file and whether that
+// process has exited. Lock files without a process ID are not stale, since
+// their owner may still be writing it.
+func staleLock(lockPath string) (owner string, stale bool) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return "unknown", errors.Is(err, os.ErrNotExist)
+	}
+	owner = strings.TrimSpace(string(data))
+	pid, err := strconv.Atoi(owner)
+	if err != nil || pid <= 0 {
+		return "unknown", false
+	}
+	return owner, !processRunning(pid)
+}
+
+// processRunning reports whether a process with the given ID exists
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// On Windows FindProcess fails for processes that do not exist
+	if runtime.GOOS == "windows" {
+		process.Release()
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// loadState restores the seats of the state file that have not expired.
+// Seats recorded for another pool license are dropped.
+func (s *Server) loadState(now time.Time) error {
+	data, err := os.ReadFile(s.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read seat state: %v", err)
+	}
+
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("failed to parse seat state %s: %v", s.statePath, err)
+	}
+	if st.LicenseID != s.license.ID() {
+		return nil
+	}
+	for _, seat := range st.Seats {
+		if seat == nil || seat.ID == "" {
+			return fmt.Errorf("failed to parse seat state %s: seat without ID", s.statePath)
+		}
+		s.seats[seat.ID] = seat
+	}
+	s.expireSeats(now)
+	return nil
+}
+
+// saveState writes the seats in use to the state file, if any. The caller
+// must hold s.mu.
+func (s *Server) saveState() error {
+	if s.statePath == "" {
+		return nil
+	}
+
+	st := state{LicenseID: s.license.ID(), Seats: make([]*Seat, 0, len(s.seats))}
+	for _, seat := range s.seats {
+		st.Seats = append(st.Seats, seat)
+	}
+	sort.Slice(st.Seats, func(i, j int) bool {
+		return st.Seats[i].Acquired.Before(st.Seats[j].Acquired)
+	})
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode seat state: %v", err)
+	}
+
+	// Write atomically so a crash never leaves a truncated state file
+	tmp := s.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write seat state: %v", err)
+	}
+	if err := os.Rename(tmp, s.statePath); err != nil {
+		return fmt.Errorf("failed to write seat state: %v", err)
+	}
+	return nil
+}
@@ -1,6 +1,10 @@
 package licformat
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -10,15 +14,21 @@ import (
 // License represents the structure of a license from the licverify package
 // This is a copy of the struct to avoid import cycles
 type License struct {
-	ID           string
-	CustomerID   string
-	ProductID    string
-	SerialNumber string
-	IssueDate    time.Time
-	ExpiryDate   time.Time
-	Features     []string
-	HardwareIDs  HardwareBinding
-	Signature    []byte
+	ID            string
+	CustomerID    string
+	ProductID     string
+	SerialNumber  string
+	IssueDate     time.Time
+	ExpiryDate    time.Time
+	Features      []string
+	HardwareIDs   HardwareBinding
+	Signature     []byte
+	Scheme        SignatureScheme
+	PreviousID    string
+	Seats         uint32
+	SeatServerKey []byte
+	TrialDuration time.Duration
+	Quotas        map[string]uint64
 }
 
 // HardwareBinding is a copy of the struct from licverify
@@ -27,6 +37,7 @@ type HardwareBinding struct {
 	DiskIDs      []string
 	HostNames    []string
 	CustomIDs    []string
+	ContainerIDs []string
 }
 
 // ToLicenseData converts a License to LicenseData
@@ -44,7 +55,14 @@ func ToLicenseData(license *License) *LicenseData {
 			DiskIDs:      license.HardwareIDs.DiskIDs,
 			HostNames:    license.HardwareIDs.HostNames,
 			CustomIDs:    license.HardwareIDs.CustomIDs,
+			ContainerIDs: license.HardwareIDs.ContainerIDs,
 		},
+		PreviousID:    license.PreviousID,
+		Seats:         license.Seats,
+		SeatServerKey: license.SeatServerKey,
+		TrialDuration: license.TrialDuration,
+		Quotas:        license.Quotas,
+		Scheme:        license.Scheme,
 	}
 }
 
@@ -63,7 +81,14 @@ func FromLicenseData(data *LicenseData) *License {
 			DiskIDs:      data.HardwareIDs.DiskIDs,
 			HostNames:    data.HardwareIDs.HostNames,
 			CustomIDs:    data.HardwareIDs.CustomIDs,
+			ContainerIDs: data.HardwareIDs.ContainerIDs,
 		},
+		PreviousID:    data.PreviousID,
+		Seats:         data.Seats,
+		SeatServerKey: data.SeatServerKey,
+		TrialDuration: data.TrialDuration,
+		Quotas:        data.Quotas,
+		Scheme:        data.Scheme,
 	}
 }
 
@@ -81,3 +106,48 @@ func DecodeLicense(data []byte) (*License, error) {
 	}
 	return FromLicenseData(licenseData), nil
 }
+
+// SplitLicenseFile splits a license file into the encoded license, as
+// declared by its header, and the signature that follows it. The signature
+// is not verified.
+func SplitLicenseFile(data []byte) (encoded, signature []byte, err error) {
+	var headerSize int
+	var length uint32
+	switch {
+	case len(data) > 0 && data[0] == versionPKCS1:
+		var h headerV1
+		if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &h); err != nil {
+			return nil, nil, fmt.Errorf("failed to read header: %v", err)
+		}
+		headerSize, length = binary.Size(h), h.Length
+	case len(data) > 0 && data[0] == currentVersion:
+		var h header
+		if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &h); err != nil {
+			return nil, nil, fmt.Errorf("failed to read header: %v", err)
+		}
+		headerSize, length = binary.Size(h), h.Length
+	default:
+		return nil, nil, errors.New("unsupported license format version")
+	}
+
+	size := int64(headerSize) + int64(length)
+	if size >= int64(len(data)) {
+		return nil, nil, errors.New("license file has no signature")
+	}
+	return data[:size], data[size:], nil
+}
+
+// DecodeLicenseFile decodes a license file, that is an encoded license
+// followed by its signature, without verifying the signature
+func DecodeLicenseFile(data []byte) (*License, error) {
+	encoded, signature, err := SplitLicenseFile(data)
+	if err != nil {
+		return nil, err
+	}
+	license, err := DecodeLicense(encoded)
+	if err != nil {
+		return nil, err
+	}
+	license.Signature = append([]byte(nil), signature...)
+	return license, nil
+}
@@ -4,21 +4,89 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // BinaryFormat implements a binary serialization format for licenses
 // This is an internal implementation that doesn't change the public API
 
-// Format version to ensure compatibility
-const currentVersion byte = 1
+// Format versions to ensure compatibility
+const (
+	// versionPKCS1 is the original v2.0.x layout, always signed with PKCS#1 v1.5
+	versionPKCS1 byte = 1
+	// currentVersion records the signature scheme in the header
+	currentVersion byte = 2
+)
+
+// SignatureScheme identifies the algorithm used to sign a license
+type SignatureScheme byte
+
+const (
+	// SchemePKCS1v15 is RSASSA-PKCS1-v1_5 with SHA-256 (default)
+	SchemePKCS1v15 SignatureScheme = 1
+	// SchemePSS is RSASSA-PSS with SHA-256
+	SchemePSS SignatureScheme = 2
+)
+
+// String returns the name of the signature scheme
+func (s SignatureScheme) String() string {
+	switch s {
+	case SchemePKCS1v15:
+		return "pkcs1v15"
+	case SchemePSS:
+		return "pss"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(s))
+	}
+}
+
+// ParseSignatureScheme parses a signature scheme name as returned by String
+func ParseSignatureScheme(name string) (SignatureScheme, error) {
+	switch strings.ToLower(name) {
+	case "pkcs1v15", "pkcs1":
+		return SchemePKCS1v15, nil
+	case "pss":
+		return SchemePSS, nil
+	default:
+		return 0, fmt.Errorf("unknown signature scheme %q", name)
+	}
+}
+
+// Size limits of the binary format
+const (
+	// MaxStringLength is the maximum length in bytes of a string field
+	MaxStringLength = math.MaxUint16
+	// MaxSliceLength is the maximum number of entries in a list field
+	MaxSliceLength = 1024
+)
+
+// Errors returned when license data cannot be encoded
+var (
+	// ErrFieldTooLarge is returned when a field exceeds MaxStringLength or MaxSliceLength
+	ErrFieldTooLarge = errors.New("license field too large")
+	// ErrInvalidField is returned when a field cannot be represented in the binary format
+	ErrInvalidField = errors.New("invalid license field")
+)
 
 // Header for the binary format
 type header struct {
 	Version byte
+	Scheme  SignatureScheme
+	Flags   byte   // Header flags, see FlagEncrypted and FlagExtensions
 	Length  uint32 // Length of the license data (excluding signature)
 }
 
+// headerV1 is the header layout of version 1 licenses
+type headerV1 struct {
+	Version byte
+	Length  uint32
+}
+
 // LicenseData holds the data for a license in a format-agnostic way
 type LicenseData struct {
 	ID           string
@@ -29,6 +97,33 @@ type LicenseData struct {
 	ExpiryDate   time.Time
 	Features     []string
 	HardwareIDs  HardwareBindingData
+
+	// PreviousID is the ID of the license this one renews or replaces.
+	// It is stored as an extension, see FlagExtensions.
+	PreviousID string
+
+	// Seats is the number of concurrent users of a floating license,
+	// zero for licenses that are not floating. It is stored as an extension.
+	Seats uint32
+
+	// SeatServerKey is the SHA-256 hash of the PKIX encoded public key of
+	// the seat server allowed to serve a floating license. It is stored as
+	// an extension.
+	SeatServerKey []byte
+
+	// TrialDuration is the length of a trial that starts when the license
+	// is first used, zero for licenses that are not trials. It is stored
+	// in whole seconds as an extension.
+	TrialDuration time.Duration
+
+	// Quotas maps metered features to the number of units the license
+	// allows them to consume, see licverify.UsageMeter. It is stored as an
+	// extension.
+	Quotas map[string]uint64
+
+	// Scheme is the signature scheme declared in the header.
+	// The zero value is encoded as SchemePKCS1v15.
+	Scheme SignatureScheme
 }
 
 // HardwareBindingData contains hardware identifiers for license binding
@@ -37,46 +132,102 @@ type HardwareBindingData struct {
 	DiskIDs      []string
 	HostNames    []string
 	CustomIDs    []string
+
+	// ContainerIDs identify a container environment, such as a Kubernetes
+	// cluster. They are stored as an extension.
+	ContainerIDs []string
 }
 
 // EncodeLicenseData converts license data to binary format
 func EncodeLicenseData(data *LicenseData) ([]byte, error) {
 	var buf bytes.Buffer
 
+	scheme := data.Scheme
+	if scheme == 0 {
+		scheme = SchemePKCS1v15
+	}
+	if scheme != SchemePKCS1v15 && scheme != SchemePSS {
+		return nil, fmt.Errorf("unsupported signature scheme: %v", scheme)
+	}
+
+	var flags byte
+	if hasExtensions(data) {
+		flags |= FlagExtensions
+	}
+
 	// Write header placeholder (will update length later)
-	h := header{Version: currentVersion, Length: 0}
-	if err := binary.Write(&buf, binary.LittleEndian, h); err != nil {
+	if err := writeHeader(&buf, scheme, flags); err != nil {
 		return nil, err
 	}
 	headerSize := buf.Len()
 
 	// Write license fields
-	writeString(&buf, data.ID)
-	writeString(&buf, data.CustomerID)
-	writeString(&buf, data.ProductID)
-	writeString(&buf, data.SerialNumber)
+	for _, field := range []struct {
+		name  string
+		value string
+	}{
+		{"ID", data.ID},
+		{"customer ID", data.CustomerID},
+		{"product ID", data.ProductID},
+		{"serial number", data.SerialNumber},
+	} {
+		if err := writeString(&buf, field.name, field.value); err != nil {
+			return nil, err
+		}
+	}
 
 	// Write timestamps
-	binary.Write(&buf, binary.LittleEndian, data.IssueDate.Unix())
-	binary.Write(&buf, binary.LittleEndian, data.ExpiryDate.Unix())
+	if err := binary.Write(&buf, binary.LittleEndian, data.IssueDate.Unix()); err != nil {
+		return nil, fmt.Errorf("failed to write issue date: %v", err)
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, data.ExpiryDate.Unix()); err != nil {
+		return nil, fmt.Errorf("failed to write expiry date: %v", err)
+	}
 
-	// Write features
-	writeStringSlice(&buf, data.Features)
+	// Write features and hardware binding
+	for _, field := range []struct {
+		name  string
+		value []string
+	}{
+		{"features", data.Features},
+		{"MAC addresses", data.HardwareIDs.MACAddresses},
+		{"disk IDs", data.HardwareIDs.DiskIDs},
+		{"host names", data.HardwareIDs.HostNames},
+		{"custom IDs", data.HardwareIDs.CustomIDs},
+	} {
+		if err := writeStringSlice(&buf, field.name, field.value); err != nil {
+			return nil, err
+		}
+	}
 
-	// Write hardware binding
-	writeStringSlice(&buf, data.HardwareIDs.MACAddresses)
-	writeStringSlice(&buf, data.HardwareIDs.DiskIDs)
-	writeStringSlice(&buf, data.HardwareIDs.HostNames)
-	writeStringSlice(&buf, data.HardwareIDs.CustomIDs)
+	// Write optional fields
+	if flags&FlagExtensions != 0 {
+		if err := writeExtensions(&buf, data); err != nil {
+			return nil, err
+		}
+	}
 
 	// Update header with correct length
 	bytes := buf.Bytes()
-	binary.LittleEndian.PutUint32(bytes[1:5], uint32(len(bytes)-headerSize))
+	binary.LittleEndian.PutUint32(bytes[headerSize-4:headerSize], uint32(len(bytes)-headerSize))
 
 	return bytes, nil
 }
 
-// DecodeLicenseData converts binary data back to license data
+// writeHeader writes a header with a zero length. PKCS#1 v1.5 licenses
+// without flags keep the version 1 layout so that v2.0.x verifiers can
+// still read them.
+func writeHeader(buf *bytes.Buffer, scheme SignatureScheme, flags byte) error {
+	var h any = header{Version: currentVersion, Scheme: scheme, Flags: flags}
+	if scheme == SchemePKCS1v15 && flags == 0 {
+		h = headerV1{Version: versionPKCS1}
+	}
+	return binary.Write(buf, binary.LittleEndian, h)
+}
+
+// DecodeLicenseData converts binary data back to license data.
+// Decoding is strict: the header length must match the data, trailing
+// bytes are rejected, and list and string sizes are bounded.
 func DecodeLicenseData(data []byte) (*LicenseData, error) {
 	if len(data) < 5 { // Minimum size for header
 		return nil, errors.New("data too small to be a valid license")
@@ -84,48 +235,74 @@ func DecodeLicenseData(data []byte) (*LicenseData, error) {
 
 	buf := bytes.NewReader(data)
 
-	// Read header
-	var h header
-	if err := binary.Read(buf, binary.LittleEndian, &h); err != nil {
-		return nil, err
-	}
+	licenseData := &LicenseData{}
 
-	// Check version
-	if h.Version != currentVersion {
+	// Read header, the first byte always holds the version
+	var length uint32
+	var flags byte
+	switch data[0] {
+	case versionPKCS1:
+		var h headerV1
+		if err := binary.Read(buf, binary.LittleEndian, &h); err != nil {
+			return nil, fmt.Errorf("failed to read header: %v", err)
+		}
+		licenseData.Scheme = SchemePKCS1v15
+		length = h.Length
+	case currentVersion:
+		var h header
+		if err := binary.Read(buf, binary.LittleEndian, &h); err != nil {
+			return nil, fmt.Errorf("failed to read header: %v", err)
+		}
+		if h.Scheme != SchemePKCS1v15 && h.Scheme != SchemePSS {
+			return nil, fmt.Errorf("unsupported signature scheme: %v", h.Scheme)
+		}
+		if h.Flags&FlagEncrypted != 0 {
+			return nil, ErrEncrypted
+		}
+		if h.Flags&^FlagExtensions != 0 {
+			return nil, fmt.Errorf("unsupported header flags: %#x", h.Flags)
+		}
+		licenseData.Scheme = h.Scheme
+		flags = h.Flags
+		length = h.Length
+	default:
 		return nil, errors.New("unsupported license format version")
 	}
 
-	licenseData := &LicenseData{}
+	// Check declared length
+	if int64(length) != int64(buf.Len()) {
+		return nil, fmt.Errorf("license length mismatch: header declares %d bytes, got %d", length, buf.Len())
+	}
 
 	// Read license fields
 	var err error
 	licenseData.ID, err = readString(buf)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read ID: %v", err)
 	}
 
 	licenseData.CustomerID, err = readString(buf)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read customer ID: %v", err)
 	}
 
 	licenseData.ProductID, err = readString(buf)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read product ID: %v", err)
 	}
 
 	licenseData.SerialNumber, err = readString(buf)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read serial number: %v", err)
 	}
 
 	// Read timestamps
 	var issueUnix, expiryUnix int64
 	if err := binary.Read(buf, binary.LittleEndian, &issueUnix); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read issue date: %v", err)
 	}
 	if err := binary.Read(buf, binary.LittleEndian, &expiryUnix); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read expiry date: %v", err)
 	}
 	licenseData.IssueDate = time.Unix(issueUnix, 0)
 	licenseData.ExpiryDate = time.Unix(expiryUnix, 0)
@@ -133,28 +310,40 @@ func DecodeLicenseData(data []byte) (*LicenseData, error) {
 	// Read features
 	licenseData.Features, err = readStringSlice(buf)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read features: %v", err)
 	}
 
 	// Read hardware binding
 	licenseData.HardwareIDs.MACAddresses, err = readStringSlice(buf)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read MAC addresses: %v", err)
 	}
 
 	licenseData.HardwareIDs.DiskIDs, err = readStringSlice(buf)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read disk IDs: %v", err)
 	}
 
 	licenseData.HardwareIDs.HostNames, err = readStringSlice(buf)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read host names: %v", err)
 	}
 
 	licenseData.HardwareIDs.CustomIDs, err = readStringSlice(buf)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read custom IDs: %v", err)
+	}
+
+	// Read optional fields
+	if flags&FlagExtensions != 0 {
+		if err := readExtensions(buf, licenseData); err != nil {
+			return nil, fmt.Errorf("failed to read extensions: %v", err)
+		}
+	}
+
+	// Reject trailing bytes
+	if buf.Len() != 0 {
+		return nil, fmt.Errorf("unexpected %d trailing bytes after license data", buf.Len())
 	}
 
 	return licenseData, nil
@@ -162,10 +351,19 @@ func DecodeLicenseData(data []byte) (*LicenseData, error) {
 
 // Helper functions for reading/writing strings and slices
 
-func writeString(buf *bytes.Buffer, s string) {
-	data := []byte(s)
-	binary.Write(buf, binary.LittleEndian, uint16(len(data)))
-	buf.Write(data)
+func writeString(buf *bytes.Buffer, field, s string) error {
+	if len(s) > MaxStringLength {
+		return fmt.Errorf("%w: %s is %d bytes, maximum is %d", ErrFieldTooLarge, field, len(s), MaxStringLength)
+	}
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("%w: %s is not valid UTF-8", ErrInvalidField, field)
+	}
+
+	if err := binary.Write(buf, binary.LittleEndian, uint16(len(s))); err != nil {
+		return fmt.Errorf("failed to write %s: %v", field, err)
+	}
+	buf.WriteString(s)
+	return nil
 }
 
 func readString(buf *bytes.Reader) (string, error) {
@@ -174,19 +372,37 @@ func readString(buf *bytes.Reader) (string, error) {
 		return "", err
 	}
 
+	// Never allocate more than the remaining input
+	if int(length) > buf.Len() {
+		return "", fmt.Errorf("string length %d exceeds remaining %d bytes", length, buf.Len())
+	}
+
 	data := make([]byte, length)
-	if _, err := buf.Read(data); err != nil {
+	if _, err := io.ReadFull(buf, data); err != nil {
 		return "", err
 	}
 
+	if !utf8.Valid(data) {
+		return "", errors.New("string is not valid UTF-8")
+	}
+
 	return string(data), nil
 }
 
-func writeStringSlice(buf *bytes.Buffer, slice []string) {
-	binary.Write(buf, binary.LittleEndian, uint16(len(slice)))
-	for _, s := range slice {
-		writeString(buf, s)
+func writeStringSlice(buf *bytes.Buffer, field string, slice []string) error {
+	if len(slice) > MaxSliceLength {
+		return fmt.Errorf("%w: %s has %d entries, maximum is %d", ErrFieldTooLarge, field, len(slice), MaxSliceLength)
+	}
+
+	if err := binary.Write(buf, binary.LittleEndian, uint16(len(slice))); err != nil {
+		return fmt.Errorf("failed to write %s: %v", field, err)
 	}
+	for i, s := range slice {
+		if err := writeString(buf, fmt.Sprintf("%s[%d]", field, i), s); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func readStringSlice(buf *bytes.Reader) ([]string, error) {
@@ -195,6 +411,14 @@ func readStringSlice(buf *bytes.Reader) ([]string, error) {
 		return nil, err
 	}
 
+	// Each entry takes at least its two byte length prefix
+	if int(length) > MaxSliceLength {
+		return nil, fmt.Errorf("list has %d entries, maximum is %d", length, MaxSliceLength)
+	}
+	if int(length)*2 > buf.Len() {
+		return nil, fmt.Errorf("list of %d entries exceeds remaining %d bytes", length, buf.Len())
+	}
+
 	result := make([]string, length)
 	for i := 0; i < int(length); i++ {
 		s, err := readString(buf)
@@ -1,6 +1,10 @@
 package licformat
 
 import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -199,3 +203,223 @@ func TestEncodeLicense(t *testing.T) {
 	checkStringSlice(t, "HostNames", license.HardwareIDs.HostNames, decoded.HardwareIDs.HostNames)
 	checkStringSlice(t, "CustomIDs", license.HardwareIDs.CustomIDs, decoded.HardwareIDs.CustomIDs)
 }
+
+func TestSignatureSchemeHeader(t *testing.T) {
+	data := &LicenseData{
+		ID:         "test-license-123",
+		IssueDate:  time.Now().Truncate(time.Second),
+		ExpiryDate: time.Now().AddDate(1, 0, 0).Truncate(time.Second),
+		Features:   []string{"feature1"},
+	}
+
+	// PKCS#1 v1.5 is the default and keeps the version 1 layout
+	encoded, err := EncodeLicenseData(data)
+	if err != nil {
+		t.Fatalf("Failed to encode license data: %v", err)
+	}
+	if encoded[0] != versionPKCS1 {
+		t.Errorf("Version mismatch: expected %d, got %d", versionPKCS1, encoded[0])
+	}
+	decoded, err := DecodeLicenseData(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode license data: %v", err)
+	}
+	if decoded.Scheme != SchemePKCS1v15 {
+		t.Errorf("Scheme mismatch: expected %v, got %v", SchemePKCS1v15, decoded.Scheme)
+	}
+
+	// PSS is recorded in the header
+	data.Scheme = SchemePSS
+	encoded, err = EncodeLicenseData(data)
+	if err != nil {
+		t.Fatalf("Failed to encode license data: %v", err)
+	}
+	if encoded[0] != currentVersion {
+		t.Errorf("Version mismatch: expected %d, got %d", currentVersion, encoded[0])
+	}
+	decoded, err = DecodeLicenseData(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode license data: %v", err)
+	}
+	if decoded.Scheme != SchemePSS {
+		t.Errorf("Scheme mismatch: expected %v, got %v", SchemePSS, decoded.Scheme)
+	}
+	if decoded.ID != data.ID {
+		t.Errorf("ID mismatch: expected %s, got %s", data.ID, decoded.ID)
+	}
+
+	// Unknown schemes are rejected
+	encoded[1] = 0x7F
+	if _, err := DecodeLicenseData(encoded); err == nil {
+		t.Errorf("Expected unknown signature scheme to be rejected")
+	}
+	data.Scheme = SignatureScheme(0x7F)
+	if _, err := EncodeLicenseData(data); err == nil {
+		t.Errorf("Expected unknown signature scheme to fail encoding")
+	}
+}
+
+func TestParseSignatureScheme(t *testing.T) {
+	for _, scheme := range []SignatureScheme{SchemePKCS1v15, SchemePSS} {
+		parsed, err := ParseSignatureScheme(scheme.String())
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", scheme, err)
+		}
+		if parsed != scheme {
+			t.Errorf("Scheme mismatch: expected %v, got %v", scheme, parsed)
+		}
+	}
+	if _, err := ParseSignatureScheme("ecdsa"); err == nil {
+		t.Errorf("Expected unknown scheme name to be rejected")
+	}
+}
+
+func TestDecodeLicenseDataStrict(t *testing.T) {
+	valid, err := EncodeLicenseData(&LicenseData{
+		ID:         "test-license-123",
+		CustomerID: "customer-456",
+		IssueDate:  time.Now().Truncate(time.Second),
+		ExpiryDate: time.Now().AddDate(1, 0, 0).Truncate(time.Second),
+		Features:   []string{"feature1", "feature2"},
+		Scheme:     SchemePSS,
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode license data: %v", err)
+	}
+
+	modify := func(f func(data []byte) []byte) []byte {
+		return f(append([]byte(nil), valid...))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"TrailingBytes", append(append([]byte(nil), valid...), 0x00)},
+		{"Truncated", valid[:len(valid)-1]},
+		{"LengthMismatch", modify(func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[3:7], uint32(len(data)))
+			return data
+		})},
+		{"ReservedFlags", modify(func(data []byte) []byte {
+			data[2] = 0x80
+			return data
+		})},
+		{"InvalidUTF8", modify(func(data []byte) []byte {
+			data[9] = 0xFF // First byte of the ID
+			return data
+		})},
+		{"StringBeyondInput", modify(func(data []byte) []byte {
+			binary.LittleEndian.PutUint16(data[7:9], 0xFFFF)
+			return data
+		})},
+		{"HugeSliceCount", func() []byte {
+			// A valid prefix followed by a feature list claiming 65535 entries
+			data := modify(func(data []byte) []byte { return data })
+			offset := 7 + 2 + len("test-license-123") + 2 + len("customer-456") + 2 + 2 + 16
+			binary.LittleEndian.PutUint16(data[offset:offset+2], 0xFFFF)
+			return data
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeLicenseData(tt.data); err == nil {
+				t.Errorf("Expected decoding to fail")
+			}
+		})
+	}
+}
+
+func FuzzDecodeLicenseData(f *testing.F) {
+	// Seed the corpus with valid licenses in both header layouts
+	for _, scheme := range []SignatureScheme{SchemePKCS1v15, SchemePSS} {
+		seed, err := EncodeLicenseData(&LicenseData{
+			ID:           "test-license-123",
+			CustomerID:   "customer-456",
+			ProductID:    "product-789",
+			SerialNumber: "SN-ABCDEF",
+			IssueDate:    time.Unix(1700000000, 0),
+			ExpiryDate:   time.Unix(1800000000, 0),
+			Features:     []string{"feature1", "feature2"},
+			HardwareIDs: HardwareBindingData{
+				MACAddresses: []string{"00:11:22:33:44:55"},
+				HostNames:    []string{"host1.example.com"},
+			},
+			Scheme: scheme,
+		})
+		if err != nil {
+			f.Fatalf("Failed to encode seed: %v", err)
+		}
+		f.Add(seed)
+		f.Add(seed[:len(seed)/2])
+	}
+	extended, err := EncodeLicenseData(&LicenseData{ID: "renewed", PreviousID: "original"})
+	if err != nil {
+		f.Fatalf("Failed to encode seed: %v", err)
+	}
+	f.Add(extended)
+	f.Add([]byte{})
+	f.Add([]byte{currentVersion, byte(SchemePSS), 0, 0, 0, 0, 0})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decoded, err := DecodeLicenseData(data)
+		if err != nil {
+			return
+		}
+
+		// Anything that decodes must survive a round trip unchanged
+		encoded, err := EncodeLicenseData(decoded)
+		if err != nil {
+			t.Fatalf("Failed to re-encode decoded license: %v", err)
+		}
+		again, err := DecodeLicenseData(encoded)
+		if err != nil {
+			t.Fatalf("Failed to decode re-encoded license: %v", err)
+		}
+		if !reflect.DeepEqual(decoded, again) {
+			t.Fatalf("Round trip mismatch: %+v != %+v", decoded, again)
+		}
+	})
+}
+
+func TestEncodeLicenseDataLimits(t *testing.T) {
+	tooMany := make([]string, MaxSliceLength+1)
+
+	tests := []struct {
+		name string
+		data *LicenseData
+		want error
+	}{
+		{"StringTooLong", &LicenseData{ID: strings.Repeat("x", MaxStringLength+1)}, ErrFieldTooLarge},
+		{"EntryTooLong", &LicenseData{Features: []string{strings.Repeat("x", MaxStringLength+1)}}, ErrFieldTooLarge},
+		{"TooManyEntries", &LicenseData{HardwareIDs: HardwareBindingData{CustomIDs: tooMany}}, ErrFieldTooLarge},
+		{"InvalidUTF8", &LicenseData{CustomerID: "\xff"}, ErrInvalidField},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EncodeLicenseData(tt.data)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Values at the limits still round trip
+	data := &LicenseData{
+		ID:       strings.Repeat("x", MaxStringLength),
+		Features: make([]string, MaxSliceLength),
+	}
+	encoded, err := EncodeLicenseData(data)
+	if err != nil {
+		t.Fatalf("Failed to encode license data at the limits: %v", err)
+	}
+	decoded, err := DecodeLicenseData(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode license data at the limits: %v", err)
+	}
+	if decoded.ID != data.ID || len(decoded.Features) != MaxSliceLength {
+		t.Errorf("Round trip at the limits failed")
+	}
+}
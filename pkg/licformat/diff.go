@@ -0,0 +1,118 @@
+package licformat
+
+import (
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// FieldDiff describes how one license field differs between two licenses.
+// Scalar fields set Old and New, list fields set Added and Removed.
+type FieldDiff struct {
+	Field   string
+	Old     string
+	New     string
+	Added   []string
+	Removed []string
+}
+
+// Diff returns the fields that differ from a to b, in field order.
+// Lists are compared as sets and signatures are ignored.
+func Diff(a, b *License) []FieldDiff {
+	var diffs []FieldDiff
+
+	for _, f := range []struct {
+		name     string
+		old, new string
+	}{
+		{"ID", a.ID, b.ID},
+		{"Customer ID", a.CustomerID, b.CustomerID},
+		{"Product ID", a.ProductID, b.ProductID},
+		{"Serial Number", a.SerialNumber, b.SerialNumber},
+		{"Issue Date", formatDate(a.IssueDate), formatDate(b.IssueDate)},
+		{"Expiry Date", formatDate(a.ExpiryDate), formatDate(b.ExpiryDate)},
+		{"Previous ID", a.PreviousID, b.PreviousID},
+		{"Seats", formatSeats(a.Seats), formatSeats(b.Seats)},
+		{"Seat Server Key", hex.EncodeToString(a.SeatServerKey), hex.EncodeToString(b.SeatServerKey)},
+		{"Trial Duration", formatDuration(a.TrialDuration), formatDuration(b.TrialDuration)},
+		{"Signature Scheme", schemeName(a.Scheme), schemeName(b.Scheme)},
+	} {
+		if f.old != f.new {
+			diffs = append(diffs, FieldDiff{Field: f.name, Old: f.old, New: f.new})
+		}
+	}
+
+	for _, f := range []struct {
+		name     string
+		old, new []string
+	}{
+		{"Features", a.Features, b.Features},
+		{"MAC Addresses", a.HardwareIDs.MACAddresses, b.HardwareIDs.MACAddresses},
+		{"Disk IDs", a.HardwareIDs.DiskIDs, b.HardwareIDs.DiskIDs},
+		{"Host Names", a.HardwareIDs.HostNames, b.HardwareIDs.HostNames},
+		{"Custom IDs", a.HardwareIDs.CustomIDs, b.HardwareIDs.CustomIDs},
+		{"Container IDs", a.HardwareIDs.ContainerIDs, b.HardwareIDs.ContainerIDs},
+		{"Usage Quotas", formatQuotas(a.Quotas), formatQuotas(b.Quotas)},
+	} {
+		added, removed := subtract(f.new, f.old), subtract(f.old, f.new)
+		if len(added) > 0 || len(removed) > 0 {
+			diffs = append(diffs, FieldDiff{Field: f.name, Added: added, Removed: removed})
+		}
+	}
+
+	return diffs
+}
+
+// formatDate formats a date for comparison, ignoring the location
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatSeats formats a seat count for comparison, zero meaning not floating
+func formatSeats(seats uint32) string {
+	if seats == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(seats), 10)
+}
+
+// formatDuration formats a duration for comparison, zero meaning unset
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// formatQuotas formats usage quotas as sorted feature=limit entries
+func formatQuotas(quotas map[string]uint64) []string {
+	var entries []string
+	for feature, limit := range quotas {
+		entries = append(entries, feature+"="+strconv.FormatUint(limit, 10))
+	}
+	slices.Sort(entries)
+	return entries
+}
+
+// schemeName returns the name of a scheme, treating zero as the default
+func schemeName(s SignatureScheme) string {
+	if s == 0 {
+		s = SchemePKCS1v15
+	}
+	return s.String()
+}
+
+// subtract returns the entries of a that are not in b, in the order of a
+func subtract(a, b []string) []string {
+	var result []string
+	for _, s := range a {
+		if !slices.Contains(b, s) && !slices.Contains(result, s) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package licformat
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	a := &License{
+		ID:         "license-1",
+		CustomerID: "customer",
+		ExpiryDate: time.Unix(1800000000, 0),
+		Features:   []string{"basic", "premium"},
+		Quotas:     map[string]uint64{"runs": 100},
+		HardwareIDs: HardwareBinding{
+			MACAddresses: []string{"00:11:22:33:44:55", "66:77:88:99:aa:bb"},
+		},
+	}
+
+	// Identical licenses and reordered lists have no differences
+	b := *a
+	b.Features = []string{"premium", "basic"}
+	b.Scheme = SchemePKCS1v15
+	if diffs := Diff(a, &b); len(diffs) != 0 {
+		t.Errorf("Expected no differences, got %+v", diffs)
+	}
+
+	b.ID = "license-2"
+	b.PreviousID = "license-1"
+	b.ExpiryDate = time.Unix(1900000000, 0)
+	b.Features = []string{"basic", "enterprise"}
+	b.HardwareIDs.MACAddresses = []string{"00:11:22:33:44:55"}
+	b.Quotas = map[string]uint64{"runs": 100, "exports": 10}
+
+	expected := []FieldDiff{
+		{Field: "ID", Old: "license-1", New: "license-2"},
+		{Field: "Expiry Date", Old: "2027-01-15T08:00:00Z", New: "2030-03-17T17:46:40Z"},
+		{Field: "Previous ID", Old: "", New: "license-1"},
+		{Field: "Features", Added: []string{"enterprise"}, Removed: []string{"premium"}},
+		{Field: "MAC Addresses", Removed: []string{"66:77:88:99:aa:bb"}},
+		{Field: "Usage Quotas", Added: []string{"exports=10"}},
+	}
+	if diffs := Diff(a, &b); !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Unexpected differences:\n got %+v\nwant %+v", diffs, expected)
+	}
+}
+
+func TestDecodeLicenseFile(t *testing.T) {
+	for _, scheme := range []SignatureScheme{SchemePKCS1v15, SchemePSS} {
+		encoded, err := EncodeLicense(&License{ID: "license-1", Features: []string{"basic"}, Scheme: scheme})
+		if err != nil {
+			t.Fatalf("Failed to encode license: %v", err)
+		}
+		signature := bytes.Repeat([]byte{0xAB}, 256)
+
+		license, err := DecodeLicenseFile(append(append([]byte(nil), encoded...), signature...))
+		if err != nil {
+			t.Fatalf("Failed to decode %v license file: %v", scheme, err)
+		}
+		if license.ID != "license-1" || !bytes.Equal(license.Signature, signature) {
+			t.Errorf("Unexpected license: %+v", license)
+		}
+
+		// A license without a signature is not a license file
+		if _, err := DecodeLicenseFile(encoded); err == nil {
+			t.Error("Expected error for a license file without signature")
+		}
+	}
+
+	if _, err := DecodeLicenseFile([]byte("{}")); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
@@ -0,0 +1,255 @@
+package licformat
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Encryption wraps the license body in an authenticated encryption envelope.
+// The header stays in clear text so the signature scheme is known before
+// decrypting, and the signature is computed over the encrypted license.
+
+// FlagEncrypted marks a license whose body is an encryption envelope
+const FlagEncrypted byte = 0x01
+
+// EncryptionMode identifies how the content key of an envelope is derived
+type EncryptionMode byte
+
+const (
+	// EncryptionX25519 derives the content key from an X25519 key agreement
+	// between an ephemeral key and the recipient's key
+	EncryptionX25519 EncryptionMode = 1
+	// EncryptionFingerprint derives the content key from a machine
+	// fingerprint, so the license can only be read on the bound machine
+	EncryptionFingerprint EncryptionMode = 2
+)
+
+// ErrEncrypted is returned when decoding an encrypted license without opening it first
+var ErrEncrypted = errors.New("license contents are encrypted")
+
+// Envelope layout: mode, ephemeral public key, nonce, AES-256-GCM ciphertext
+const (
+	x25519KeySize = 32
+	saltSize      = 32
+	nonceSize     = 12
+	keySize       = 32
+)
+
+// hkdfInfo binds derived keys to this format
+const hkdfInfo = "go-license payload encryption v1"
+
+// IsEncrypted reports whether the encoded license has an encrypted body
+func IsEncrypted(data []byte) bool {
+	return len(data) >= 3 && data[0] == currentVersion && data[2]&FlagEncrypted != 0
+}
+
+// EncryptedMode returns the encryption mode of an encrypted license
+func EncryptedMode(data []byte) (EncryptionMode, error) {
+	_, body, err := splitEncrypted(data)
+	if err != nil {
+		return 0, err
+	}
+	if len(body) == 0 {
+		return 0, errors.New("encrypted license has an empty envelope")
+	}
+	return EncryptionMode(body[0]), nil
+}
+
+// SealLicense encrypts the body of an encoded license to the recipient's
+// X25519 public key. The result keeps the signature scheme of the input
+// and must be signed like any other encoded license.
+func SealLicense(encoded []byte, recipient *ecdh.PublicKey) ([]byte, error) {
+	if recipient == nil || recipient.Curve() != ecdh.X25519() {
+		return nil, errors.New("recipient must be an X25519 public key")
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %v", err)
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute shared secret: %v", err)
+	}
+
+	ephemeralPub := ephemeral.PublicKey().Bytes()
+	salt := append(append([]byte(nil), ephemeralPub...), recipient.Bytes()...)
+	return seal(encoded, EncryptionX25519, ephemeralPub, shared, salt)
+}
+
+// OpenLicense decrypts a license sealed with SealLicense and returns the
+// clear text encoding, which can be passed to DecodeLicenseData
+func OpenLicense(encoded []byte, key *ecdh.PrivateKey) ([]byte, error) {
+	if key == nil || key.Curve() != ecdh.X25519() {
+		return nil, errors.New("decryption key must be an X25519 private key")
+	}
+
+	h, body, err := splitEncrypted(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < 1+x25519KeySize || EncryptionMode(body[0]) != EncryptionX25519 {
+		return nil, errors.New("license is not encrypted to an X25519 key")
+	}
+
+	ephemeralPub := body[1 : 1+x25519KeySize]
+	ephemeral, err := ecdh.X25519().NewPublicKey(ephemeralPub)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %v", err)
+	}
+	shared, err := key.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute shared secret: %v", err)
+	}
+
+	salt := append(append([]byte(nil), ephemeralPub...), key.PublicKey().Bytes()...)
+	return open(encoded[:len(encoded)-len(body)], h, body[1+x25519KeySize:], shared, salt)
+}
+
+// SealLicenseWithFingerprint encrypts the body of an encoded license with
+// a key derived from a machine fingerprint and a random salt
+func SealLicenseWithFingerprint(encoded []byte, fingerprint []byte) ([]byte, error) {
+	if len(fingerprint) == 0 {
+		return nil, errors.New("fingerprint cannot be empty")
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %v", err)
+	}
+	return seal(encoded, EncryptionFingerprint, salt, fingerprint, salt)
+}
+
+// OpenLicenseWithFingerprint decrypts a license sealed with
+// SealLicenseWithFingerprint and returns the clear text encoding
+func OpenLicenseWithFingerprint(encoded []byte, fingerprint []byte) ([]byte, error) {
+	h, body, err := splitEncrypted(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < 1+saltSize || EncryptionMode(body[0]) != EncryptionFingerprint {
+		return nil, errors.New("license is not bound to a machine fingerprint")
+	}
+
+	salt := body[1 : 1+saltSize]
+	return open(encoded[:len(encoded)-len(body)], h, body[1+saltSize:], fingerprint, salt)
+}
+
+// seal builds an encrypted license from a clear text encoding. keyMaterial
+// is stored after the mode byte and secret and salt feed the key derivation.
+func seal(encoded []byte, mode EncryptionMode, keyMaterial, secret, salt []byte) ([]byte, error) {
+	if IsEncrypted(encoded) {
+		return nil, errors.New("license is already encrypted")
+	}
+
+	// Validate the input and find the body
+	licenseData, err := DecodeLicenseData(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid license encoding: %v", err)
+	}
+	headerSize := binary.Size(headerV1{})
+	if encoded[0] == currentVersion {
+		headerSize = binary.Size(header{})
+	}
+	body := encoded[headerSize:]
+
+	aead, err := newAEAD(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	// Build the header, its bytes are authenticated as additional data
+	var buf bytes.Buffer
+	flags := FlagEncrypted
+	if encoded[0] == currentVersion {
+		flags |= encoded[2] // Keep the flags of the clear text encoding
+	}
+	if err := writeHeader(&buf, licenseData.Scheme, flags); err != nil {
+		return nil, err
+	}
+	envelopeSize := 1 + len(keyMaterial) + nonceSize + len(body) + aead.Overhead()
+	headerBytes := buf.Bytes()
+	binary.LittleEndian.PutUint32(headerBytes[len(headerBytes)-4:], uint32(envelopeSize))
+	additionalData := append([]byte(nil), headerBytes...)
+
+	buf.WriteByte(byte(mode))
+	buf.Write(keyMaterial)
+	buf.Write(nonce)
+	buf.Write(aead.Seal(nil, nonce, body, additionalData))
+
+	return buf.Bytes(), nil
+}
+
+// open decrypts the remainder of an envelope (nonce and ciphertext) and
+// rebuilds the clear text encoding
+func open(headerBytes []byte, h header, sealed, secret, salt []byte) ([]byte, error) {
+	if len(sealed) < nonceSize {
+		return nil, errors.New("encrypted license envelope is truncated")
+	}
+
+	aead, err := newAEAD(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+	body, err := aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], headerBytes)
+	if err != nil {
+		return nil, errors.New("failed to decrypt license: wrong key or corrupted data")
+	}
+
+	var buf bytes.Buffer
+	if err := writeHeader(&buf, h.Scheme, h.Flags&^FlagEncrypted); err != nil {
+		return nil, err
+	}
+	encoded := append(buf.Bytes(), body...)
+	binary.LittleEndian.PutUint32(encoded[buf.Len()-4:buf.Len()], uint32(len(body)))
+
+	return encoded, nil
+}
+
+// splitEncrypted validates the header of an encrypted license and returns it with the envelope
+func splitEncrypted(data []byte) (header, []byte, error) {
+	var h header
+	if !IsEncrypted(data) {
+		return h, nil, errors.New("license is not encrypted")
+	}
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &h); err != nil {
+		return h, nil, fmt.Errorf("failed to read header: %v", err)
+	}
+	if h.Scheme != SchemePKCS1v15 && h.Scheme != SchemePSS {
+		return h, nil, fmt.Errorf("unsupported signature scheme: %v", h.Scheme)
+	}
+	if h.Flags&^knownFlags != 0 {
+		return h, nil, fmt.Errorf("unsupported header flags: %#x", h.Flags)
+	}
+
+	body := data[binary.Size(h):]
+	if int64(h.Length) != int64(len(body)) {
+		return h, nil, fmt.Errorf("license length mismatch: header declares %d bytes, got %d", h.Length, len(body))
+	}
+	return h, body, nil
+}
+
+// newAEAD derives an AES-256-GCM cipher from a shared secret
+func newAEAD(secret, salt []byte) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, secret, salt, hkdfInfo, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive content key: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
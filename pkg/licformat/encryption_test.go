@@ -0,0 +1,146 @@
+package licformat
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSealOpenLicense(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	for _, scheme := range []SignatureScheme{SchemePKCS1v15, SchemePSS} {
+		t.Run(scheme.String(), func(t *testing.T) {
+			encoded, err := EncodeLicenseData(&LicenseData{
+				ID:         "test-license-123",
+				CustomerID: "confidential-customer",
+				IssueDate:  time.Now().Truncate(time.Second),
+				ExpiryDate: time.Now().AddDate(1, 0, 0).Truncate(time.Second),
+				Features:   []string{"secret-feature"},
+				Scheme:     scheme,
+			})
+			if err != nil {
+				t.Fatalf("Failed to encode license data: %v", err)
+			}
+
+			sealed, err := SealLicense(encoded, key.PublicKey())
+			if err != nil {
+				t.Fatalf("Failed to seal license: %v", err)
+			}
+			if !IsEncrypted(sealed) || IsEncrypted(encoded) {
+				t.Fatalf("IsEncrypted returned unexpected results")
+			}
+			if bytes.Contains(sealed, []byte("confidential-customer")) {
+				t.Errorf("Sealed license contains clear text contents")
+			}
+			if mode, err := EncryptedMode(sealed); err != nil || mode != EncryptionX25519 {
+				t.Errorf("Unexpected encryption mode %v (%v)", mode, err)
+			}
+
+			// Encrypted licenses cannot be decoded directly
+			if _, err := DecodeLicenseData(sealed); !errors.Is(err, ErrEncrypted) {
+				t.Errorf("Expected ErrEncrypted, got %v", err)
+			}
+
+			// Opening restores the exact clear text encoding
+			opened, err := OpenLicense(sealed, key)
+			if err != nil {
+				t.Fatalf("Failed to open license: %v", err)
+			}
+			if !bytes.Equal(opened, encoded) {
+				t.Errorf("Opened license does not match the original encoding")
+			}
+			decoded, err := DecodeLicenseData(opened)
+			if err != nil {
+				t.Fatalf("Failed to decode opened license: %v", err)
+			}
+			if decoded.Scheme != scheme || decoded.CustomerID != "confidential-customer" {
+				t.Errorf("Decoded license mismatch: %+v", decoded)
+			}
+		})
+	}
+}
+
+func TestOpenLicenseRejectsTampering(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	otherKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	encoded, err := EncodeLicenseData(&LicenseData{ID: "test-license-123"})
+	if err != nil {
+		t.Fatalf("Failed to encode license data: %v", err)
+	}
+	sealed, err := SealLicense(encoded, key.PublicKey())
+	if err != nil {
+		t.Fatalf("Failed to seal license: %v", err)
+	}
+
+	if _, err := OpenLicense(sealed, otherKey); err == nil {
+		t.Errorf("Expected opening with the wrong key to fail")
+	}
+
+	// Changing the declared scheme is detected through the additional data
+	tampered := append([]byte(nil), sealed...)
+	tampered[1] = byte(SchemePSS)
+	if _, err := OpenLicense(tampered, key); err == nil {
+		t.Errorf("Expected opening with a modified header to fail")
+	}
+
+	tampered = append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 0xFF
+	if _, err := OpenLicense(tampered, key); err == nil {
+		t.Errorf("Expected opening a modified ciphertext to fail")
+	}
+
+	if _, err := SealLicense(sealed, key.PublicKey()); err == nil {
+		t.Errorf("Expected sealing an encrypted license to fail")
+	}
+}
+
+func TestSealOpenLicenseWithFingerprint(t *testing.T) {
+	encoded, err := EncodeLicenseData(&LicenseData{ID: "test-license-123", Scheme: SchemePSS})
+	if err != nil {
+		t.Fatalf("Failed to encode license data: %v", err)
+	}
+
+	fingerprint := []byte("machine-fingerprint")
+	sealed, err := SealLicenseWithFingerprint(encoded, fingerprint)
+	if err != nil {
+		t.Fatalf("Failed to seal license: %v", err)
+	}
+	if mode, err := EncryptedMode(sealed); err != nil || mode != EncryptionFingerprint {
+		t.Errorf("Unexpected encryption mode %v (%v)", mode, err)
+	}
+
+	opened, err := OpenLicenseWithFingerprint(sealed, fingerprint)
+	if err != nil {
+		t.Fatalf("Failed to open license: %v", err)
+	}
+	if !bytes.Equal(opened, encoded) {
+		t.Errorf("Opened license does not match the original encoding")
+	}
+
+	if _, err := OpenLicenseWithFingerprint(sealed, []byte("other-machine")); err == nil {
+		t.Errorf("Expected opening with a different fingerprint to fail")
+	}
+
+	// The envelope modes are not interchangeable
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if _, err := OpenLicense(sealed, key); err == nil {
+		t.Errorf("Expected opening a fingerprint envelope with an X25519 key to fail")
+	}
+}
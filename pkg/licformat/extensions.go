@@ -0,0 +1,244 @@
+package licformat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"time"
+	"unicode/utf8"
+)
+
+// Extensions carry optional license fields without changing the layout of
+// licenses that do not use them. When FlagExtensions is set in the header,
+// the hardware binding is followed by a block of tagged records:
+//
+//	count uint16, then per record: tag byte, length uint16, value
+//
+// Records appear in ascending tag order, each tag at most once, so every
+// license has exactly one encoding. Unknown tags are rejected, because an
+// older verifier must not silently ignore a restriction it cannot enforce.
+
+// FlagExtensions marks a license with an extension block
+const FlagExtensions byte = 0x02
+
+// knownFlags are the header flags understood by this version
+const knownFlags = FlagEncrypted | FlagExtensions
+
+// Extension tags
+const (
+	extPreviousID   byte = 1
+	extSeats        byte = 2
+	extTrial        byte = 3
+	extQuotas       byte = 4
+	extContainerIDs byte = 5
+	extSeatServer   byte = 6
+)
+
+// SeatServerKeyLength is the length of the seat server key hash of a
+// floating license
+const SeatServerKeyLength = 32
+
+// hasExtensions reports whether the license data uses any extension
+func hasExtensions(data *LicenseData) bool {
+	return data.PreviousID != "" || data.Seats != 0 || data.TrialDuration != 0 || len(data.Quotas) > 0 ||
+		len(data.HardwareIDs.ContainerIDs) > 0 || len(data.SeatServerKey) > 0
+}
+
+// writeExtensions writes the extension block
+func writeExtensions(buf *bytes.Buffer, data *LicenseData) error {
+	type record struct {
+		tag   byte
+		name  string
+		value []byte
+	}
+	var records []record
+
+	if data.PreviousID != "" {
+		if !utf8.ValidString(data.PreviousID) {
+			return fmt.Errorf("%w: previous ID is not valid UTF-8", ErrInvalidField)
+		}
+		records = append(records, record{extPreviousID, "previous ID", []byte(data.PreviousID)})
+	}
+	if data.Seats != 0 {
+		records = append(records, record{extSeats, "seats", binary.LittleEndian.AppendUint32(nil, data.Seats)})
+	}
+	if data.TrialDuration != 0 {
+		if data.TrialDuration < 0 || data.TrialDuration%time.Second != 0 || data.TrialDuration/time.Second > math.MaxUint32 {
+			return fmt.Errorf("%w: trial duration must be a positive number of seconds", ErrInvalidField)
+		}
+		seconds := uint32(data.TrialDuration / time.Second)
+		records = append(records, record{extTrial, "trial duration", binary.LittleEndian.AppendUint32(nil, seconds)})
+	}
+	if len(data.Quotas) > 0 {
+		value, err := encodeQuotas(data.Quotas)
+		if err != nil {
+			return err
+		}
+		records = append(records, record{extQuotas, "usage quotas", value})
+	}
+	if len(data.HardwareIDs.ContainerIDs) > 0 {
+		var value bytes.Buffer
+		if err := writeStringSlice(&value, "container IDs", data.HardwareIDs.ContainerIDs); err != nil {
+			return err
+		}
+		records = append(records, record{extContainerIDs, "container IDs", value.Bytes()})
+	}
+	if len(data.SeatServerKey) > 0 {
+		if len(data.SeatServerKey) != SeatServerKeyLength {
+			return fmt.Errorf("%w: seat server key hash must be %d bytes", ErrInvalidField, SeatServerKeyLength)
+		}
+		records = append(records, record{extSeatServer, "seat server key", data.SeatServerKey})
+	}
+
+	if err := binary.Write(buf, binary.LittleEndian, uint16(len(records))); err != nil {
+		return fmt.Errorf("failed to write extensions: %v", err)
+	}
+	for _, r := range records {
+		if len(r.value) > MaxStringLength {
+			return fmt.Errorf("%w: %s is %d bytes, maximum is %d", ErrFieldTooLarge, r.name, len(r.value), MaxStringLength)
+		}
+		buf.WriteByte(r.tag)
+		if err := binary.Write(buf, binary.LittleEndian, uint16(len(r.value))); err != nil {
+			return fmt.Errorf("failed to write %s: %v", r.name, err)
+		}
+		buf.Write(r.value)
+	}
+	return nil
+}
+
+// readExtensions reads the extension block into data
+func readExtensions(buf *bytes.Reader, data *LicenseData) error {
+	var count uint16
+	if err := binary.Read(buf, binary.LittleEndian, &count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("extension block is empty")
+	}
+
+	var lastTag byte
+	for i := 0; i < int(count); i++ {
+		tag, err := buf.ReadByte()
+		if err != nil {
+			return err
+		}
+		if tag <= lastTag {
+			return fmt.Errorf("extension %d is duplicated or out of order", tag)
+		}
+		lastTag = tag
+
+		var length uint16
+		if err := binary.Read(buf, binary.LittleEndian, &length); err != nil {
+			return err
+		}
+		if int(length) > buf.Len() {
+			return fmt.Errorf("extension length %d exceeds remaining %d bytes", length, buf.Len())
+		}
+		value := make([]byte, length)
+		if _, err := io.ReadFull(buf, value); err != nil {
+			return err
+		}
+
+		switch tag {
+		case extPreviousID:
+			if len(value) == 0 || !utf8.Valid(value) {
+				return errors.New("invalid previous ID")
+			}
+			data.PreviousID = string(value)
+		case extSeats:
+			if len(value) != 4 || binary.LittleEndian.Uint32(value) == 0 {
+				return errors.New("invalid seat count")
+			}
+			data.Seats = binary.LittleEndian.Uint32(value)
+		case extTrial:
+			if len(value) != 4 || binary.LittleEndian.Uint32(value) == 0 {
+				return errors.New("invalid trial duration")
+			}
+			data.TrialDuration = time.Duration(binary.LittleEndian.Uint32(value)) * time.Second
+		case extQuotas:
+			quotas, err := decodeQuotas(value)
+			if err != nil {
+				return fmt.Errorf("invalid usage quotas: %v", err)
+			}
+			data.Quotas = quotas
+		case extContainerIDs:
+			value := bytes.NewReader(value)
+			containerIDs, err := readStringSlice(value)
+			if err != nil || len(containerIDs) == 0 || value.Len() != 0 {
+				return errors.New("invalid container IDs")
+			}
+			data.HardwareIDs.ContainerIDs = containerIDs
+		case extSeatServer:
+			if len(value) != SeatServerKeyLength {
+				return errors.New("invalid seat server key")
+			}
+			data.SeatServerKey = value
+		default:
+			return fmt.Errorf("unsupported extension %d", tag)
+		}
+	}
+	return nil
+}
+
+// encodeQuotas encodes usage quotas in ascending feature order, each as
+// a uint16 length prefixed feature name followed by a uint64 limit
+func encodeQuotas(quotas map[string]uint64) ([]byte, error) {
+	features := make([]string, 0, len(quotas))
+	for feature := range quotas {
+		features = append(features, feature)
+	}
+	slices.Sort(features)
+
+	var value []byte
+	for _, feature := range features {
+		if feature == "" || len(feature) > MaxStringLength || !utf8.ValidString(feature) {
+			return nil, fmt.Errorf("%w: invalid usage quota feature %q", ErrInvalidField, feature)
+		}
+		if quotas[feature] == 0 {
+			return nil, fmt.Errorf("%w: usage quota for %q must be positive", ErrInvalidField, feature)
+		}
+		value = binary.LittleEndian.AppendUint16(value, uint16(len(feature)))
+		value = append(value, feature...)
+		value = binary.LittleEndian.AppendUint64(value, quotas[feature])
+	}
+	return value, nil
+}
+
+// decodeQuotas decodes usage quotas, rejecting encodings that
+// encodeQuotas would not produce
+func decodeQuotas(value []byte) (map[string]uint64, error) {
+	quotas := make(map[string]uint64)
+	var last string
+	for len(value) > 0 {
+		if len(value) < 2 {
+			return nil, errors.New("truncated feature name")
+		}
+		length := int(binary.LittleEndian.Uint16(value))
+		value = value[2:]
+		if length == 0 || length+8 > len(value) {
+			return nil, errors.New("invalid feature name length")
+		}
+		feature := string(value[:length])
+		if !utf8.ValidString(feature) {
+			return nil, errors.New("feature name is not valid UTF-8")
+		}
+		if len(quotas) > 0 && feature <= last {
+			return nil, fmt.Errorf("feature %q is duplicated or out of order", feature)
+		}
+		limit := binary.LittleEndian.Uint64(value[length:])
+		if limit == 0 {
+			return nil, fmt.Errorf("quota for %q is zero", feature)
+		}
+		quotas[feature] = limit
+		last = feature
+		value = value[length+8:]
+	}
+	if len(quotas) == 0 {
+		return nil, errors.New("no quotas")
+	}
+	return quotas, nil
+}
@@ -0,0 +1,180 @@
+package licformat
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExtensionsRoundTrip(t *testing.T) {
+	data := &LicenseData{
+		ID:            "renewed-license",
+		CustomerID:    "customer-456",
+		IssueDate:     time.Unix(1700000000, 0),
+		ExpiryDate:    time.Unix(1800000000, 0),
+		PreviousID:    "original-license",
+		Seats:         25,
+		SeatServerKey: bytes.Repeat([]byte{0xAB}, SeatServerKeyLength),
+		TrialDuration: 30 * 24 * time.Hour,
+		Quotas:        map[string]uint64{"runs": 100, "documents": 5000},
+		HardwareIDs: HardwareBindingData{
+			HostNames:    []string{"host1"},
+			ContainerIDs: []string{"k8s-cluster:6f1c2a4e-0b7d-4c55-9a63-2d8e1f0c3b9a"},
+		},
+	}
+
+	encoded, err := EncodeLicenseData(data)
+	if err != nil {
+		t.Fatalf("Failed to encode license data: %v", err)
+	}
+
+	// Extensions need the version 2 header, even for PKCS#1 v1.5
+	if encoded[0] != currentVersion || encoded[2] != FlagExtensions {
+		t.Fatalf("Expected version 2 header with FlagExtensions, got % x", encoded[:3])
+	}
+
+	decoded, err := DecodeLicenseData(encoded)
+	if err != nil {
+		t.Fatalf("Failed to decode license data: %v", err)
+	}
+	if decoded.PreviousID != data.PreviousID {
+		t.Errorf("Expected previous ID %q, got %q", data.PreviousID, decoded.PreviousID)
+	}
+	if decoded.Seats != data.Seats {
+		t.Errorf("Expected %d seats, got %d", data.Seats, decoded.Seats)
+	}
+	if !bytes.Equal(decoded.SeatServerKey, data.SeatServerKey) {
+		t.Errorf("Expected seat server key %x, got %x", data.SeatServerKey, decoded.SeatServerKey)
+	}
+	if decoded.TrialDuration != data.TrialDuration {
+		t.Errorf("Expected trial duration %s, got %s", data.TrialDuration, decoded.TrialDuration)
+	}
+	if !reflect.DeepEqual(decoded.Quotas, data.Quotas) {
+		t.Errorf("Expected quotas %v, got %v", data.Quotas, decoded.Quotas)
+	}
+	if !reflect.DeepEqual(decoded.HardwareIDs.ContainerIDs, data.HardwareIDs.ContainerIDs) {
+		t.Errorf("Expected container IDs %v, got %v", data.HardwareIDs.ContainerIDs, decoded.HardwareIDs.ContainerIDs)
+	}
+
+	// Trial durations are whole seconds
+	for _, d := range []time.Duration{-time.Hour, 1500 * time.Millisecond} {
+		invalid := *data
+		invalid.TrialDuration = d
+		if _, err := EncodeLicenseData(&invalid); !errors.Is(err, ErrInvalidField) {
+			t.Errorf("Expected ErrInvalidField for trial duration %s, got %v", d, err)
+		}
+	}
+
+	// Quotas need a feature name and a positive limit
+	for _, quotas := range []map[string]uint64{{"": 1}, {"runs": 0}} {
+		invalid := *data
+		invalid.Quotas = quotas
+		if _, err := EncodeLicenseData(&invalid); !errors.Is(err, ErrInvalidField) {
+			t.Errorf("Expected ErrInvalidField for quotas %v, got %v", quotas, err)
+		}
+	}
+
+	// Seat server keys are SHA-256 hashes
+	invalid := *data
+	invalid.SeatServerKey = []byte{1, 2, 3}
+	if _, err := EncodeLicenseData(&invalid); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("Expected ErrInvalidField for a short seat server key, got %v", err)
+	}
+
+	// Licenses without extensions keep their original layout
+	data.PreviousID = ""
+	data.Seats = 0
+	data.SeatServerKey = nil
+	data.TrialDuration = 0
+	data.Quotas = nil
+	data.HardwareIDs.ContainerIDs = nil
+	plain, err := EncodeLicenseData(data)
+	if err != nil {
+		t.Fatalf("Failed to encode license data: %v", err)
+	}
+	if plain[0] != versionPKCS1 {
+		t.Errorf("Expected version 1 header without extensions, got %d", plain[0])
+	}
+
+	// Extensions survive encryption
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	sealed, err := SealLicense(encoded, key.PublicKey())
+	if err != nil {
+		t.Fatalf("Failed to seal license: %v", err)
+	}
+	if sealed[2] != FlagEncrypted|FlagExtensions {
+		t.Errorf("Expected encrypted and extension flags, got %#x", sealed[2])
+	}
+	opened, err := OpenLicense(sealed, key)
+	if err != nil {
+		t.Fatalf("Failed to open license: %v", err)
+	}
+	if !bytes.Equal(opened, encoded) {
+		t.Error("Opened license differs from the original encoding")
+	}
+}
+
+func TestExtensionsStrict(t *testing.T) {
+	base, err := EncodeLicenseData(&LicenseData{ID: "license"})
+	if err != nil {
+		t.Fatalf("Failed to encode license data: %v", err)
+	}
+
+	// withBlock builds a version 2 license with a raw extension block
+	withBlock := func(block []byte) []byte {
+		var buf bytes.Buffer
+		if err := writeHeader(&buf, SchemePKCS1v15, FlagExtensions); err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(base[binary.Size(headerV1{}):])
+		buf.Write(block)
+		data := buf.Bytes()
+		binary.LittleEndian.PutUint32(data[3:7], uint32(len(data)-binary.Size(header{})))
+		return data
+	}
+
+	if _, err := DecodeLicenseData(withBlock([]byte{1, 0, extPreviousID, 2, 0, 'i', 'd'})); err != nil {
+		t.Fatalf("Valid extension block rejected: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		block []byte
+	}{
+		{"Missing", nil},
+		{"Empty", []byte{0, 0}},
+		{"UnknownTag", []byte{1, 0, 0x7F, 1, 0, 'x'}},
+		{"Duplicate", []byte{2, 0, extPreviousID, 1, 0, 'a', extPreviousID, 1, 0, 'b'}},
+		{"EmptyPreviousID", []byte{1, 0, extPreviousID, 0, 0}},
+		{"ValueBeyondInput", []byte{1, 0, extPreviousID, 9, 0, 'i', 'd'}},
+		{"InvalidUTF8", []byte{1, 0, extPreviousID, 1, 0, 0xFF}},
+		{"ZeroSeats", []byte{1, 0, extSeats, 4, 0, 0, 0, 0, 0}},
+		{"ShortSeats", []byte{1, 0, extSeats, 2, 0, 5, 0}},
+		{"ZeroTrial", []byte{1, 0, extTrial, 4, 0, 0, 0, 0, 0}},
+		{"EmptyQuotas", []byte{1, 0, extQuotas, 0, 0}},
+		{"ZeroQuota", []byte{1, 0, extQuotas, 11, 0, 1, 0, 'a', 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"TruncatedQuota", []byte{1, 0, extQuotas, 7, 0, 1, 0, 'a', 5, 0, 0, 0}},
+		{"UnsortedQuotas", []byte{1, 0, extQuotas, 22, 0,
+			1, 0, 'b', 1, 0, 0, 0, 0, 0, 0, 0,
+			1, 0, 'a', 1, 0, 0, 0, 0, 0, 0, 0}},
+		{"EmptyContainerIDs", []byte{1, 0, extContainerIDs, 2, 0, 0, 0}},
+		{"TrailingContainerIDs", []byte{1, 0, extContainerIDs, 6, 0, 1, 0, 1, 0, 'a', 'b'}},
+		{"ShortSeatServerKey", []byte{1, 0, extSeatServer, 2, 0, 1, 2}},
+		{"OutOfOrder", []byte{2, 0, extSeats, 4, 0, 5, 0, 0, 0, extPreviousID, 1, 0, 'a'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeLicenseData(withBlock(tt.block)); err == nil {
+				t.Error("Expected decoding to fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package licgen
+
+import (
+	"crypto/rsa"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// defaultBatchDays is the validity period of orders that do not set one
+const defaultBatchDays = 365
+
+// BatchOrder is one row of a batch manifest
+type BatchOrder struct {
+	// Row is the 1-based row of the order in the manifest
+	Row        int                       `json:"-"`
+	ID         string                    `json:"id"`
+	CustomerID string                    `json:"customer"`
+	ProductID  string                    `json:"product"`
+	Serial     string                    `json:"serial"`
+	Days       int                       `json:"days"`
+	Features   []string                  `json:"features"`
+	Hardware   licverify.HardwareBinding `json:"hardware"`
+
+	// Err records a row that could not be parsed, so that it is reported
+	// as a failure instead of aborting the whole batch
+	Err error `json:"-"`
+}
+
+// BatchResult is the outcome of one manifest row
+type BatchResult struct {
+	Row    int    `json:"row"`
+	ID     string `json:"id"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchReport summarizes a batch run
+type BatchReport struct {
+	Manifest  string        `json:"manifest"`
+	Generated int           `json:"generated"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// BatchGenerator generates the licenses of a batch manifest concurrently
+type BatchGenerator struct {
+	// OutputDir receives one <id>.lic file per generated license
+	OutputDir string
+	// PrivateKey signs the licenses
+	PrivateKey *rsa.PrivateKey
+	// Workers is the number of licenses generated concurrently, by
+	// default the number of CPUs
+	Workers int
+	// FillIdentity fills in missing license IDs and serial numbers. It is
+	// called concurrently. If nil, orders must contain both.
+	FillIdentity func(licenseID, serialNumber *string) error
+	// Options are passed to GenerateLicense for every order
+	Options []Option
+}
+
+// unsafeFileChars matches characters not allowed in output file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Generate generates one license per order. Failing orders are reported in
+// the results and do not stop the others; results are in order.
+func (g *BatchGenerator) Generate(orders []BatchOrder) *BatchReport {
+	workers := g.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([]BatchResult, len(orders))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = g.generate(orders[i])
+			}
+		}()
+	}
+	for i := range orders {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	report := &BatchReport{Results: results}
+	for _, result := range results {
+		if result.Error != "" {
+			report.Failed++
+		} else {
+			report.Generated++
+		}
+	}
+	return report
+}
+
+// generate generates and saves the license for one order
+func (g *BatchGenerator) generate(order BatchOrder) BatchResult {
+	result := BatchResult{Row: order.Row, ID: order.ID}
+
+	if order.Err != nil {
+		result.Error = order.Err.Error()
+		return result
+	}
+	if g.FillIdentity != nil {
+		if err := g.FillIdentity(&order.ID, &order.Serial); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+	result.ID = order.ID
+	if err := validateOrder(order); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// Reserve the output file first, so that rows with the same ID never
+	// overwrite each other and a conflicting row is not signed or recorded
+	outputPath := filepath.Join(g.OutputDir, unsafeFileChars.ReplaceAllString(order.ID, "_")+".lic")
+	file, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer file.Close()
+
+	licenseData, err := GenerateLicense(
+		order.ID,
+		order.CustomerID,
+		order.ProductID,
+		order.Serial,
+		time.Duration(order.Days)*24*time.Hour,
+		order.Features,
+		order.Hardware,
+		g.PrivateKey,
+		g.Options...,
+	)
+	if err == nil {
+		_, err = file.Write(licenseData)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(outputPath)
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Output = outputPath
+	return result
+}
+
+// validateOrder checks the required fields of an order
+func validateOrder(order BatchOrder) error {
+	var missing []string
+	for _, field := range []struct{ name, value string }{
+		{"id", order.ID},
+		{"customer", order.CustomerID},
+		{"product", order.ProductID},
+		{"serial", order.Serial},
+	} {
+		if field.value == "" {
+			missing = append(missing, field.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+	if order.Days <= 0 {
+		return fmt.Errorf("invalid validity period: %d days", order.Days)
+	}
+	return nil
+}
+
+// ReadManifest reads orders from a CSV or JSON manifest, based on the file
+// extension
+func ReadManifest(path string) ([]BatchOrder, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ReadJSONManifest(file)
+	}
+	return ReadCSVManifest(file)
+}
+
+// ReadJSONManifest reads a JSON array of orders. Orders without days are
+// valid for a year.
+func ReadJSONManifest(r io.Reader) ([]BatchOrder, error) {
+	var orders []BatchOrder
+	if err := json.NewDecoder(r).Decode(&orders); err != nil {
+		return nil, fmt.Errorf("invalid JSON manifest: %v", err)
+	}
+	for i := range orders {
+		orders[i].Row = i + 1
+		if orders[i].Days == 0 {
+			orders[i].Days = defaultBatchDays
+		}
+	}
+	return orders, nil
+}
+
+// ReadCSVManifest reads a CSV manifest with a header row. Recognized columns
+// are id, customer, product, serial, days, features and hardware. Features
+// are comma or semicolon separated, hardware entries are semicolon separated
+// kind:value pairs where kind is mac, disk, host, custom or container. Rows
+// with invalid days or hardware entries are returned with Err set.
+func ReadCSVManifest(r io.Reader) ([]BatchOrder, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV manifest: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("manifest is empty")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"id", "customer", "product", "serial"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("manifest is missing the %q column", required)
+		}
+	}
+
+	get := func(record []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var orders []BatchOrder
+	for i, record := range records[1:] {
+		order := BatchOrder{
+			Row:        i + 1,
+			ID:         get(record, "id"),
+			CustomerID: get(record, "customer"),
+			ProductID:  get(record, "product"),
+			Serial:     get(record, "serial"),
+			Days:       defaultBatchDays,
+			Features:   splitList(get(record, "features"), ",;"),
+		}
+
+		if days := get(record, "days"); days != "" {
+			if order.Days, err = strconv.Atoi(days); err != nil {
+				order.Err = fmt.Errorf("invalid days %q", days)
+			}
+		}
+
+		if order.Hardware, err = parseHardwareSpec(get(record, "hardware")); err != nil {
+			order.Err = err
+		}
+
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// parseHardwareSpec parses semicolon separated kind:value hardware entries
+func parseHardwareSpec(spec string) (licverify.HardwareBinding, error) {
+	var hardware licverify.HardwareBinding
+	for _, entry := range splitList(spec, ";") {
+		kind, value, ok := strings.Cut(entry, ":")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			return hardware, fmt.Errorf("invalid hardware entry %q", entry)
+		}
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case "mac":
+			hardware.MACAddresses = append(hardware.MACAddresses, value)
+		case "disk":
+			hardware.DiskIDs = append(hardware.DiskIDs, value)
+		case "host":
+			hardware.HostNames = append(hardware.HostNames, value)
+		case "custom":
+			hardware.CustomIDs = append(hardware.CustomIDs, value)
+		case "container":
+			hardware.ContainerIDs = append(hardware.ContainerIDs, value)
+		default:
+			return hardware, fmt.Errorf("unknown hardware kind %q", kind)
+		}
+	}
+	return hardware, nil
+}
+
+// splitList splits a list on any of the separator characters
+func splitList(list, separators string) []string {
+	var result []string
+	for _, item := range strings.FieldsFunc(list, func(r rune) bool {
+		return strings.ContainsRune(separators, r)
+	}) {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
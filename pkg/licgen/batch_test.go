@@ -0,0 +1,205 @@
+package licgen_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// TestReadCSVManifest tests CSV parsing, including malformed rows that are
+// reported per row
+func TestReadCSVManifest(t *testing.T) {
+	manifest := `id,customer,product,serial,days,features,hardware
+LIC-1, ACME, APP, SN-1, 30, "basic,pro", mac:00:11:22:33:44:55;container:k8s-cluster:abc
+LIC-2,ACME,APP,SN-2,,basic;pro,
+LIC-3,ACME,APP,SN-3,thirty,,
+LIC-4,ACME,APP,SN-4,30,,gpu:0
+LIC-5,ACME
+`
+	orders, err := licgen.ReadCSVManifest(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if len(orders) != 5 {
+		t.Fatalf("Expected 5 orders, got %d", len(orders))
+	}
+
+	first := orders[0]
+	if first.Row != 1 || first.ID != "LIC-1" || first.CustomerID != "ACME" || first.Days != 30 || first.Err != nil {
+		t.Errorf("Unexpected first order: %+v", first)
+	}
+	if !reflect.DeepEqual(first.Features, []string{"basic", "pro"}) {
+		t.Errorf("Unexpected features: %v", first.Features)
+	}
+	wantHardware := licverify.HardwareBinding{
+		MACAddresses: []string{"00:11:22:33:44:55"},
+		ContainerIDs: []string{"k8s-cluster:abc"},
+	}
+	if !reflect.DeepEqual(first.Hardware, wantHardware) {
+		t.Errorf("Unexpected hardware: %+v", first.Hardware)
+	}
+
+	if orders[1].Days != 365 || !reflect.DeepEqual(orders[1].Features, []string{"basic", "pro"}) || orders[1].Err != nil {
+		t.Errorf("Unexpected defaults: %+v", orders[1])
+	}
+	if orders[2].Err == nil || !strings.Contains(orders[2].Err.Error(), "invalid days") {
+		t.Errorf("Expected an invalid days error, got %v", orders[2].Err)
+	}
+	if orders[3].Err == nil || !strings.Contains(orders[3].Err.Error(), "unknown hardware kind") {
+		t.Errorf("Expected an unknown hardware kind error, got %v", orders[3].Err)
+	}
+	if orders[4].Row != 5 || orders[4].CustomerID != "ACME" || orders[4].Serial != "" {
+		t.Errorf("Unexpected short row: %+v", orders[4])
+	}
+
+	for name, manifest := range map[string]string{
+		"Empty":         "",
+		"MissingColumn": "id,customer,product\nLIC-1,ACME,APP\n",
+		"BadQuoting":    "id,customer,product,serial\n\"LIC-1,ACME,APP,SN-1\n",
+	} {
+		if _, err := licgen.ReadCSVManifest(strings.NewReader(manifest)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestReadJSONManifest tests JSON parsing and defaults
+func TestReadJSONManifest(t *testing.T) {
+	manifest := `[
+		{"id": "LIC-1", "customer": "ACME", "product": "APP", "serial": "SN-1", "days": 30, "features": ["basic"]},
+		{"id": "LIC-2", "customer": "ACME", "product": "APP", "serial": "SN-2", "hardware": {"host_names": ["build"]}}
+	]`
+	orders, err := licgen.ReadJSONManifest(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if len(orders) != 2 || orders[0].Row != 1 || orders[1].Row != 2 {
+		t.Fatalf("Unexpected orders: %+v", orders)
+	}
+	if orders[0].Days != 30 || orders[1].Days != 365 {
+		t.Errorf("Unexpected validity periods: %d %d", orders[0].Days, orders[1].Days)
+	}
+
+	if _, err := licgen.ReadJSONManifest(strings.NewReader(`{"id": "LIC-1"}`)); err == nil {
+		t.Error("Expected an error for a manifest that is not an array")
+	}
+}
+
+// TestBatchGenerator tests that failing rows are reported without stopping
+// the others, with any number of workers
+func TestBatchGenerator(t *testing.T) {
+	privateKeyPEM, publicKeyPEM, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	verifier, err := licverify.NewVerifier(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+
+	manifest := `id,customer,product,serial,days,features,hardware
+LIC-1,ACME,APP,SN-1,30,basic,
+LIC-2,ACME,APP,SN-2,thirty,,
+LIC-3,ACME,APP,,30,,
+LIC-1,ACME,APP,SN-4,30,,
+,ACME,APP,SN-5,30,,
+LIC-6,ACME,APP,SN-6,-1,,
+LIC-7,ACME,APP,SN-7,30,pro,host:build
+`
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("Workers%d", workers), func(t *testing.T) {
+			orders, err := licgen.ReadCSVManifest(strings.NewReader(manifest))
+			if err != nil {
+				t.Fatalf("Failed to read manifest: %v", err)
+			}
+
+			var filled atomic.Int32
+			generator := &licgen.BatchGenerator{
+				OutputDir:  t.TempDir(),
+				PrivateKey: privateKey,
+				Workers:    workers,
+				FillIdentity: func(licenseID, serialNumber *string) error {
+					filled.Add(1)
+					if *licenseID == "" {
+						*licenseID = "LIC-AUTO"
+					}
+					return nil
+				},
+			}
+			report := generator.Generate(orders)
+
+			if report.Generated != 3 || report.Failed != 4 || len(report.Results) != len(orders) {
+				t.Fatalf("Unexpected report: %+v", report)
+			}
+			for i, result := range report.Results {
+				if result.Row != i+1 {
+					t.Errorf("Result %d is for row %d", i, result.Row)
+				}
+			}
+
+			failed := map[int]string{
+				2: "invalid days",
+				3: "missing required fields: serial",
+				4: "exists",
+				6: "invalid validity period",
+			}
+			// Rows 1 and 4 race for the same ID, only one of them wins
+			if report.Results[0].Error != "" {
+				failed[1] = "exists"
+				delete(failed, 4)
+			}
+			for i, result := range report.Results {
+				want, ok := failed[result.Row]
+				if !ok {
+					if result.Error != "" {
+						t.Errorf("Row %d failed: %s", result.Row, result.Error)
+						continue
+					}
+					data, err := os.ReadFile(result.Output)
+					if err != nil {
+						t.Fatalf("Row %d: failed to read license: %v", result.Row, err)
+					}
+					license, err := verifier.ParseLicense(data)
+					if err != nil {
+						t.Fatalf("Row %d: failed to parse license: %v", result.Row, err)
+					}
+					if license.ID() != result.ID || license.ProductID() != orders[i].ProductID {
+						t.Errorf("Row %d: unexpected license %s", result.Row, license.ID())
+					}
+					continue
+				}
+				if !strings.Contains(result.Error, want) {
+					t.Errorf("Row %d: expected error containing %q, got %q", result.Row, want, result.Error)
+				}
+				if result.Output != "" {
+					t.Errorf("Row %d: failed row has output %s", result.Row, result.Output)
+				}
+			}
+			if report.Results[4].ID != "LIC-AUTO" {
+				t.Errorf("Expected the filled in ID, got %q", report.Results[4].ID)
+			}
+			if filled.Load() != 6 {
+				t.Errorf("Expected FillIdentity for the 6 parsed rows, got %d calls", filled.Load())
+			}
+
+			files, err := filepath.Glob(filepath.Join(generator.OutputDir, "*.lic"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != report.Generated {
+				t.Errorf("Expected %d license files, found %v", report.Generated, files)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package licgen
 
 import (
 	"crypto"
+	"crypto/ecdh"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -9,6 +10,8 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
 )
 
 // GenerateKeyPair generates an RSA key pair for license signing
@@ -54,13 +57,101 @@ func ParsePrivateKey(privateKeyPEM string) (*rsa.PrivateKey, error) {
 	return privateKey, nil
 }
 
-// SignData signs data with the provided private key
+// GenerateEncryptionKeyPair generates an X25519 key pair for license payload encryption.
+// Licenses are encrypted to the public key, and the private key is given to the
+// application (per product) or kept on the customer's machine (per machine).
+func GenerateEncryptionKeyPair() (privateKey, publicKey string, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate X25519 key: %v", err)
+	}
+
+	// Convert private key to PEM format
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal private key: %v", err)
+	}
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: privateKeyBytes,
+	})
+
+	// Convert public key to PEM format
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(key.PublicKey())
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal public key: %v", err)
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyBytes,
+	})
+
+	return string(privateKeyPEM), string(publicKeyPEM), nil
+}
+
+// ParsePublicKey parses a PEM-encoded RSA public key
+func ParsePublicKey(publicKeyPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, errors.New("failed to parse PEM block containing the public key")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %v", err)
+	}
+
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key")
+	}
+
+	return rsaPub, nil
+}
+
+// ParseEncryptionPublicKey parses a PEM-encoded X25519 public key
+func ParseEncryptionPublicKey(publicKeyPEM string) (*ecdh.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, errors.New("failed to parse PEM block containing the public key")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %v", err)
+	}
+
+	ecdhPub, ok := pub.(*ecdh.PublicKey)
+	if !ok || ecdhPub.Curve() != ecdh.X25519() {
+		return nil, errors.New("not an X25519 public key")
+	}
+
+	return ecdhPub, nil
+}
+
+// SignData signs data with the provided private key using PKCS#1 v1.5
 func SignData(data []byte, privateKey *rsa.PrivateKey) ([]byte, error) {
+	return SignDataWithScheme(data, privateKey, licformat.SchemePKCS1v15)
+}
+
+// SignDataWithScheme signs data with the provided private key using the given signature scheme
+func SignDataWithScheme(data []byte, privateKey *rsa.PrivateKey, scheme licformat.SignatureScheme) ([]byte, error) {
 	// Calculate hash of data
 	hashed := sha256.Sum256(data)
 
 	// Sign the hash
-	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+	var signature []byte
+	var err error
+	switch scheme {
+	case licformat.SchemePKCS1v15:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+	case licformat.SchemePSS:
+		signature, err = rsa.SignPSS(rand.Reader, privateKey, crypto.SHA256, hashed[:], &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported signature scheme: %v", scheme)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign data: %v", err)
 	}
@@ -0,0 +1,61 @@
+package licgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// EmbeddedKeySource returns a Go source file that declares varName in
+// packageName as the embedded key, for licverify.NewVerifierFromEmbedded.
+// The file does not contain the key pin, which the caller keeps in its own
+// code. It is the output of licforge embed-key and is meant to be regenerated with
+// go generate rather than edited.
+func EmbeddedKeySource(key *licverify.EmbeddedKey, packageName, varName string) ([]byte, error) {
+	if !token.IsIdentifier(packageName) {
+		return nil, fmt.Errorf("invalid package name %q", packageName)
+	}
+	if !token.IsIdentifier(varName) {
+		return nil, fmt.Errorf("invalid variable name %q", varName)
+	}
+	if len(key.Shares) < 2 {
+		return nil, errors.New("embedded key has fewer than two shares")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by licforge embed-key. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", packageName)
+	fmt.Fprintf(&buf, "import \"github.com/luhtfiimanal/go-license/v2/pkg/licverify\"\n\n")
+	fmt.Fprintf(&buf, "// %s is the license public key, split into shares.\n", varName)
+	fmt.Fprintf(&buf, "// Create a verifier with licverify.NewVerifierFromEmbedded(%s, pin),\n", varName)
+	fmt.Fprintf(&buf, "// with the key pin printed by licforge embed-key kept outside this file.\n")
+	fmt.Fprintf(&buf, "var %s = &licverify.EmbeddedKey{\n", varName)
+	fmt.Fprintf(&buf, "Shares: [][]byte{\n")
+	for _, share := range key.Shares {
+		writeByteSlice(&buf, share)
+		buf.WriteString(",\n")
+	}
+	fmt.Fprintf(&buf, "},\n}\n")
+
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format embedded key source: %v", err)
+	}
+	return source, nil
+}
+
+// writeByteSlice writes a byte slice literal, 16 bytes per line
+func writeByteSlice(buf *bytes.Buffer, data []byte) {
+	buf.WriteString("{")
+	for i, b := range data {
+		if i%16 == 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(buf, "0x%02x,", b)
+	}
+	buf.WriteString("\n}")
+}
@@ -0,0 +1,54 @@
+package licgen_test
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// TestEmbeddedKeySource tests that the generated source declares the key without the PEM block
+func TestEmbeddedKeySource(t *testing.T) {
+	_, publicKeyPEM, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	key, err := licverify.NewEmbeddedKey(publicKeyPEM, 3)
+	if err != nil {
+		t.Fatalf("Failed to embed key: %v", err)
+	}
+
+	source, err := licgen.EmbeddedKeySource(key, "main", "licenseKey")
+	if err != nil {
+		t.Fatalf("Failed to generate source: %v", err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "license_key.go", source, 0)
+	if err != nil {
+		t.Fatalf("Generated source does not parse: %v\n%s", err, source)
+	}
+	if file.Name.Name != "main" || file.Scope.Lookup("licenseKey") == nil {
+		t.Errorf("Generated source does not declare main.licenseKey:\n%s", source)
+	}
+	if !bytes.HasPrefix(source, []byte("// Code generated by licforge embed-key. DO NOT EDIT.")) {
+		t.Error("Generated source is not marked as generated")
+	}
+	if bytes.Contains(source, []byte("PUBLIC KEY")) {
+		t.Error("Generated source contains the PEM block")
+	}
+	pin, err := licverify.PublicKeyPin(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to compute key pin: %v", err)
+	}
+	if bytes.Contains(source, []byte(pin)) {
+		t.Error("Generated source contains the key pin")
+	}
+
+	for _, name := range [][2]string{{"main", "1key"}, {"my-app", "licenseKey"}} {
+		if _, err := licgen.EmbeddedKeySource(key, name[0], name[1]); err == nil {
+			t.Errorf("Expected an error for package %q and variable %q", name[0], name[1])
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package licgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IDFormat selects the format of generated license IDs
+type IDFormat int
+
+const (
+	// IDFormatUUIDv7 is a time-ordered RFC 9562 UUID version 7 (default)
+	IDFormatUUIDv7 IDFormat = iota
+	// IDFormatULID is a 26 character Crockford base32 ULID
+	IDFormatULID
+)
+
+// String returns the name of the ID format
+func (f IDFormat) String() string {
+	switch f {
+	case IDFormatUUIDv7:
+		return "uuidv7"
+	case IDFormatULID:
+		return "ulid"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(f))
+	}
+}
+
+// ParseIDFormat parses an ID format name as returned by String
+func ParseIDFormat(name string) (IDFormat, error) {
+	switch strings.ToLower(name) {
+	case "uuidv7", "uuid":
+		return IDFormatUUIDv7, nil
+	case "ulid":
+		return IDFormatULID, nil
+	default:
+		return 0, fmt.Errorf("unknown ID format %q", name)
+	}
+}
+
+// GenerateID generates a unique, time-ordered license ID
+func GenerateID(format IDFormat) (string, error) {
+	switch format {
+	case IDFormatUUIDv7:
+		return NewUUIDv7()
+	case IDFormatULID:
+		return NewULID()
+	default:
+		return "", fmt.Errorf("unknown ID format %v", format)
+	}
+}
+
+// newTimeOrderedID returns 16 bytes starting with the big-endian 48-bit
+// Unix millisecond timestamp followed by random bytes
+func newTimeOrderedID() ([16]byte, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[6:]); err != nil {
+		return id, fmt.Errorf("failed to read random bytes: %v", err)
+	}
+
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(time.Now().UnixMilli()))
+	copy(id[:6], ts[2:])
+	return id, nil
+}
+
+// NewUUIDv7 generates a UUID version 7 in its canonical string form
+func NewUUIDv7() (string, error) {
+	id, err := newTimeOrderedID()
+	if err != nil {
+		return "", err
+	}
+	id[6] = id[6]&0x0f | 0x70 // Version 7
+	id[8] = id[8]&0x3f | 0x80 // RFC 9562 variant
+
+	h := hex.EncodeToString(id[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// crockfordAlphabet is the Crockford base32 alphabet used by ULIDs
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID generates a ULID in its canonical 26 character form
+func NewULID() (string, error) {
+	id, err := newTimeOrderedID()
+	if err != nil {
+		return "", err
+	}
+
+	// 128 bits are encoded as 26 characters of 5 bits, with 2 leading zero bits
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out), nil
+}
+
+// CheckDigit selects the check digit appended to generated serial numbers
+type CheckDigit int
+
+const (
+	// CheckDigitNone appends no check digit
+	CheckDigitNone CheckDigit = iota
+	// CheckDigitLuhn appends a Luhn (mod 10) check digit over the sequence number
+	CheckDigitLuhn
+)
+
+// SerialScheme describes the layout of generated serial numbers:
+// the prefix, the zero padded sequence number and an optional check digit.
+type SerialScheme struct {
+	Prefix     string
+	Width      int // Minimum number of sequence digits
+	CheckDigit CheckDigit
+}
+
+// ParseSerialScheme parses a comma-separated serial scheme specification
+// such as "prefix=SN-,width=8,check=luhn". Width defaults to 6.
+func ParseSerialScheme(spec string) (SerialScheme, error) {
+	scheme := SerialScheme{Width: 6}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return scheme, fmt.Errorf("invalid serial scheme option %q", part)
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "prefix":
+			scheme.Prefix = value
+		case "width":
+			width, err := strconv.Atoi(value)
+			if err != nil || width < 1 || width > 19 {
+				return scheme, fmt.Errorf("invalid serial width %q", value)
+			}
+			scheme.Width = width
+		case "check":
+			switch strings.ToLower(value) {
+			case "none", "":
+				scheme.CheckDigit = CheckDigitNone
+			case "luhn":
+				scheme.CheckDigit = CheckDigitLuhn
+			default:
+				return scheme, fmt.Errorf("unknown check digit %q", value)
+			}
+		default:
+			return scheme, fmt.Errorf("unknown serial scheme option %q", key)
+		}
+	}
+	return scheme, nil
+}
+
+// Format returns the serial number for a sequence number
+func (s SerialScheme) Format(sequence uint64) string {
+	digits := fmt.Sprintf("%0*d", s.Width, sequence)
+	if s.CheckDigit == CheckDigitLuhn {
+		digits += string(luhnCheckDigit(digits))
+	}
+	return s.Prefix + digits
+}
+
+// Validate checks that a serial number matches the scheme, including its check digit
+func (s SerialScheme) Validate(serial string) error {
+	digits, ok := strings.CutPrefix(serial, s.Prefix)
+	if !ok {
+		return fmt.Errorf("serial number %q does not start with %q", serial, s.Prefix)
+	}
+	minLength := s.Width
+	if s.CheckDigit == CheckDigitLuhn {
+		minLength++
+	}
+	if len(digits) < minLength || strings.Trim(digits, "0123456789") != "" {
+		return fmt.Errorf("serial number %q has an invalid sequence", serial)
+	}
+	if s.CheckDigit == CheckDigitLuhn {
+		body, check := digits[:len(digits)-1], digits[len(digits)-1]
+		if luhnCheckDigit(body) != check {
+			return fmt.Errorf("serial number %q has an invalid check digit", serial)
+		}
+	}
+	return nil
+}
+
+// luhnCheckDigit computes the Luhn check digit of a string of decimal digits
+func luhnCheckDigit(digits string) byte {
+	sum := 0
+	double := true // The check digit itself will be in the undoubled position
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// SerialCounter hands out sequence numbers for serial numbers
+type SerialCounter interface {
+	// Next returns the next sequence number for the given prefix, starting at 1
+	Next(prefix string) (uint64, error)
+}
+
+// FileCounter is a SerialCounter persisted as a JSON file mapping prefixes
+// to the last issued sequence number. It is safe for concurrent use within
+// a process, and a lock file guards against concurrent processes.
+type FileCounter struct {
+	path string
+	mu   sync.Mutex
+}
+
+// lockTimeout is how long FileCounter waits for another process' lock
+const lockTimeout = 5 * time.Second
+
+// NewFileCounter returns a counter stored at path. The file is created on first use.
+func NewFileCounter(path string) *FileCounter {
+	return &FileCounter{path: path}
+}
+
+// Next increments and persists the sequence number for prefix
+func (c *FileCounter) Next(prefix string) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	unlock, err := c.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	counters := make(map[string]uint64)
+	data, err := os.ReadFile(c.path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &counters); err != nil {
+			return 0, fmt.Errorf("failed to parse serial counter file: %v", err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return 0, fmt.Errorf("failed to read serial counter file: %v", err)
+	}
+
+	counters[prefix]++
+	next := counters[prefix]
+
+	data, err = json.MarshalIndent(counters, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode serial counter file: %v", err)
+	}
+
+	// Write atomically so a crash never loses issued sequence numbers
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write serial counter file: %v", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return 0, fmt.Errorf("failed to write serial counter file: %v", err)
+	}
+
+	return next, nil
+}
+
+// lock creates the lock file next to the counter file, waiting for other processes
+func (c *FileCounter) lock() (func(), error) {
+	if dir := filepath.Dir(c.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create serial counter directory: %v", err)
+		}
+	}
+
+	lockPath := c.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock serial counter file: %v", err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for serial counter lock %s (remove it if no other licforge is running)", lockPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// SerialGenerator generates serial numbers from a scheme and a counter
+type SerialGenerator struct {
+	scheme  SerialScheme
+	counter SerialCounter
+}
+
+// NewSerialGenerator returns a generator for scheme backed by counter
+func NewSerialGenerator(scheme SerialScheme, counter SerialCounter) *SerialGenerator {
+	return &SerialGenerator{scheme: scheme, counter: counter}
+}
+
+// Next returns the next serial number
+func (g *SerialGenerator) Next() (string, error) {
+	sequence, err := g.counter.Next(g.scheme.Prefix)
+	if err != nil {
+		return "", err
+	}
+	return g.scheme.Format(sequence), nil
+}
@@ -0,0 +1,147 @@
+package licgen_test
+
+import (
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+)
+
+// TestGenerateID tests UUIDv7 and ULID generation
+func TestGenerateID(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulidPattern := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+	for _, tc := range []struct {
+		format  licgen.IDFormat
+		pattern *regexp.Regexp
+	}{
+		{licgen.IDFormatUUIDv7, uuidPattern},
+		{licgen.IDFormatULID, ulidPattern},
+	} {
+		t.Run(tc.format.String(), func(t *testing.T) {
+			seen := make(map[string]bool)
+			previous := ""
+			for i := 0; i < 100; i++ {
+				id, err := licgen.GenerateID(tc.format)
+				if err != nil {
+					t.Fatalf("Failed to generate ID: %v", err)
+				}
+				if !tc.pattern.MatchString(id) {
+					t.Fatalf("ID %q does not match the %v format", id, tc.format)
+				}
+				if seen[id] {
+					t.Fatalf("Duplicate ID %q", id)
+				}
+				seen[id] = true
+
+				// The timestamp prefix keeps IDs ordered across milliseconds
+				if previous != "" && id[:8] < previous[:8] {
+					t.Errorf("ID %q sorts before previous ID %q", id, previous)
+				}
+				previous = id
+			}
+		})
+	}
+
+	format, err := licgen.ParseIDFormat("ULID")
+	if err != nil || format != licgen.IDFormatULID {
+		t.Errorf("ParseIDFormat(ULID) = %v, %v", format, err)
+	}
+	if _, err := licgen.ParseIDFormat("uuidv4"); err == nil {
+		t.Error("Expected error for unknown ID format")
+	}
+}
+
+// TestSerialScheme tests serial number formatting and validation
+func TestSerialScheme(t *testing.T) {
+	scheme, err := licgen.ParseSerialScheme("prefix=SN-,width=10,check=luhn")
+	if err != nil {
+		t.Fatalf("Failed to parse serial scheme: %v", err)
+	}
+
+	// 7992739871 has the well-known Luhn check digit 3
+	serial := scheme.Format(7992739871)
+	if serial != "SN-79927398713" {
+		t.Errorf("Expected SN-79927398713, got %s", serial)
+	}
+	if err := scheme.Validate(serial); err != nil {
+		t.Errorf("Valid serial rejected: %v", err)
+	}
+	for _, invalid := range []string{"SN-79927398710", "XX-79927398713", "SN-7992739A713", "SN-1"} {
+		if err := scheme.Validate(invalid); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+
+	plain, err := licgen.ParseSerialScheme("prefix=ACME")
+	if err != nil {
+		t.Fatalf("Failed to parse serial scheme: %v", err)
+	}
+	if serial := plain.Format(42); serial != "ACME000042" {
+		t.Errorf("Expected ACME000042, got %s", serial)
+	}
+
+	for _, spec := range []string{"width=0", "check=crc", "prefix", "color=red"} {
+		if _, err := licgen.ParseSerialScheme(spec); err == nil {
+			t.Errorf("Expected error for serial scheme %q", spec)
+		}
+	}
+}
+
+// TestSerialGenerator tests that serial numbers are unique and persisted
+func TestSerialGenerator(t *testing.T) {
+	counterPath := filepath.Join(t.TempDir(), "state", "serials.json")
+	scheme := licgen.SerialScheme{Prefix: "SN-", Width: 4, CheckDigit: licgen.CheckDigitLuhn}
+
+	generator := licgen.NewSerialGenerator(scheme, licgen.NewFileCounter(counterPath))
+
+	const count = 50
+	serials := make(chan string, count)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serial, err := generator.Next()
+			if err != nil {
+				t.Errorf("Failed to generate serial: %v", err)
+				return
+			}
+			serials <- serial
+		}()
+	}
+	wg.Wait()
+	close(serials)
+
+	seen := make(map[string]bool)
+	for serial := range serials {
+		if seen[serial] {
+			t.Errorf("Duplicate serial %s", serial)
+		}
+		seen[serial] = true
+		if err := scheme.Validate(serial); err != nil {
+			t.Errorf("Generated serial is invalid: %v", err)
+		}
+	}
+
+	// A new counter on the same file continues the sequence
+	next, err := licgen.NewSerialGenerator(scheme, licgen.NewFileCounter(counterPath)).Next()
+	if err != nil {
+		t.Fatalf("Failed to generate serial: %v", err)
+	}
+	if want := scheme.Format(count + 1); next != want {
+		t.Errorf("Expected %s after restart, got %s", want, next)
+	}
+
+	// Other prefixes have their own sequence
+	other, err := licgen.NewSerialGenerator(licgen.SerialScheme{Prefix: "EV-", Width: 4}, licgen.NewFileCounter(counterPath)).Next()
+	if err != nil {
+		t.Fatalf("Failed to generate serial: %v", err)
+	}
+	if other != "EV-0001" {
+		t.Errorf("Expected EV-0001, got %s", other)
+	}
+}
@@ -0,0 +1,35 @@
+package licgen
+
+import (
+	"crypto/rsa"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// SignLease signs a check-in lease with the license signing key. Leases are
+// always signed with RSA-PSS, whatever the scheme of the license.
+func SignLease(lease *licverify.Lease, privateKey *rsa.PrivateKey) (*licverify.SignedLease, error) {
+	payload, err := licverify.EncodeLease(lease)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := SignDataWithScheme(payload, privateKey, licformat.SchemePSS)
+	if err != nil {
+		return nil, err
+	}
+	return &licverify.SignedLease{Payload: payload, Signature: signature}, nil
+}
+
+// SignSeatLease signs a floating license seat lease with the seat server's key
+func SignSeatLease(lease *licverify.SeatLease, privateKey *rsa.PrivateKey) (*licverify.SignedLease, error) {
+	payload, err := licverify.EncodeSeatLease(lease)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := SignDataWithScheme(payload, privateKey, licformat.SchemePSS)
+	if err != nil {
+		return nil, err
+	}
+	return &licverify.SignedLease{Payload: payload, Signature: signature}, nil
+}
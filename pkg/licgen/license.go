@@ -2,8 +2,11 @@ package licgen
 
 import (
 	"crypto/rsa"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
@@ -20,9 +23,12 @@ func GenerateLicense(
 	features []string,
 	hardwareIDs licverify.HardwareBinding,
 	privateKey *rsa.PrivateKey,
+	opts ...Option,
 ) ([]byte, error) {
+	o := newOptions(opts)
+
 	// Create the license
-	license := licverify.License{
+	license := licformat.License{
 		ID:           id,
 		CustomerID:   customerID,
 		ProductID:    productID,
@@ -30,42 +36,254 @@ func GenerateLicense(
 		IssueDate:    time.Now(),
 		ExpiryDate:   time.Now().Add(expiryDuration),
 		Features:     features,
-		HardwareIDs:  hardwareIDs,
+		HardwareIDs:  toFormatBinding(hardwareIDs),
+	}
+
+	return signLicense(&license, privateKey, o)
+}
+
+// GenerateTrialLicense creates a trial license that is not bound to a
+// customer or hardware. The trial lasts trialDuration from the first run
+// on each machine, as recorded by licverify.TrialTracker, and always ends
+// when the license expires, availability after issuance.
+func GenerateTrialLicense(
+	id string,
+	productID string,
+	trialDuration time.Duration,
+	availability time.Duration,
+	features []string,
+	privateKey *rsa.PrivateKey,
+	opts ...Option,
+) ([]byte, error) {
+	if trialDuration <= 0 {
+		return nil, errors.New("trial duration must be positive")
+	}
+	if availability < trialDuration {
+		return nil, errors.New("availability must be at least the trial duration")
+	}
+
+	license := licformat.License{
+		ID:            id,
+		ProductID:     productID,
+		IssueDate:     time.Now(),
+		ExpiryDate:    time.Now().Add(availability),
+		Features:      features,
+		TrialDuration: trialDuration,
+	}
+
+	return signLicense(&license, privateKey, newOptions(opts))
+}
+
+// MigrateLegacyLicense re-signs a legacy v1.x JSON license file in the binary format.
+// The legacy signature is verified against the public half of privateKey first, and
+// all license fields, including the issue and expiry dates, are preserved.
+func MigrateLegacyLicense(legacyData []byte, privateKey *rsa.PrivateKey, opts ...Option) ([]byte, error) {
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey, licverify.WithLegacyJSON())
+
+	license, err := verifier.ParseLicense(legacyData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse legacy license: %v", err)
+	}
+	if !license.IsLegacyJSON() {
+		return nil, errors.New("license is already in the binary format")
+	}
+	if err := verifier.VerifySignature(license); err != nil {
+		return nil, fmt.Errorf("failed to verify legacy license: %v", err)
+	}
+
+	migrated := toFormatLicense(license)
+	return signLicense(&migrated, privateKey, newOptions(opts))
+}
+
+// Renew issues a new license that extends an existing one. The previous
+// license is verified against the public half of privateKey, and its
+// customer, product, serial number, features and hardware binding are
+// preserved. The new license is valid for validity starting at the previous
+// expiry date, or now if the previous license has already expired, and
+// records the previous license ID. A UUIDv7 ID is generated if newID is empty.
+// The signature scheme of the previous license is kept unless
+// WithSignatureScheme is passed; encryption options must be passed again.
+// An encrypted previous license is decoded with WithDecryptionKey, a
+// machine-bound one with the hardware info passed to WithMachineBinding.
+func Renew(previousData []byte, newID string, validity time.Duration, privateKey *rsa.PrivateKey, opts ...Option) ([]byte, error) {
+	previous, err := parseIssuedLicense(previousData, privateKey, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	renewed, err := deriveLicense(previous, newID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := renewed.IssueDate
+	if previous.ExpiryDate().After(start) {
+		start = previous.ExpiryDate()
+	}
+	renewed.ExpiryDate = start.Add(validity)
+
+	return signLicense(&renewed, privateKey, newOptions(append([]Option{WithSignatureScheme(previous.SignatureScheme())}, opts...)))
+}
+
+// deriveLicense copies a license as the basis of a replacement with a new
+// ID, issued now, that records the ID of the previous license. A UUIDv7 ID
+// is generated if newID is empty.
+func deriveLicense(previous *licverify.License, newID string) (licformat.License, error) {
+	if newID == "" {
+		var err error
+		if newID, err = GenerateID(IDFormatUUIDv7); err != nil {
+			return licformat.License{}, err
+		}
+	}
+	if newID == previous.ID() {
+		return licformat.License{}, errors.New("replacement license must have a new ID")
+	}
+
+	derived := toFormatLicense(previous)
+	derived.ID = newID
+	derived.IssueDate = time.Now()
+	derived.PreviousID = previous.ID()
+	return derived, nil
+}
+
+// parseIssuedLicense parses a binary license and verifies that it was
+// signed with privateKey. Encrypted and machine-bound licenses are decoded
+// with the decryption key and hardware info of o.
+func parseIssuedLicense(data []byte, privateKey *rsa.PrivateKey, o *options) (*licverify.License, error) {
+	var verifierOpts []licverify.Option
+	if o.decryption != nil {
+		verifierOpts = append(verifierOpts, licverify.WithDecryptionKey(o.decryption))
+	}
+	if o.machine != nil {
+		verifierOpts = append(verifierOpts, licverify.WithHardwareInfo(o.machine))
+	}
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey, verifierOpts...)
+
+	license, err := verifier.ParseLicense(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse license: %w", err)
+	}
+	if err := verifier.VerifySignature(license); err != nil {
+		return nil, fmt.Errorf("failed to verify license: %w", err)
+	}
+	return license, nil
+}
+
+// signLicense encodes the license in the binary format and appends its signature
+func signLicense(license *licformat.License, privateKey *rsa.PrivateKey, o *options) ([]byte, error) {
+	license.Scheme = o.scheme
+	if o.seats != 0 {
+		license.Seats = o.seats
+	}
+	if o.seatServer != nil {
+		serverID, err := licverify.SeatServerKeyID(o.seatServer)
+		if err != nil {
+			return nil, err
+		}
+		license.SeatServerKey = serverID
+	}
+	if license.Seats != 0 && len(license.SeatServerKey) == 0 {
+		return nil, errors.New("floating licenses require a seat server key")
+	}
+	if len(o.quotas) > 0 {
+		quotas := maps.Clone(license.Quotas)
+		if quotas == nil {
+			quotas = make(map[string]uint64)
+		}
+		maps.Copy(quotas, o.quotas)
+		license.Quotas = quotas
 	}
 
 	// Convert the license to binary format
-	licenseFormatObj := licformat.License{
-		ID:           license.ID,
-		CustomerID:   license.CustomerID,
-		ProductID:    license.ProductID,
-		SerialNumber: license.SerialNumber,
-		IssueDate:    license.IssueDate,
-		ExpiryDate:   license.ExpiryDate,
-		Features:     license.Features,
-		HardwareIDs: licformat.HardwareBinding{
-			MACAddresses: license.HardwareIDs.MACAddresses,
-			DiskIDs:      license.HardwareIDs.DiskIDs,
-			HostNames:    license.HardwareIDs.HostNames,
-			CustomIDs:    license.HardwareIDs.CustomIDs},
-	}
-
-	licenseData, err := licformat.EncodeLicense(&licenseFormatObj)
+	licenseData, err := licformat.EncodeLicense(license)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode license: %v", err)
+		return nil, fmt.Errorf("failed to encode license: %w", err)
+	}
+
+	// Encrypt the license contents before signing
+	switch {
+	case o.recipient != nil && o.bound:
+		return nil, errors.New("encryption and machine binding cannot be combined")
+	case o.recipient != nil:
+		licenseData, err = licformat.SealLicense(licenseData, o.recipient)
+	case o.bound && o.machine == nil:
+		return nil, errors.New("machine binding requires hardware info")
+	case o.bound:
+		licenseData, err = licformat.SealLicenseWithFingerprint(licenseData, o.machine.Fingerprint())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt license: %v", err)
 	}
 
 	// Sign the license
-	signature, err := SignData(licenseData, privateKey)
+	signature, err := SignDataWithScheme(licenseData, privateKey, o.scheme)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign license: %v", err)
 	}
 
 	// Combine license data and signature
 	licenseFile := append(licenseData, signature...)
+
+	if o.recorder != nil {
+		license.Signature = signature
+		if err := o.recorder.RecordIssued(license, licenseFile); err != nil {
+			return nil, fmt.Errorf("failed to record license: %w", err)
+		}
+	}
+
 	return licenseFile, nil
 }
 
-// SaveLicenseToFile saves a license to a file
+// toFormatLicense copies the fields of a verified license
+func toFormatLicense(license *licverify.License) licformat.License {
+	return licformat.License{
+		ID:            license.ID(),
+		CustomerID:    license.CustomerID(),
+		ProductID:     license.ProductID(),
+		SerialNumber:  license.SerialNumber(),
+		IssueDate:     license.IssueDate(),
+		ExpiryDate:    license.ExpiryDate(),
+		Features:      license.Features(),
+		HardwareIDs:   toFormatBinding(license.HardwareIDs()),
+		PreviousID:    license.PreviousID(),
+		Seats:         license.Seats(),
+		SeatServerKey: license.SeatServerKey(),
+		TrialDuration: license.TrialDuration(),
+		Quotas:        license.Quotas(),
+	}
+}
+
+// toFormatBinding converts a hardware binding to its licformat representation
+func toFormatBinding(hardwareIDs licverify.HardwareBinding) licformat.HardwareBinding {
+	return licformat.HardwareBinding{
+		MACAddresses: hardwareIDs.MACAddresses,
+		DiskIDs:      hardwareIDs.DiskIDs,
+		HostNames:    hardwareIDs.HostNames,
+		CustomIDs:    hardwareIDs.CustomIDs,
+		ContainerIDs: hardwareIDs.ContainerIDs,
+	}
+}
+
+// SaveLicenseToFile saves a license to a file. The file is written to a
+// temporary file next to it and renamed into place, so that a failed write
+// never leaves a truncated license behind.
 func SaveLicenseToFile(licenseData []byte, filePath string) error {
-	return os.WriteFile(filePath, licenseData, 0644)
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(licenseData); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
 }
@@ -1,11 +1,16 @@
 package licgen_test
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
 	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
 	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
 )
@@ -77,3 +82,361 @@ func TestGenerateLicense(t *testing.T) {
 		t.Fatalf("License file too small: %d bytes", fileInfo.Size())
 	}
 }
+
+// TestSaveLicenseToFile tests that saving replaces an existing license
+// without leaving temporary files behind
+func TestSaveLicenseToFile(t *testing.T) {
+	tempDir := t.TempDir()
+	licenseFilePath := filepath.Join(tempDir, "license.lic")
+	for _, data := range [][]byte{[]byte("previous license"), []byte("renewed")} {
+		if err := licgen.SaveLicenseToFile(data, licenseFilePath); err != nil {
+			t.Fatalf("Failed to save license: %v", err)
+		}
+		saved, err := os.ReadFile(licenseFilePath)
+		if err != nil {
+			t.Fatalf("Failed to read license: %v", err)
+		}
+		if string(saved) != string(data) {
+			t.Errorf("Expected %q, got %q", data, saved)
+		}
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the license file, got %d entries", len(entries))
+	}
+
+	if err := licgen.SaveLicenseToFile([]byte("lost"), filepath.Join(tempDir, "missing", "license.lic")); err == nil {
+		t.Error("Expected saving to a missing directory to fail")
+	}
+}
+
+// TestGenerateLicenseSignatureScheme tests that the signature scheme is recorded in the license
+func TestGenerateLicenseSignatureScheme(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	for _, scheme := range []licformat.SignatureScheme{licformat.SchemePKCS1v15, licformat.SchemePSS} {
+		licenseData, err := licgen.GenerateLicense(
+			"TEST-LICENSE-001",
+			"CUSTOMER-001",
+			"PRODUCT-001",
+			"SERIAL-001",
+			24*time.Hour,
+			[]string{"feature1"},
+			licverify.HardwareBinding{},
+			privateKey,
+			licgen.WithSignatureScheme(scheme),
+		)
+		if err != nil {
+			t.Fatalf("Failed to generate %s license: %v", scheme, err)
+		}
+
+		decoded, err := licformat.DecodeLicense(licenseData[:len(licenseData)-privateKey.Size()])
+		if err != nil {
+			t.Fatalf("Failed to decode %s license: %v", scheme, err)
+		}
+		if decoded.Scheme != scheme {
+			t.Errorf("Scheme mismatch: expected %v, got %v", scheme, decoded.Scheme)
+		}
+	}
+
+	// Unknown schemes fail before signing
+	_, err = licgen.GenerateLicense("ID", "C", "P", "S", time.Hour, nil, licverify.HardwareBinding{}, privateKey,
+		licgen.WithSignatureScheme(licformat.SignatureScheme(0x7F)))
+	if err == nil {
+		t.Errorf("Expected unknown signature scheme to be rejected")
+	}
+}
+
+// TestMigrateLegacyLicense tests re-signing a legacy JSON license in the binary format
+func TestMigrateLegacyLicense(t *testing.T) {
+	privateKeyPEM, publicKeyPEM, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	// Create a signed legacy JSON license
+	expiry := time.Now().AddDate(0, 6, 0).Truncate(time.Second)
+	legacyData, err := json.Marshal(legacyLicense{
+		ID:           "LEGACY-001",
+		CustomerID:   "CUSTOMER-001",
+		ProductID:    "PRODUCT-001",
+		SerialNumber: "SERIAL-001",
+		IssueDate:    time.Now().AddDate(-1, 0, 0).Truncate(time.Second),
+		ExpiryDate:   expiry,
+		Features:     []string{"feature1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal license: %v", err)
+	}
+	signature, err := licgen.SignData(legacyData, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to sign license: %v", err)
+	}
+	legacyFile := append(legacyData, signature...)
+
+	migrated, err := licgen.MigrateLegacyLicense(legacyFile, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to migrate license: %v", err)
+	}
+
+	// The migrated license verifies without legacy support
+	verifier, err := licverify.NewVerifier(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	license, err := verifier.ParseLicense(migrated)
+	if err != nil {
+		t.Fatalf("Failed to parse migrated license: %v", err)
+	}
+	if err := verifier.VerifySignature(license); err != nil {
+		t.Errorf("Signature verification failed: %v", err)
+	}
+	if license.ID() != "LEGACY-001" || license.SerialNumber() != "SERIAL-001" {
+		t.Errorf("License identity not preserved: %s / %s", license.ID(), license.SerialNumber())
+	}
+	if !license.ExpiryDate().Equal(expiry) {
+		t.Errorf("ExpiryDate mismatch: expected %v, got %v", expiry, license.ExpiryDate())
+	}
+
+	// Migrating a binary license or a tampered legacy license fails
+	if _, err := licgen.MigrateLegacyLicense(migrated, privateKey); err == nil {
+		t.Errorf("Expected migrating a binary license to fail")
+	}
+	legacyFile[10] ^= 0xFF
+	if _, err := licgen.MigrateLegacyLicense(legacyFile, privateKey); err == nil {
+		t.Errorf("Expected migrating a tampered legacy license to fail")
+	}
+}
+
+// legacyLicense mirrors the v1.x JSON license layout
+type legacyLicense struct {
+	ID           string                    `json:"id"`
+	CustomerID   string                    `json:"customer_id"`
+	ProductID    string                    `json:"product_id"`
+	SerialNumber string                    `json:"serial_number"`
+	IssueDate    time.Time                 `json:"issue_date"`
+	ExpiryDate   time.Time                 `json:"expiry_date"`
+	Features     []string                  `json:"features"`
+	HardwareIDs  licverify.HardwareBinding `json:"hardware_ids"`
+}
+
+// TestGenerateLicenseFieldTooLarge tests that oversized fields are rejected before signing
+func TestGenerateLicenseFieldTooLarge(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	_, err = licgen.GenerateLicense(
+		"TEST-LICENSE-001",
+		strings.Repeat("C", licformat.MaxStringLength+1),
+		"PRODUCT-001",
+		"SERIAL-001",
+		24*time.Hour,
+		nil,
+		licverify.HardwareBinding{},
+		privateKey,
+	)
+	if !errors.Is(err, licformat.ErrFieldTooLarge) {
+		t.Errorf("Expected ErrFieldTooLarge, got %v", err)
+	}
+}
+
+// TestRenewLicense tests renewing a license with lineage
+func TestRenewLicense(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+
+	hwBinding := licverify.HardwareBinding{
+		MACAddresses: []string{"00:11:22:33:44:55"},
+		HostNames:    []string{"test-hostname"},
+	}
+	original, err := licgen.GenerateLicense("LIC-001", "CUSTOMER-001", "PRODUCT-001", "SERIAL-001",
+		30*24*time.Hour, []string{"basic", "premium"}, hwBinding, privateKey,
+		licgen.WithSignatureScheme(licformat.SchemePSS))
+	if err != nil {
+		t.Fatalf("Failed to generate license: %v", err)
+	}
+	previous, err := verifier.ParseLicense(original)
+	if err != nil {
+		t.Fatalf("Failed to parse license: %v", err)
+	}
+
+	renewedData, err := licgen.Renew(original, "LIC-002", 365*24*time.Hour, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to renew license: %v", err)
+	}
+	renewed, err := verifier.ParseLicense(renewedData)
+	if err != nil {
+		t.Fatalf("Failed to parse renewed license: %v", err)
+	}
+	if err := verifier.VerifySignature(renewed); err != nil {
+		t.Fatalf("Renewed license signature is invalid: %v", err)
+	}
+
+	// Identity, features, binding and scheme are preserved
+	if renewed.ID() != "LIC-002" || renewed.PreviousID() != "LIC-001" {
+		t.Errorf("Expected LIC-002 renewing LIC-001, got %s renewing %q", renewed.ID(), renewed.PreviousID())
+	}
+	if renewed.CustomerID() != previous.CustomerID() || renewed.ProductID() != previous.ProductID() ||
+		renewed.SerialNumber() != previous.SerialNumber() {
+		t.Error("Renewed license changed the license identity")
+	}
+	if !reflect.DeepEqual(renewed.Features(), previous.Features()) {
+		t.Errorf("Features changed: %v", renewed.Features())
+	}
+	if !reflect.DeepEqual(renewed.HardwareIDs(), previous.HardwareIDs()) {
+		t.Errorf("Hardware binding changed: %+v", renewed.HardwareIDs())
+	}
+	if renewed.SignatureScheme() != licformat.SchemePSS {
+		t.Errorf("Expected the PSS scheme to be kept, got %v", renewed.SignatureScheme())
+	}
+
+	// The remaining validity of an active license is kept
+	if want := previous.ExpiryDate().Add(365 * 24 * time.Hour); !renewed.ExpiryDate().Equal(want) {
+		t.Errorf("Expected expiry %v, got %v", want, renewed.ExpiryDate())
+	}
+
+	// An expired license is renewed from now
+	expired, err := licgen.GenerateLicense("LIC-003", "CUSTOMER-001", "PRODUCT-001", "SERIAL-002",
+		-24*time.Hour, nil, licverify.HardwareBinding{}, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to generate license: %v", err)
+	}
+	renewedData, err = licgen.Renew(expired, "", 10*24*time.Hour, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to renew expired license: %v", err)
+	}
+	renewed, err = verifier.ParseLicense(renewedData)
+	if err != nil {
+		t.Fatalf("Failed to parse renewed license: %v", err)
+	}
+	if renewed.ID() == "" || renewed.ID() == "LIC-003" {
+		t.Errorf("Expected a generated ID, got %q", renewed.ID())
+	}
+	if remaining := time.Until(renewed.ExpiryDate()); remaining < 9*24*time.Hour || remaining > 10*24*time.Hour {
+		t.Errorf("Expected about 10 days validity, got %v", remaining)
+	}
+
+	// Renewing requires a new ID and a license signed with the same key
+	if _, err := licgen.Renew(original, "LIC-001", time.Hour, privateKey); err == nil {
+		t.Error("Expected renewal with the same ID to fail")
+	}
+	otherKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	otherKey, err := licgen.ParsePrivateKey(otherKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	if _, err := licgen.Renew(original, "LIC-004", time.Hour, otherKey); err == nil {
+		t.Error("Expected renewal with a different key to fail")
+	}
+}
+
+// TestRenewEncryptedLicense tests renewing encrypted and machine-bound
+// licenses, which the vendor cannot read with the public key alone
+func TestRenewEncryptedLicense(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	encryptionPrivatePEM, _, err := licgen.GenerateEncryptionKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to generate encryption key pair: %v", err)
+	}
+	decryptionKey, err := licverify.ParseDecryptionKey(encryptionPrivatePEM)
+	if err != nil {
+		t.Fatalf("Failed to parse decryption key: %v", err)
+	}
+	machine := &licverify.HardwareInfo{MachineID: "customer-machine-id"}
+
+	for _, tc := range []struct {
+		name     string
+		encrypt  licgen.Option
+		reissue  []licgen.Option
+		verifier *licverify.Verifier
+	}{
+		{
+			"Encrypted",
+			licgen.WithEncryption(decryptionKey.PublicKey()),
+			[]licgen.Option{licgen.WithDecryptionKey(decryptionKey), licgen.WithEncryption(decryptionKey.PublicKey())},
+			licverify.NewVerifierFromPublicKey(&privateKey.PublicKey, licverify.WithDecryptionKey(decryptionKey)),
+		},
+		{
+			"MachineBound",
+			licgen.WithMachineBinding(machine),
+			[]licgen.Option{licgen.WithMachineBinding(machine)},
+			licverify.NewVerifierFromPublicKey(&privateKey.PublicKey, licverify.WithHardwareInfo(machine)),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			original, err := licgen.GenerateLicense("LIC-001", "CUSTOMER-001", "PRODUCT-001", "SERIAL-001",
+				30*24*time.Hour, []string{"basic"}, licverify.HardwareBinding{}, privateKey, tc.encrypt)
+			if err != nil {
+				t.Fatalf("Failed to generate license: %v", err)
+			}
+
+			// The public key alone cannot decode the previous license
+			if _, err := licgen.Renew(original, "LIC-002", 365*24*time.Hour, privateKey); err == nil {
+				t.Error("Expected renewal without the decryption key or hardware info to fail")
+			}
+
+			renewedData, err := licgen.Renew(original, "LIC-002", 365*24*time.Hour, privateKey, tc.reissue...)
+			if err != nil {
+				t.Fatalf("Failed to renew license: %v", err)
+			}
+			renewed, err := tc.verifier.ParseLicense(renewedData)
+			if err != nil {
+				t.Fatalf("Failed to parse renewed license: %v", err)
+			}
+			if !renewed.IsEncrypted() || renewed.PreviousID() != "LIC-001" || renewed.CustomerID() != "CUSTOMER-001" {
+				t.Errorf("Unexpected renewed license: encrypted=%v previous=%q customer=%s",
+					renewed.IsEncrypted(), renewed.PreviousID(), renewed.CustomerID())
+			}
+
+			modifiedData, err := licgen.Modify(original, "LIC-003", licgen.Changes{AddFeatures: []string{"pro"}}, privateKey, tc.reissue...)
+			if err != nil {
+				t.Fatalf("Failed to modify license: %v", err)
+			}
+			modified, err := tc.verifier.ParseLicense(modifiedData)
+			if err != nil {
+				t.Fatalf("Failed to parse modified license: %v", err)
+			}
+			if !reflect.DeepEqual(modified.Features(), []string{"basic", "pro"}) {
+				t.Errorf("Unexpected features: %v", modified.Features())
+			}
+		})
+	}
+}
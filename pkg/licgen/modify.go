@@ -0,0 +1,86 @@
+package licgen
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Changes describes a modification of an existing license. Added entries
+// that are already present are ignored, removed entries must be present.
+type Changes struct {
+	AddFeatures    []string
+	RemoveFeatures []string
+
+	AddMACAddresses    []string
+	RemoveMACAddresses []string
+	AddDiskIDs         []string
+	RemoveDiskIDs      []string
+	AddHostNames       []string
+	RemoveHostNames    []string
+	AddContainerIDs    []string
+	RemoveContainerIDs []string
+
+	// ExpiryDate replaces the expiry date unless it is zero
+	ExpiryDate time.Time
+}
+
+// Modify issues a new license derived from an existing one with the given
+// changes applied, for example to upgrade or downgrade its features. The
+// previous license is verified against the public half of privateKey. The
+// new license keeps all other fields, including the expiry date, and records
+// the previous license ID. A UUIDv7 ID is generated if newID is empty.
+// The signature scheme of the previous license is kept unless
+// WithSignatureScheme is passed; encryption options must be passed again.
+// Encrypted and machine-bound previous licenses are decoded as by Renew.
+func Modify(previousData []byte, newID string, changes Changes, privateKey *rsa.PrivateKey, opts ...Option) ([]byte, error) {
+	previous, err := parseIssuedLicense(previousData, privateKey, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	modified, err := deriveLicense(previous, newID)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	for _, c := range []struct {
+		name        string
+		list        *[]string
+		add, remove []string
+	}{
+		{"feature", &modified.Features, changes.AddFeatures, changes.RemoveFeatures},
+		{"MAC address", &modified.HardwareIDs.MACAddresses, changes.AddMACAddresses, changes.RemoveMACAddresses},
+		{"disk ID", &modified.HardwareIDs.DiskIDs, changes.AddDiskIDs, changes.RemoveDiskIDs},
+		{"host name", &modified.HardwareIDs.HostNames, changes.AddHostNames, changes.RemoveHostNames},
+		{"container ID", &modified.HardwareIDs.ContainerIDs, changes.AddContainerIDs, changes.RemoveContainerIDs},
+	} {
+		for _, item := range c.remove {
+			i := slices.Index(*c.list, item)
+			if i < 0 {
+				return nil, fmt.Errorf("%s %q is not in the license", c.name, item)
+			}
+			*c.list = slices.Delete(*c.list, i, i+1)
+			changed = true
+		}
+		for _, item := range c.add {
+			if !slices.Contains(*c.list, item) {
+				*c.list = append(*c.list, item)
+				changed = true
+			}
+		}
+	}
+
+	if !changes.ExpiryDate.IsZero() && !changes.ExpiryDate.Equal(modified.ExpiryDate) {
+		modified.ExpiryDate = changes.ExpiryDate
+		changed = true
+	}
+	if !changed {
+		return nil, errors.New("changes do not modify the license")
+	}
+
+	return signLicense(&modified, privateKey, newOptions(append([]Option{WithSignatureScheme(previous.SignatureScheme())}, opts...)))
+}
@@ -0,0 +1,90 @@
+package licgen_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// TestModifyLicense tests deriving a license with changed features and binding
+func TestModifyLicense(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	original, err := licgen.GenerateLicense("LIC-001", "CUSTOMER-001", "PRODUCT-001", "SERIAL-001",
+		30*24*time.Hour, []string{"basic", "reports"},
+		licverify.HardwareBinding{MACAddresses: []string{"00:11:22:33:44:55"}, HostNames: []string{"host1"}},
+		privateKey)
+	if err != nil {
+		t.Fatalf("Failed to generate license: %v", err)
+	}
+
+	modifiedData, err := licgen.Modify(original, "LIC-002", licgen.Changes{
+		AddFeatures:        []string{"premium", "basic"},
+		RemoveFeatures:     []string{"reports"},
+		AddMACAddresses:    []string{"66:77:88:99:aa:bb"},
+		RemoveMACAddresses: []string{"00:11:22:33:44:55"},
+	}, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to modify license: %v", err)
+	}
+
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+	modified, err := verifier.ParseLicense(modifiedData)
+	if err != nil {
+		t.Fatalf("Failed to parse modified license: %v", err)
+	}
+	if err := verifier.VerifySignature(modified); err != nil {
+		t.Fatalf("Modified license signature is invalid: %v", err)
+	}
+
+	// Compare field by field with licformat.Diff
+	before, err := licformat.DecodeLicenseFile(original)
+	if err != nil {
+		t.Fatalf("Failed to decode license: %v", err)
+	}
+	after, err := licformat.DecodeLicenseFile(modifiedData)
+	if err != nil {
+		t.Fatalf("Failed to decode license: %v", err)
+	}
+	changed := make(map[string]licformat.FieldDiff)
+	for _, diff := range licformat.Diff(before, after) {
+		changed[diff.Field] = diff
+	}
+	for _, field := range []string{"ID", "Previous ID", "Features", "MAC Addresses"} {
+		if _, ok := changed[field]; !ok {
+			t.Errorf("Expected %s to change", field)
+		}
+	}
+	for _, field := range []string{"Customer ID", "Serial Number", "Expiry Date", "Host Names"} {
+		if _, ok := changed[field]; ok {
+			t.Errorf("Expected %s to be unchanged", field)
+		}
+	}
+	if got := changed["Features"]; !reflect.DeepEqual(got.Added, []string{"premium"}) || !reflect.DeepEqual(got.Removed, []string{"reports"}) {
+		t.Errorf("Unexpected feature changes: %+v", got)
+	}
+	if modified.PreviousID() != "LIC-001" {
+		t.Errorf("Expected previous ID LIC-001, got %q", modified.PreviousID())
+	}
+
+	// Invalid modifications
+	for name, changes := range map[string]licgen.Changes{
+		"missing feature": {RemoveFeatures: []string{"enterprise"}},
+		"no changes":      {AddFeatures: []string{"basic"}},
+	} {
+		if _, err := licgen.Modify(original, "LIC-003", changes, privateKey); err == nil {
+			t.Errorf("Expected %s to fail", name)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package licgen
+
+import (
+	"crypto/ecdh"
+	"crypto/rsa"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// Option configures optional behaviour of license generation
+type Option func(*options)
+
+// options holds the settings applied by Option values
+type options struct {
+	scheme     licformat.SignatureScheme
+	recipient  *ecdh.PublicKey
+	decryption *ecdh.PrivateKey
+	machine    *licverify.HardwareInfo
+	bound      bool
+	recorder   Recorder
+	seats      uint32
+	seatServer *rsa.PublicKey
+	quotas     map[string]uint64
+}
+
+// newOptions applies opts on top of the defaults
+func newOptions(opts []Option) *options {
+	o := &options{
+		scheme: licformat.SchemePKCS1v15,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithSignatureScheme selects the signature scheme for the license.
+// The default is licformat.SchemePKCS1v15.
+func WithSignatureScheme(scheme licformat.SignatureScheme) Option {
+	return func(o *options) {
+		o.scheme = scheme
+	}
+}
+
+// WithEncryption encrypts the license contents to the recipient's X25519
+// public key before signing. Only holders of the matching private key can
+// read the license, see licverify.WithDecryptionKey.
+func WithEncryption(recipient *ecdh.PublicKey) Option {
+	return func(o *options) {
+		o.recipient = recipient
+	}
+}
+
+// WithMachineBinding encrypts the license contents with a key derived from
+// the fingerprint of the activation hardware, so that the license can only
+// be decoded on that machine. hwInfo must be the complete hardware info
+// reported by licverify.GetHardwareInfo on the target machine; generating
+// a license with a nil hwInfo fails. Renew and Modify also use hwInfo to
+// decode a machine-bound previous license.
+func WithMachineBinding(hwInfo *licverify.HardwareInfo) Option {
+	return func(o *options) {
+		o.machine = hwInfo
+		o.bound = true
+	}
+}
+
+// WithDecryptionKey sets the X25519 private key that Renew and Modify use
+// to decode a previous license generated with WithEncryption. It does not
+// encrypt the new license; pass WithEncryption for that.
+func WithDecryptionKey(key *ecdh.PrivateKey) Option {
+	return func(o *options) {
+		o.decryption = key
+	}
+}
+
+// WithSeats makes the license a floating license for the given number of
+// concurrent users, served to clients by a licfloat seat server. Floating
+// licenses must also bind the seat server key with WithSeatServer.
+func WithSeats(seats uint32) Option {
+	return func(o *options) {
+		o.seats = seats
+	}
+}
+
+// WithSeatServer binds a floating license to the public key of the seat
+// server that signs its seat leases. Seat servers with another key cannot
+// serve the license and seat clients refuse it.
+func WithSeatServer(serverKey *rsa.PublicKey) Option {
+	return func(o *options) {
+		o.seatServer = serverKey
+	}
+}
+
+// WithQuota limits the usage of a metered feature to limit units, as
+// enforced by licverify.UsageMeter. It can be passed once per feature.
+func WithQuota(feature string, limit uint64) Option {
+	return func(o *options) {
+		if o.quotas == nil {
+			o.quotas = make(map[string]uint64)
+		}
+		o.quotas[feature] = limit
+	}
+}
+
+// Recorder is notified of every license that is generated, for example to
+// keep an issuance ledger. license holds the plaintext fields and data the
+// signed license file, which may be encrypted.
+type Recorder interface {
+	RecordIssued(license *licformat.License, data []byte) error
+}
+
+// WithRecorder records the license with r after it has been signed.
+// Generation fails if the license cannot be recorded.
+func WithRecorder(r Recorder) Option {
+	return func(o *options) {
+		o.recorder = r
+	}
+}
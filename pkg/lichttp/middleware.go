@@ -0,0 +1,198 @@
+// Package lichttp gates net/http handlers by licensed features.
+//
+// A Gate verifies the license on every request and stores it in the
+// request context, where handlers read it with FromContext:
+//
+//	gate := lichttp.New(verifier, license)
+//	mux.Handle("/export", gate.RequireFeature("export")(exportHandler))
+//
+// Requests are refused with a JSON ErrorResponse: 402 Payment Required
+// when the license has expired or does not grant the feature, and
+// 403 Forbidden when there is no license or it does not verify. Other
+// frameworks, such as RPC interceptors, can call Gate.Check directly.
+// Trial licenses are refused unless the gate is created with
+// WithTrialTracker, which enforces the trial period.
+// Use a verifier created with licverify.WithCache, so that requests do not
+// collect the hardware information each time.
+package lichttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// Error codes of refused requests
+const (
+	// CodeNoLicense means no license is loaded
+	CodeNoLicense = "no_license"
+	// CodeInvalidLicense means the license signature or hardware binding does not verify
+	CodeInvalidLicense = "invalid_license"
+	// CodeLicenseExpired means the license has expired
+	CodeLicenseExpired = "license_expired"
+	// CodeFeatureNotLicensed means the license does not grant the feature
+	CodeFeatureNotLicensed = "feature_not_licensed"
+)
+
+// Error describes why a license check failed
+type Error struct {
+	Status  int    // HTTP status of the response
+	Code    string // One of the Code constants
+	Feature string // The required feature, if any
+	Err     error  // The verification error, if any
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	switch {
+	case e.Err != nil:
+		return e.Err.Error()
+	case e.Code == CodeFeatureNotLicensed:
+		return "license does not grant feature " + e.Feature
+	default:
+		return "no license"
+	}
+}
+
+// Unwrap returns the verification error
+func (e *Error) Unwrap() error { return e.Err }
+
+// ErrorResponse is the body of refused requests
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Code    string `json:"code"`
+	Feature string `json:"feature,omitempty"`
+}
+
+// Gate checks requests against a license, see New
+type Gate struct {
+	verifier *licverify.Verifier
+	current  func() *licverify.License
+	trials   *licverify.TrialTracker
+}
+
+// Option configures optional behaviour of a gate
+type Option func(*Gate)
+
+// WithTrialTracker accepts trial licenses while their trial period, as
+// recorded by tracker, has not ended
+func WithTrialTracker(tracker *licverify.TrialTracker) Option {
+	return func(g *Gate) {
+		g.trials = tracker
+	}
+}
+
+// New creates a gate for a loaded license
+func New(verifier *licverify.Verifier, license *licverify.License, opts ...Option) *Gate {
+	g := &Gate{
+		verifier: verifier,
+		current:  func() *licverify.License { return license },
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// NewFromWatcher creates a gate for the current license of a watcher, so
+// that replaced licenses take effect without restarting the service
+func NewFromWatcher(verifier *licverify.Verifier, watcher *licverify.Watcher, opts ...Option) *Gate {
+	g := &Gate{
+		verifier: verifier,
+		current:  watcher.Current,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Check verifies the license and, if feature is not empty, that it grants
+// the feature. It returns the license, or an *Error.
+func (g *Gate) Check(feature string) (*licverify.License, error) {
+	license := g.current()
+	if license == nil {
+		return nil, &Error{Status: http.StatusForbidden, Code: CodeNoLicense, Feature: feature}
+	}
+
+	if license.IsTrial() {
+		if err := g.checkTrial(license); err != nil {
+			err.Feature = feature
+			return nil, err
+		}
+	} else {
+		if err := g.verifier.VerifySignature(license); err != nil {
+			return nil, &Error{Status: http.StatusForbidden, Code: CodeInvalidLicense, Feature: feature, Err: err}
+		}
+		if err := g.verifier.VerifyHardwareBinding(license); err != nil {
+			return nil, &Error{Status: http.StatusForbidden, Code: CodeInvalidLicense, Feature: feature, Err: err}
+		}
+		if err := g.verifier.VerifyExpiry(license); err != nil {
+			return nil, &Error{Status: http.StatusPaymentRequired, Code: CodeLicenseExpired, Feature: feature, Err: err}
+		}
+	}
+	if feature != "" && !license.HasFeature(feature) {
+		return nil, &Error{Status: http.StatusPaymentRequired, Code: CodeFeatureNotLicensed, Feature: feature}
+	}
+	return license, nil
+}
+
+// checkTrial checks a trial license with the trial tracker. Without a
+// tracker trial licenses are refused.
+func (g *Gate) checkTrial(license *licverify.License) *Error {
+	if g.trials == nil {
+		return &Error{Status: http.StatusForbidden, Code: CodeInvalidLicense, Err: licverify.ErrTrialNotTracked}
+	}
+	if _, err := g.trials.Check(license); err != nil {
+		if errors.Is(err, licverify.ErrTrialExpired) {
+			return &Error{Status: http.StatusPaymentRequired, Code: CodeLicenseExpired, Err: err}
+		}
+		return &Error{Status: http.StatusForbidden, Code: CodeInvalidLicense, Err: err}
+	}
+	return nil
+}
+
+// RequireLicense returns middleware that refuses requests unless the
+// license verifies
+func (g *Gate) RequireLicense() func(http.Handler) http.Handler {
+	return g.RequireFeature("")
+}
+
+// RequireFeature returns middleware that refuses requests unless the
+// license verifies and grants feature
+func (g *Gate) RequireFeature(feature string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			license, err := g.Check(feature)
+			if err != nil {
+				writeError(w, err.(*Error))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), license)))
+		})
+	}
+}
+
+// contextKey is the context key of the license
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the license
+func NewContext(ctx context.Context, license *licverify.License) context.Context {
+	return context.WithValue(ctx, contextKey{}, license)
+}
+
+// FromContext returns the license stored by the gate middleware
+func FromContext(ctx context.Context) (*licverify.License, bool) {
+	license, ok := ctx.Value(contextKey{}).(*licverify.License)
+	return license, ok
+}
+
+// writeError writes a refused request response
+func writeError(w http.ResponseWriter, e *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: e.Error(), Code: e.Code, Feature: e.Feature})
+}
@@ -0,0 +1,141 @@
+package lichttp_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/lichttp"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+func TestRequireFeature(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+
+	newLicense := func(validity time.Duration, binding licverify.HardwareBinding) *licverify.License {
+		t.Helper()
+		data, err := licgen.GenerateLicense("LIC-1", "ACME", "APP", "SN-1", validity,
+			[]string{"basic", "export"}, binding, privateKey)
+		if err != nil {
+			t.Fatalf("Failed to generate license: %v", err)
+		}
+		license, err := verifier.ParseLicense(data)
+		if err != nil {
+			t.Fatalf("Failed to parse license: %v", err)
+		}
+		return license
+	}
+
+	// handler reports the license found in the request context
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		license, ok := lichttp.FromContext(r.Context())
+		if !ok {
+			t.Error("No license in request context")
+			return
+		}
+		w.Write([]byte(license.CustomerID()))
+	})
+
+	valid := newLicense(24*time.Hour, licverify.HardwareBinding{})
+	tests := []struct {
+		name    string
+		license *licverify.License
+		feature string
+		status  int
+		code    string
+	}{
+		{"Granted", valid, "export", http.StatusOK, ""},
+		{"AnyLicense", valid, "", http.StatusOK, ""},
+		{"NotLicensed", valid, "audit", http.StatusPaymentRequired, lichttp.CodeFeatureNotLicensed},
+		{"Expired", newLicense(-time.Hour, licverify.HardwareBinding{}), "export", http.StatusPaymentRequired, lichttp.CodeLicenseExpired},
+		{"OtherMachine", newLicense(24*time.Hour, licverify.HardwareBinding{HostNames: []string{"not-this-host.invalid"}}), "export", http.StatusForbidden, lichttp.CodeInvalidLicense},
+		{"NoLicense", nil, "export", http.StatusForbidden, lichttp.CodeNoLicense},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate := lichttp.New(verifier, tt.license)
+			rec := httptest.NewRecorder()
+			gate.RequireFeature(tt.feature)(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+			if tt.status == http.StatusOK {
+				if rec.Body.String() != "ACME" {
+					t.Errorf("Unexpected body %q", rec.Body)
+				}
+				return
+			}
+
+			var resp lichttp.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Invalid error response %q: %v", rec.Body, err)
+			}
+			if resp.Code != tt.code || resp.Feature != tt.feature || resp.Error == "" {
+				t.Errorf("Unexpected error response: %+v", resp)
+			}
+			if rec.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Unexpected content type %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	// Check serves other frameworks
+	var gateErr *lichttp.Error
+	if _, err := lichttp.New(verifier, valid).Check("audit"); !errors.As(err, &gateErr) || gateErr.Status != http.StatusPaymentRequired {
+		t.Errorf("Expected a 402 gate error, got %v", err)
+	}
+}
+
+func TestGateTrialLicense(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+
+	data, err := licgen.GenerateTrialLicense("TRIAL-1", "APP", 24*time.Hour, 365*24*time.Hour, []string{"export"}, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to generate trial license: %v", err)
+	}
+	trial, err := verifier.ParseLicense(data)
+	if err != nil {
+		t.Fatalf("Failed to parse trial license: %v", err)
+	}
+
+	// Without a tracker a trial is not a full license
+	var gateErr *lichttp.Error
+	_, err = lichttp.New(verifier, trial).Check("export")
+	if !errors.As(err, &gateErr) || gateErr.Status != http.StatusForbidden || !errors.Is(err, licverify.ErrTrialNotTracked) {
+		t.Errorf("Expected a 403 ErrTrialNotTracked gate error, got %v", err)
+	}
+
+	tracker, err := licverify.NewTrialTracker(verifier, []byte("test application"), filepath.Join(t.TempDir(), "trial.json"))
+	if err != nil {
+		t.Fatalf("Failed to create trial tracker: %v", err)
+	}
+	gate := lichttp.New(verifier, trial, lichttp.WithTrialTracker(tracker))
+	if _, err := gate.Check("export"); err != nil {
+		t.Errorf("Expected the tracked trial to be accepted, got %v", err)
+	}
+	if _, err := gate.Check("audit"); !errors.As(err, &gateErr) || gateErr.Code != lichttp.CodeFeatureNotLicensed {
+		t.Errorf("Expected a feature error, got %v", err)
+	}
+}
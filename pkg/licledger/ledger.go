@@ -0,0 +1,438 @@
+// Package licledger keeps an append-only record of issued licenses.
+//
+// The ledger is a JSON Lines file: every line is one record and existing
+// lines are never rewritten. It implements licgen.Recorder, so passing
+// licgen.WithRecorder(ledger) records every generated license.
+package licledger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// Errors returned by ledger lookups
+var (
+	// ErrNotFound is returned when no license with the requested ID was issued
+	ErrNotFound = errors.New("license not found in ledger")
+	// ErrRevoked is returned when revoking a license that is already revoked
+	ErrRevoked = errors.New("license is revoked")
+)
+
+// maxRecordSize bounds the length of a single ledger line
+const maxRecordSize = 16 << 20
+
+// Event identifies the kind of a ledger record
+type Event string
+
+const (
+	// EventIssued records a newly generated license
+	EventIssued Event = "issued"
+	// EventRevoked records the revocation of all licenses issued with an ID
+	EventRevoked Event = "revoked"
+)
+
+// Entry describes an issued license
+type Entry struct {
+	ID            string                    `json:"id"`
+	CustomerID    string                    `json:"customer_id"`
+	ProductID     string                    `json:"product_id"`
+	SerialNumber  string                    `json:"serial_number"`
+	IssueDate     time.Time                 `json:"issue_date"`
+	ExpiryDate    time.Time                 `json:"expiry_date"`
+	Features      []string                  `json:"features,omitempty"`
+	HardwareIDs   licverify.HardwareBinding `json:"hardware_ids"`
+	PreviousID    string                    `json:"previous_id,omitempty"`
+	Seats         uint32                    `json:"seats,omitempty"`
+	SeatServerKey []byte                    `json:"seat_server_key,omitempty"` // See licverify.SeatServerKeyID
+	TrialDuration time.Duration             `json:"trial_duration,omitempty"`  // Nanoseconds
+	Quotas        map[string]uint64         `json:"quotas,omitempty"`
+	Scheme        string                    `json:"scheme"`
+	Encrypted     bool                      `json:"encrypted,omitempty"`
+	Data          []byte                    `json:"data,omitempty"` // The signed license file
+
+	// Revocation is set when the license was revoked after it was issued.
+	// It is derived from later records and never stored with the entry.
+	Revocation *Revocation `json:"revocation,omitempty"`
+}
+
+// Revocation describes when and why a license was revoked
+type Revocation struct {
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Revoked reports whether the license was revoked
+func (e *Entry) Revoked() bool {
+	return e.Revocation != nil
+}
+
+// Record is one line of the ledger. Issued records hold the license,
+// revoked records the license ID and the reason.
+type Record struct {
+	Event   Event     `json:"event"`
+	Time    time.Time `json:"time"`
+	License *Entry    `json:"license,omitempty"`
+	ID      string    `json:"id,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+}
+
+// Ledger is an append-only license issuance ledger stored in a file.
+// It is safe for concurrent use.
+type Ledger struct {
+	path string
+	mu   sync.Mutex
+
+	// index holds the latest entry per license ID for Get. It is rebuilt
+	// when the file no longer has the size and modification time it was
+	// built from, which includes appends by other processes.
+	index     map[string]*Entry
+	indexSize int64
+	indexTime time.Time
+}
+
+// Open returns the ledger stored at path. The file is created on the first write.
+func Open(path string) *Ledger {
+	return &Ledger{path: path}
+}
+
+// Path returns the path of the ledger file
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Append adds a record to the end of the ledger
+func (l *Ledger) Append(record Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.appendLocked(record)
+}
+
+// appendLocked appends a record while l.mu is held
+func (l *Ledger) appendLocked(record Record) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode ledger record: %v", err)
+	}
+	line = append(line, '\n')
+	if len(line) > maxRecordSize {
+		return fmt.Errorf("ledger record is %d bytes, maximum is %d", len(line), maxRecordSize)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open ledger: %v", err)
+	}
+	if err := repairTail(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to repair ledger: %v", err)
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write ledger: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write ledger: %v", err)
+	}
+	return file.Close()
+}
+
+// repairTail ends the last line of the ledger if an earlier write was
+// interrupted, so that the next record starts on a line of its own. A
+// complete record that only lacks its newline is terminated, a partial
+// record is truncated, matching what readRecords ignores.
+func repairTail(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if size == 0 {
+		return nil
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, size-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+
+	// Find the start of the unterminated line
+	start := size
+	buf := make([]byte, 4096)
+	for start > 0 {
+		n := min(int64(len(buf)), start)
+		if _, err := file.ReadAt(buf[:n], start-n); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			start = start - n + int64(i) + 1
+			break
+		}
+		start -= n
+	}
+
+	if size-start <= maxRecordSize {
+		tail := make([]byte, size-start)
+		if _, err := file.ReadAt(tail, start); err != nil {
+			return err
+		}
+		var record Record
+		if len(bytes.TrimSpace(tail)) > 0 && json.Unmarshal(tail, &record) == nil {
+			_, err := file.Write([]byte{'\n'})
+			return err
+		}
+	}
+	return file.Truncate(start)
+}
+
+// RecordIssued appends an issued record for the license. It implements licgen.Recorder.
+func (l *Ledger) RecordIssued(license *licformat.License, data []byte) error {
+	return l.Append(Record{
+		Event:   EventIssued,
+		License: NewEntry(license, data),
+	})
+}
+
+// NewEntry creates a ledger entry for a license and its signed file contents
+func NewEntry(license *licformat.License, data []byte) *Entry {
+	return &Entry{
+		ID:           license.ID,
+		CustomerID:   license.CustomerID,
+		ProductID:    license.ProductID,
+		SerialNumber: license.SerialNumber,
+		IssueDate:    time.Unix(license.IssueDate.Unix(), 0).UTC(), // The precision of the binary format
+		ExpiryDate:   time.Unix(license.ExpiryDate.Unix(), 0).UTC(),
+		Features:     license.Features,
+		HardwareIDs: licverify.HardwareBinding{
+			MACAddresses: license.HardwareIDs.MACAddresses,
+			DiskIDs:      license.HardwareIDs.DiskIDs,
+			HostNames:    license.HardwareIDs.HostNames,
+			CustomIDs:    license.HardwareIDs.CustomIDs,
+			ContainerIDs: license.HardwareIDs.ContainerIDs,
+		},
+		PreviousID:    license.PreviousID,
+		Seats:         license.Seats,
+		SeatServerKey: license.SeatServerKey,
+		TrialDuration: license.TrialDuration,
+		Quotas:        license.Quotas,
+		Scheme:        license.Scheme.String(),
+		Encrypted:     licformat.IsEncrypted(data),
+		Data:          bytes.Clone(data),
+	}
+}
+
+// Records returns all records in the order they were appended.
+// A missing ledger file has no records.
+func (l *Ledger) Records() ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.recordsLocked()
+}
+
+// recordsLocked reads all records while l.mu is held
+func (l *Ledger) recordsLocked() ([]Record, error) {
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger: %v", err)
+	}
+	defer file.Close()
+
+	return readRecords(file)
+}
+
+// readRecords parses JSON Lines records. A final line without a newline
+// that cannot be parsed is an interrupted write and is ignored.
+func readRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	reader := bufio.NewReader(r)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read ledger: %v", err)
+		}
+		complete := err == nil
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record Record
+			if jsonErr := json.Unmarshal(line, &record); jsonErr != nil {
+				if !complete {
+					break
+				}
+				return nil, fmt.Errorf("invalid ledger record on line %d: %v", lineNumber, jsonErr)
+			}
+			records = append(records, record)
+		}
+
+		if !complete {
+			break
+		}
+	}
+	return records, nil
+}
+
+// Revoke records the revocation of the licenses issued with the given ID.
+// The check that the license exists and is not revoked yet and the append
+// are atomic for this Ledger.
+func (l *Ledger) Revoke(id, reason string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records, err := l.recordsLocked()
+	if err != nil {
+		return err
+	}
+	entry, err := latestEntry(buildEntries(records), id)
+	if err != nil {
+		return err
+	}
+	if entry.Revoked() {
+		return fmt.Errorf("%w: %s", ErrRevoked, id)
+	}
+	return l.appendLocked(Record{Event: EventRevoked, ID: id, Reason: reason})
+}
+
+// Entries returns the issued licenses in issuance order, with the
+// revocation of revoked licenses set
+func (l *Ledger) Entries() ([]*Entry, error) {
+	records, err := l.Records()
+	if err != nil {
+		return nil, err
+	}
+	return buildEntries(records), nil
+}
+
+// buildEntries returns the issued licenses of the records in issuance
+// order, with the revocation of revoked licenses set
+func buildEntries(records []Record) []*Entry {
+	var entries []*Entry
+	for _, record := range records {
+		switch record.Event {
+		case EventIssued:
+			if record.License != nil {
+				record.License.Revocation = nil
+				entries = append(entries, record.License)
+			}
+		case EventRevoked:
+			for _, entry := range entries {
+				if entry.ID == record.ID && entry.Revocation == nil {
+					entry.Revocation = &Revocation{Time: record.Time, Reason: record.Reason}
+				}
+			}
+		}
+	}
+	return entries
+}
+
+// Get returns the most recently issued license with the given ID. Lookups
+// are served from an in-memory index that is only rebuilt after the ledger
+// file has changed.
+func (l *Ledger) Get(id string) (*Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info, err := os.Stat(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		l.index = nil
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger: %v", err)
+	}
+	if l.index == nil || info.Size() != l.indexSize || !info.ModTime().Equal(l.indexTime) {
+		records, err := l.recordsLocked()
+		if err != nil {
+			return nil, err
+		}
+		l.index = make(map[string]*Entry)
+		for _, entry := range buildEntries(records) {
+			l.index[entry.ID] = entry
+		}
+		l.indexSize = info.Size()
+		l.indexTime = info.ModTime()
+	}
+
+	entry, ok := l.index[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	// Callers may modify the entry, the index keeps its own
+	copied := *entry
+	if entry.Revocation != nil {
+		revocation := *entry.Revocation
+		copied.Revocation = &revocation
+	}
+	return &copied, nil
+}
+
+// latestEntry returns the most recently issued entry with the given ID
+func latestEntry(entries []*Entry, id string) (*Entry, error) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].ID == id {
+			return entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// Query selects ledger entries. Zero fields match everything.
+type Query struct {
+	CustomerID    string    // Case-insensitive exact match
+	ProductID     string    // Case-insensitive exact match
+	ExpiresAfter  time.Time // Only licenses expiring at or after this time
+	ExpiresBefore time.Time // Only licenses expiring before this time
+}
+
+// Matches reports whether the entry satisfies the query
+func (q Query) Matches(entry *Entry) bool {
+	if q.CustomerID != "" && !strings.EqualFold(entry.CustomerID, q.CustomerID) {
+		return false
+	}
+	if q.ProductID != "" && !strings.EqualFold(entry.ProductID, q.ProductID) {
+		return false
+	}
+	if !q.ExpiresAfter.IsZero() && entry.ExpiryDate.Before(q.ExpiresAfter) {
+		return false
+	}
+	if !q.ExpiresBefore.IsZero() && !entry.ExpiryDate.Before(q.ExpiresBefore) {
+		return false
+	}
+	return true
+}
+
+// Search returns the issued licenses matching the query in issuance order
+func (l *Ledger) Search(q Query) ([]*Entry, error) {
+	entries, err := l.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*Entry
+	for _, entry := range entries {
+		if q.Matches(entry) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
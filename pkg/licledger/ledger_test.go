@@ -0,0 +1,376 @@
+package licledger_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licledger"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// TestLedgerRecordsGeneratedLicenses tests recording through licgen.WithRecorder
+func TestLedgerRecordsGeneratedLicenses(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	ledger := licledger.Open(filepath.Join(t.TempDir(), "ledger.jsonl"))
+
+	licenses := []struct {
+		id, customer, product string
+		days                  int
+	}{
+		{"LIC-1", "ACME", "APP", 30},
+		{"LIC-2", "ACME", "TOOL", 365},
+		{"LIC-3", "Globex", "APP", 90},
+	}
+	var wg sync.WaitGroup
+	for _, l := range licenses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := licgen.GenerateLicense(l.id, l.customer, l.product, "SN-"+l.id,
+				time.Duration(l.days)*24*time.Hour, []string{"basic"},
+				licverify.HardwareBinding{HostNames: []string{"host1"}},
+				privateKey, licgen.WithRecorder(ledger))
+			if err != nil {
+				t.Errorf("Failed to generate license: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := ledger.Entries()
+	if err != nil {
+		t.Fatalf("Failed to read ledger: %v", err)
+	}
+	if len(entries) != len(licenses) {
+		t.Fatalf("Expected %d entries, got %d", len(licenses), len(entries))
+	}
+
+	// The recorded license file verifies
+	entry, err := ledger.Get("LIC-2")
+	if err != nil {
+		t.Fatalf("Failed to get license: %v", err)
+	}
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+	license, err := verifier.ParseLicense(entry.Data)
+	if err != nil {
+		t.Fatalf("Failed to parse recorded license: %v", err)
+	}
+	if err := verifier.VerifySignature(license); err != nil {
+		t.Errorf("Recorded license does not verify: %v", err)
+	}
+	if entry.CustomerID != "ACME" || entry.ProductID != "TOOL" || entry.Scheme != "pkcs1v15" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if len(entry.HardwareIDs.HostNames) != 1 || entry.HardwareIDs.HostNames[0] != "host1" {
+		t.Errorf("Hardware binding not recorded: %+v", entry.HardwareIDs)
+	}
+
+	if _, err := ledger.Get("LIC-404"); !errors.Is(err, licledger.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// Search by customer, product and expiry window
+	for _, tc := range []struct {
+		name  string
+		query licledger.Query
+		want  int
+	}{
+		{"all", licledger.Query{}, 3},
+		{"customer", licledger.Query{CustomerID: "acme"}, 2},
+		{"customer and product", licledger.Query{CustomerID: "ACME", ProductID: "APP"}, 1},
+		{"expiring within 100 days", licledger.Query{ExpiresBefore: time.Now().AddDate(0, 0, 100)}, 2},
+		{"expiring after 60 days", licledger.Query{ExpiresAfter: time.Now().AddDate(0, 0, 60)}, 2},
+		{"window", licledger.Query{ExpiresAfter: time.Now().AddDate(0, 0, 60), ExpiresBefore: time.Now().AddDate(0, 0, 100)}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			matches, err := ledger.Search(tc.query)
+			if err != nil {
+				t.Fatalf("Failed to search ledger: %v", err)
+			}
+			if len(matches) != tc.want {
+				t.Errorf("Expected %d matches, got %d", tc.want, len(matches))
+			}
+		})
+	}
+}
+
+// TestLedgerAppendOnly tests that records are appended and re-issues are tracked
+func TestLedgerAppendOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	ledger := licledger.Open(path)
+
+	// A missing ledger is empty
+	entries, err := ledger.Entries()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Expected empty ledger, got %d entries, %v", len(entries), err)
+	}
+
+	for _, serial := range []string{"SN-1", "SN-2"} {
+		err := ledger.Append(licledger.Record{
+			Event:   licledger.EventIssued,
+			License: &licledger.Entry{ID: "LIC-1", SerialNumber: serial, Data: []byte{1, 2, 3}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to append record: %v", err)
+		}
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read ledger file: %v", err)
+	}
+
+	// Get returns the latest issue of an ID
+	entry, err := ledger.Get("LIC-1")
+	if err != nil {
+		t.Fatalf("Failed to get license: %v", err)
+	}
+	if entry.SerialNumber != "SN-2" || !bytes.Equal(entry.Data, []byte{1, 2, 3}) {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	records, err := ledger.Records()
+	if err != nil || len(records) != 2 || records[0].Time.IsZero() {
+		t.Fatalf("Unexpected records: %+v, %v", records, err)
+	}
+
+	// Existing lines are never rewritten
+	if err := ledger.Append(licledger.Record{Event: licledger.EventIssued, License: &licledger.Entry{ID: "LIC-2"}}); err != nil {
+		t.Fatalf("Failed to append record: %v", err)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read ledger file: %v", err)
+	}
+	if !bytes.HasPrefix(after, before) {
+		t.Error("Append rewrote existing ledger records")
+	}
+}
+
+// TestLedgerCorruption tests handling of damaged ledger files
+func TestLedgerCorruption(t *testing.T) {
+	dir := t.TempDir()
+	valid := `{"event":"issued","time":"2025-01-01T00:00:00Z","license":{"id":"LIC-1"}}` + "\n"
+
+	// An interrupted final write is ignored
+	torn := filepath.Join(dir, "torn.jsonl")
+	if err := os.WriteFile(torn, []byte(valid+`{"event":"iss`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := licledger.Open(torn).Entries()
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected 1 entry from torn ledger, got %d, %v", len(entries), err)
+	}
+
+	// A damaged record in the middle is an error
+	damaged := filepath.Join(dir, "damaged.jsonl")
+	if err := os.WriteFile(damaged, []byte("not json\n"+valid), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := licledger.Open(damaged).Entries(); err == nil {
+		t.Error("Expected error for damaged ledger")
+	}
+}
+
+// TestLedgerAppendAfterTornWrite tests that appending repairs an
+// interrupted final write instead of extending it into an invalid line
+func TestLedgerAppendAfterTornWrite(t *testing.T) {
+	valid := `{"event":"issued","time":"2025-01-01T00:00:00Z","license":{"id":"LIC-1"}}`
+
+	for _, tc := range []struct {
+		name     string
+		contents string
+		ids      []string
+	}{
+		{"PartialRecord", valid + "\n" + `{"event":"iss`, []string{"LIC-1", "LIC-2"}},
+		{"MissingNewline", valid, []string{"LIC-1", "LIC-2"}},
+		{"OnlyPartialRecord", `{"event":"iss`, []string{"LIC-2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ledger.jsonl")
+			if err := os.WriteFile(path, []byte(tc.contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+			ledger := licledger.Open(path)
+
+			if err := ledger.Append(licledger.Record{Event: licledger.EventIssued, License: &licledger.Entry{ID: "LIC-2"}}); err != nil {
+				t.Fatalf("Failed to append record: %v", err)
+			}
+			if err := ledger.Revoke("LIC-2", "refunded"); err != nil {
+				t.Fatalf("Failed to revoke license: %v", err)
+			}
+
+			entries, err := ledger.Entries()
+			if err != nil {
+				t.Fatalf("Failed to read entries: %v", err)
+			}
+			var ids []string
+			for _, entry := range entries {
+				ids = append(ids, entry.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.ids) {
+				t.Errorf("Expected entries %v, got %v", tc.ids, ids)
+			}
+			if entry, err := ledger.Get("LIC-2"); err != nil || !entry.Revoked() {
+				t.Errorf("Expected LIC-2 to be revoked, got %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasSuffix(data, []byte("\n")) || bytes.Contains(data, []byte(`{"event":"iss{`)) {
+				t.Errorf("Unexpected ledger contents:\n%s", data)
+			}
+		})
+	}
+}
+
+// TestLedgerConcurrentRevoke tests that a license is revoked only once
+// when revocations race
+func TestLedgerConcurrentRevoke(t *testing.T) {
+	ledger := licledger.Open(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	if err := ledger.Append(licledger.Record{Event: licledger.EventIssued, License: &licledger.Entry{ID: "LIC-1"}}); err != nil {
+		t.Fatalf("Failed to append record: %v", err)
+	}
+
+	const revokers = 8
+	errs := make(chan error, revokers)
+	var wg sync.WaitGroup
+	for range revokers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- ledger.Revoke("LIC-1", "refunded")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	revoked := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			revoked++
+		case !errors.Is(err, licledger.ErrRevoked):
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if revoked != 1 {
+		t.Errorf("Expected exactly one revocation to succeed, got %d", revoked)
+	}
+
+	records, err := ledger.Records()
+	if err != nil {
+		t.Fatalf("Failed to read records: %v", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("Expected one issued and one revoked record, got %d records", len(records))
+	}
+}
+
+// TestLedgerRevoke tests revocation records
+func TestLedgerRevoke(t *testing.T) {
+	ledger := licledger.Open(filepath.Join(t.TempDir(), "ledger.jsonl"))
+
+	for _, id := range []string{"LIC-1", "LIC-2"} {
+		if err := ledger.Append(licledger.Record{Event: licledger.EventIssued, License: &licledger.Entry{ID: id}}); err != nil {
+			t.Fatalf("Failed to append record: %v", err)
+		}
+	}
+
+	if err := ledger.Revoke("LIC-1", "refunded"); err != nil {
+		t.Fatalf("Failed to revoke license: %v", err)
+	}
+	if err := ledger.Revoke("LIC-1", "again"); !errors.Is(err, licledger.ErrRevoked) {
+		t.Errorf("Expected ErrRevoked, got %v", err)
+	}
+	if err := ledger.Revoke("LIC-404", ""); !errors.Is(err, licledger.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	revoked, err := ledger.Get("LIC-1")
+	if err != nil {
+		t.Fatalf("Failed to get license: %v", err)
+	}
+	if !revoked.Revoked() || revoked.Revocation.Reason != "refunded" || revoked.Revocation.Time.IsZero() {
+		t.Errorf("Unexpected revocation: %+v", revoked.Revocation)
+	}
+
+	active, err := ledger.Get("LIC-2")
+	if err != nil {
+		t.Fatalf("Failed to get license: %v", err)
+	}
+	if active.Revoked() {
+		t.Error("Revocation applied to another license")
+	}
+
+	// A license issued again after its revocation is not revoked
+	if err := ledger.Append(licledger.Record{Event: licledger.EventIssued, License: &licledger.Entry{ID: "LIC-1"}}); err != nil {
+		t.Fatalf("Failed to append record: %v", err)
+	}
+	reissued, err := ledger.Get("LIC-1")
+	if err != nil {
+		t.Fatalf("Failed to get license: %v", err)
+	}
+	if reissued.Revoked() {
+		t.Error("Expected re-issued license not to be revoked")
+	}
+}
+
+// TestLedgerGetIndex tests that lookups see records appended by this ledger
+// and by other writers of the same file, and that returned entries can be
+// modified without changing the ledger
+func TestLedgerGetIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	ledger := licledger.Open(path)
+	other := licledger.Open(path)
+
+	if _, err := ledger.Get("LIC-1"); !errors.Is(err, licledger.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound for a missing ledger, got %v", err)
+	}
+
+	issue := func(l *licledger.Ledger, id, customer string) {
+		t.Helper()
+		record := licledger.Record{Event: licledger.EventIssued, License: &licledger.Entry{ID: id, CustomerID: customer, Data: []byte("license")}}
+		if err := l.Append(record); err != nil {
+			t.Fatalf("Failed to append record: %v", err)
+		}
+	}
+	issue(ledger, "LIC-1", "ACME")
+	entry, err := ledger.Get("LIC-1")
+	if err != nil || entry.CustomerID != "ACME" {
+		t.Fatalf("Unexpected entry %+v, %v", entry, err)
+	}
+	entry.Data = nil
+
+	// Another process reissues and revokes the license
+	issue(other, "LIC-1", "Globex")
+	if err := other.Revoke("LIC-1", "refunded"); err != nil {
+		t.Fatalf("Failed to revoke license: %v", err)
+	}
+	entry, err = ledger.Get("LIC-1")
+	if err != nil {
+		t.Fatalf("Failed to get license: %v", err)
+	}
+	if entry.CustomerID != "Globex" || !entry.Revoked() || len(entry.Data) == 0 {
+		t.Errorf("Lookup does not reflect the ledger file: %+v", entry)
+	}
+	if _, err := ledger.Get("LIC-2"); !errors.Is(err, licledger.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
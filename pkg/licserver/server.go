@@ -0,0 +1,555 @@
+// Package licserver exposes license issuance over an HTTP REST API.
+//
+// The server signs licenses with licgen and keeps them in a licledger
+// ledger. All endpoints require an API token sent as a bearer token:
+//
+//	POST /v1/licenses               issue a license
+//	GET  /v1/licenses               list licenses (customer, product, expires_after, expires_before)
+//	GET  /v1/licenses/{id}          fetch a license
+//	POST /v1/licenses/{id}/renew    renew a license
+//	POST /v1/licenses/{id}/revoke   revoke a license
+//
+// With WithCheckIn, clients running licverify.CheckInClient check licenses
+// in without an API token:
+//
+//	POST /v1/checkin                return a signed lease for a license
+//
+// Unknown, revoked and expired licenses all get the same signed refusal.
+//
+// Licenses are returned as JSON, with the license file base64 encoded in
+// the data field, or as the raw license file when the request has
+// "Accept: application/octet-stream" or the query parameter format=binary.
+package licserver
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licledger"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// Request limits
+const (
+	// maxBodySize bounds the size of request bodies
+	maxBodySize = 1 << 20
+	// MaxValidityDays is the longest validity a request may ask for
+	MaxValidityDays = 100 * 365
+	// maxNonceLength bounds the nonce of check-in requests
+	maxNonceLength = 128
+)
+
+// checkInPath is the path of the check-in endpoint
+const checkInPath = "/v1/checkin"
+
+// Server handles the license REST API. It implements http.Handler.
+type Server struct {
+	privateKey *rsa.PrivateKey
+	ledger     *licledger.Ledger
+	tokens     [][sha256.Size]byte
+	scheme     licformat.SignatureScheme
+	serials    *licgen.SerialGenerator
+	// leaseDuration enables check-in when positive
+	leaseDuration time.Duration
+
+	// mu serializes issuance so that license IDs stay unique
+	mu  sync.Mutex
+	mux *http.ServeMux
+}
+
+// Option configures optional behaviour of the server
+type Option func(*Server)
+
+// WithSignatureScheme selects the signature scheme of issued licenses
+func WithSignatureScheme(scheme licformat.SignatureScheme) Option {
+	return func(s *Server) {
+		s.scheme = scheme
+	}
+}
+
+// WithSerialGenerator generates serial numbers for issue requests without one
+func WithSerialGenerator(serials *licgen.SerialGenerator) Option {
+	return func(s *Server) {
+		s.serials = serials
+	}
+}
+
+// WithCheckIn enables the unauthenticated check-in endpoint, which answers
+// with leases that may be used offline for the given duration. Licenses
+// that are unknown, revoked or expired get a revoked lease, so that the
+// endpoint does not reveal which license IDs exist.
+func WithCheckIn(leaseDuration time.Duration) Option {
+	return func(s *Server) {
+		s.leaseDuration = leaseDuration
+	}
+}
+
+// New creates a server that signs licenses with privateKey, records them in
+// ledger and accepts requests carrying one of the API tokens
+func New(privateKey *rsa.PrivateKey, ledger *licledger.Ledger, tokens []string, opts ...Option) (*Server, error) {
+	if privateKey == nil || ledger == nil {
+		return nil, errors.New("private key and ledger are required")
+	}
+
+	s := &Server{
+		privateKey: privateKey,
+		ledger:     ledger,
+		scheme:     licformat.SchemePKCS1v15,
+	}
+	for _, token := range tokens {
+		if token = strings.TrimSpace(token); token != "" {
+			s.tokens = append(s.tokens, sha256.Sum256([]byte(token)))
+		}
+	}
+	if len(s.tokens) == 0 {
+		return nil, errors.New("at least one API token is required")
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("POST /v1/licenses", s.handleIssue)
+	s.mux.HandleFunc("GET /v1/licenses", s.handleList)
+	s.mux.HandleFunc("GET /v1/licenses/{id}", s.handleFetch)
+	s.mux.HandleFunc("POST /v1/licenses/{id}/renew", s.handleRenew)
+	s.mux.HandleFunc("POST /v1/licenses/{id}/revoke", s.handleRevoke)
+	if s.leaseDuration > 0 {
+		s.mux.HandleFunc("POST "+checkInPath, s.handleCheckIn)
+	}
+
+	return s, nil
+}
+
+// ServeHTTP authenticates the request and dispatches it
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Check-in is called by deployed applications, which hold no API token
+	if r.URL.Path == checkInPath && s.leaseDuration > 0 {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+	if !s.authenticate(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="licforge"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid API token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// authenticate checks the bearer token in constant time
+func (s *Server) authenticate(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(token))
+
+	valid := 0
+	for _, expected := range s.tokens {
+		valid |= subtle.ConstantTimeCompare(sum[:], expected[:])
+	}
+	return valid == 1
+}
+
+// IssueRequest is the body of an issue request
+type IssueRequest struct {
+	ID           string                    `json:"id"` // Generated when empty
+	CustomerID   string                    `json:"customer_id"`
+	ProductID    string                    `json:"product_id"`
+	SerialNumber string                    `json:"serial_number"` // Generated when empty and a serial generator is configured
+	Days         int                       `json:"days"`
+	Features     []string                  `json:"features"`
+	HardwareIDs  licverify.HardwareBinding `json:"hardware_ids"`
+	Seats        uint32                    `json:"seats"`           // Concurrent users of a floating license, 0 if not floating
+	SeatServer   string                    `json:"seat_server_key"` // PEM public key of the seat server, required with seats
+	Quotas       map[string]uint64         `json:"quotas"`          // Usage quotas of metered features
+}
+
+// RenewRequest is the body of a renew request
+type RenewRequest struct {
+	ID   string `json:"id"` // ID of the renewed license, generated when empty
+	Days int    `json:"days"`
+}
+
+// RevokeRequest is the body of a revoke request
+type RevokeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ListResponse is the body of a list response
+type ListResponse struct {
+	Licenses []*licledger.Entry `json:"licenses"`
+}
+
+// ErrorResponse is the body of error responses
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) handleIssue(w http.ResponseWriter, r *http.Request) {
+	var req IssueRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	var missing []string
+	if req.CustomerID == "" {
+		missing = append(missing, "customer_id")
+	}
+	if req.ProductID == "" {
+		missing = append(missing, "product_id")
+	}
+	if req.SerialNumber == "" && s.serials == nil {
+		missing = append(missing, "serial_number")
+	}
+	if req.Seats > 0 && req.SeatServer == "" {
+		missing = append(missing, "seat_server_key")
+	}
+	if len(missing) > 0 {
+		writeError(w, http.StatusBadRequest, "missing required fields: "+strings.Join(missing, ", "))
+		return
+	}
+	if !validDays(w, req.Days) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.ID == "" {
+		id, err := licgen.GenerateID(licgen.IDFormatUUIDv7)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		req.ID = id
+	} else if !s.checkUnusedID(w, req.ID) {
+		return
+	}
+	if req.SerialNumber == "" {
+		serial, err := s.serials.Next()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		req.SerialNumber = serial
+	}
+
+	opts := []licgen.Option{licgen.WithSignatureScheme(s.scheme), licgen.WithRecorder(s.ledger)}
+	if req.Seats > 0 {
+		opts = append(opts, licgen.WithSeats(req.Seats))
+	}
+	if req.SeatServer != "" {
+		serverKey, err := licgen.ParsePublicKey(req.SeatServer)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid seat_server_key: "+err.Error())
+			return
+		}
+		opts = append(opts, licgen.WithSeatServer(serverKey))
+	}
+	for feature, limit := range req.Quotas {
+		opts = append(opts, licgen.WithQuota(feature, limit))
+	}
+	data, err := licgen.GenerateLicense(
+		req.ID,
+		req.CustomerID,
+		req.ProductID,
+		req.SerialNumber,
+		time.Duration(req.Days)*24*time.Hour,
+		req.Features,
+		req.HardwareIDs,
+		s.privateKey,
+		opts...,
+	)
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+
+	s.writeLicense(w, r, http.StatusCreated, data)
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	query := licledger.Query{
+		CustomerID: r.URL.Query().Get("customer"),
+		ProductID:  r.URL.Query().Get("product"),
+	}
+	for _, param := range []struct {
+		name string
+		dest *time.Time
+	}{
+		{"expires_after", &query.ExpiresAfter},
+		{"expires_before", &query.ExpiresBefore},
+	} {
+		value := r.URL.Query().Get(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := parseTime(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s: %v", param.name, err))
+			return
+		}
+		*param.dest = t
+	}
+
+	entries, err := s.ledger.Search(query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// License files are only returned by fetch
+	resp := ListResponse{Licenses: make([]*licledger.Entry, 0, len(entries))}
+	for _, entry := range entries {
+		summary := *entry
+		summary.Data = nil
+		resp.Licenses = append(resp.Licenses, &summary)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleFetch(w http.ResponseWriter, r *http.Request) {
+	entry, ok := s.getEntry(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	if wantsBinary(r) {
+		writeBinary(w, http.StatusOK, entry.ID, entry.Data)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (s *Server) handleRenew(w http.ResponseWriter, r *http.Request) {
+	var req RenewRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if !validDays(w, req.Days) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.getEntry(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	if entry.Revoked() {
+		writeError(w, http.StatusConflict, "license is revoked")
+		return
+	}
+	if req.ID != "" && !s.checkUnusedID(w, req.ID) {
+		return
+	}
+
+	data, err := licgen.Renew(entry.Data, req.ID, time.Duration(req.Days)*24*time.Hour, s.privateKey,
+		licgen.WithRecorder(s.ledger))
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+
+	s.writeLicense(w, r, http.StatusCreated, data)
+}
+
+func (s *Server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	var req RevokeRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	// Serialize with renewals, so that no renewal of a revoked license
+	// passes its revocation check
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := r.PathValue("id")
+	err := s.ledger.Revoke(id, req.Reason)
+	switch {
+	case errors.Is(err, licledger.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, licledger.ErrRevoked):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	entry, ok := s.getEntry(w, id)
+	if !ok {
+		return
+	}
+	entry.Data = nil
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (s *Server) handleCheckIn(w http.ResponseWriter, r *http.Request) {
+	var req licverify.CheckInRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if fingerprint, err := hex.DecodeString(req.Fingerprint); err != nil || len(fingerprint) != sha256.Size {
+		writeError(w, http.StatusBadRequest, "fingerprint must be a hex encoded SHA-256 digest")
+		return
+	}
+	if req.Nonce == "" || len(req.Nonce) > maxNonceLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("nonce must be between 1 and %d bytes", maxNonceLength))
+		return
+	}
+
+	entry, err := s.ledger.Get(req.LicenseID)
+	if err != nil && !errors.Is(err, licledger.ErrNotFound) {
+		writeError(w, http.StatusInternalServerError, "license check-in failed")
+		return
+	}
+
+	// Unknown, revoked and expired licenses get the same refusal, so that
+	// the endpoint does not reveal which license IDs exist
+	now := time.Now().UTC()
+	lease := &licverify.Lease{
+		LicenseID:   req.LicenseID,
+		Fingerprint: req.Fingerprint,
+		Nonce:       req.Nonce,
+		Status:      licverify.LeaseRevoked,
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(s.leaseDuration),
+	}
+	if err == nil && !entry.Revoked() && now.Before(entry.ExpiryDate) {
+		lease.Status = licverify.LeaseActive
+		if lease.ExpiresAt.After(entry.ExpiryDate) {
+			lease.ExpiresAt = entry.ExpiryDate
+		}
+	}
+
+	signed, err := licgen.SignLease(lease, s.privateKey)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, signed)
+}
+
+// getEntry looks up a license and writes an error response if it fails
+func (s *Server) getEntry(w http.ResponseWriter, id string) (*licledger.Entry, bool) {
+	entry, err := s.ledger.Get(id)
+	if errors.Is(err, licledger.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return entry, true
+}
+
+// checkUnusedID writes a conflict response if a license was already issued with id
+func (s *Server) checkUnusedID(w http.ResponseWriter, id string) bool {
+	_, err := s.ledger.Get(id)
+	switch {
+	case err == nil:
+		writeError(w, http.StatusConflict, fmt.Sprintf("license %s already exists", id))
+		return false
+	case !errors.Is(err, licledger.ErrNotFound):
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	return true
+}
+
+// writeLicense writes a newly signed license
+func (s *Server) writeLicense(w http.ResponseWriter, r *http.Request, status int, data []byte) {
+	license, err := licformat.DecodeLicenseFile(data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if wantsBinary(r) {
+		writeBinary(w, status, license.ID, data)
+		return
+	}
+	writeJSON(w, status, licledger.NewEntry(license, data))
+}
+
+// validDays writes a bad request response for invalid validity periods
+func validDays(w http.ResponseWriter, days int) bool {
+	if days <= 0 || days > MaxValidityDays {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", MaxValidityDays))
+		return false
+	}
+	return true
+}
+
+// decodeRequest decodes a JSON request body, rejecting unknown fields
+func decodeRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return false
+	}
+	if decoder.More() {
+		writeError(w, http.StatusBadRequest, "invalid request body: unexpected data after JSON object")
+		return false
+	}
+	return true
+}
+
+// writeGenerationError maps license generation errors to responses
+func writeGenerationError(w http.ResponseWriter, err error) {
+	if errors.Is(err, licformat.ErrFieldTooLarge) || errors.Is(err, licformat.ErrInvalidField) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+// parseTime parses an RFC 3339 timestamp or a YYYY-MM-DD date
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// wantsBinary reports whether the client asked for the raw license file
+func wantsBinary(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "binary" ||
+		strings.Contains(r.Header.Get("Accept"), "application/octet-stream")
+}
+
+func writeBinary(w http.ResponseWriter, status int, id string, data []byte) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+".lic"))
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Error: message})
+}
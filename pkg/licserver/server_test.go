@@ -0,0 +1,414 @@
+package licserver_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licledger"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licserver"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+const testToken = "test-token"
+
+// newTestServer starts a license server with a fresh key and ledger
+func newTestServer(t *testing.T, opts ...licserver.Option) (*httptest.Server, *rsa.PrivateKey) {
+	t.Helper()
+
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	ledger := licledger.Open(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	server, err := licserver.New(privateKey, ledger, []string{"other-token", testToken}, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	return ts, privateKey
+}
+
+// do sends an authenticated request and decodes a JSON response into out
+func do(t *testing.T, ts *httptest.Server, method, path, body string, out any) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return resp
+}
+
+func TestServerAuthentication(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	for _, header := range []string{"", "Bearer", "Bearer wrong-token", "Basic " + testToken} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/licenses", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: expected 401, got %d", header, resp.StatusCode)
+		}
+	}
+
+	if resp := do(t, ts, http.MethodGet, "/v1/licenses", "", nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 with a valid token, got %d", resp.StatusCode)
+	}
+
+	if _, err := licserver.New(nil, licledger.Open("ledger.jsonl"), []string{"token"}); err == nil {
+		t.Error("Expected error without a private key")
+	}
+}
+
+func TestServerIssueAndFetch(t *testing.T) {
+	ts, privateKey := newTestServer(t)
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+
+	var issued licledger.Entry
+	resp := do(t, ts, http.MethodPost, "/v1/licenses", `{
+		"id": "LIC-001", "customer_id": "ACME", "product_id": "APP", "serial_number": "SN-001",
+		"days": 30, "features": ["basic"], "hardware_ids": {"mac_addresses": ["00:11:22:33:44:55"]},
+		"quotas": {"runs": 100}
+	}`, &issued)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+
+	license, err := verifier.ParseLicense(issued.Data)
+	if err != nil {
+		t.Fatalf("Failed to parse issued license: %v", err)
+	}
+	if err := verifier.VerifySignature(license); err != nil {
+		t.Errorf("Issued license does not verify: %v", err)
+	}
+	if license.ID() != "LIC-001" || !license.HasFeature("basic") || len(license.HardwareIDs().MACAddresses) != 1 {
+		t.Errorf("Unexpected license: %s %v %+v", license.ID(), license.Features(), license.HardwareIDs())
+	}
+	if limit, _ := license.Quota("runs"); limit != 100 || issued.Quotas["runs"] != 100 {
+		t.Errorf("Unexpected quotas: %v, ledger %v", license.Quotas(), issued.Quotas)
+	}
+
+	// Fetch as JSON
+	var fetched licledger.Entry
+	if resp := do(t, ts, http.MethodGet, "/v1/licenses/LIC-001", "", &fetched); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if !bytes.Equal(fetched.Data, issued.Data) {
+		t.Error("Fetched license differs from the issued license")
+	}
+
+	// Fetch as binary
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/licenses/LIC-001", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Accept", "application/octet-stream")
+	binResp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	data, _ := io.ReadAll(binResp.Body)
+	binResp.Body.Close()
+	if binResp.Header.Get("Content-Type") != "application/octet-stream" || !bytes.Equal(data, issued.Data) {
+		t.Errorf("Unexpected binary response: %s, %d bytes", binResp.Header.Get("Content-Type"), len(data))
+	}
+
+	if resp := do(t, ts, http.MethodGet, "/v1/licenses/LIC-404", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown license, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerIssueValidation(t *testing.T) {
+	serials := licgen.NewSerialGenerator(licgen.SerialScheme{Prefix: "SN-", Width: 4},
+		licgen.NewFileCounter(filepath.Join(t.TempDir(), "serials.json")))
+	ts, _ := newTestServer(t, licserver.WithSerialGenerator(serials))
+
+	// ID and serial number are generated when missing
+	var issued licledger.Entry
+	resp := do(t, ts, http.MethodPost, "/v1/licenses", `{"customer_id": "ACME", "product_id": "APP", "days": 10}`, &issued)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	if issued.ID == "" || issued.SerialNumber != "SN-0001" {
+		t.Errorf("Expected generated ID and serial, got %q and %q", issued.ID, issued.SerialNumber)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"MissingFields", `{"days": 10}`, http.StatusBadRequest},
+		{"InvalidDays", `{"customer_id": "A", "product_id": "P", "days": 0}`, http.StatusBadRequest},
+		{"TooManyDays", `{"customer_id": "A", "product_id": "P", "days": 1000000}`, http.StatusBadRequest},
+		{"UnknownField", `{"customer_id": "A", "product_id": "P", "days": 1, "owner": "x"}`, http.StatusBadRequest},
+		{"MalformedJSON", `{"customer_id": `, http.StatusBadRequest},
+		{"TrailingData", `{"customer_id": "A", "product_id": "P", "days": 1} {}`, http.StatusBadRequest},
+		{"FieldTooLarge", `{"customer_id": "` + strings.Repeat("a", 70000) + `", "product_id": "P", "days": 1}`, http.StatusBadRequest},
+		{"SeatsWithoutServerKey", `{"customer_id": "A", "product_id": "P", "days": 1, "seats": 5}`, http.StatusBadRequest},
+		{"InvalidSeatServerKey", `{"customer_id": "A", "product_id": "P", "days": 1, "seats": 5, "seat_server_key": "x"}`, http.StatusBadRequest},
+		{"ZeroQuota", `{"customer_id": "A", "product_id": "P", "days": 1, "quotas": {"runs": 0}}`, http.StatusBadRequest},
+		{"DuplicateID", `{"id": "` + issued.ID + `", "customer_id": "A", "product_id": "P", "days": 1}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errResp licserver.ErrorResponse
+			resp := do(t, ts, http.MethodPost, "/v1/licenses", tt.body, &errResp)
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected %d, got %d (%s)", tt.status, resp.StatusCode, errResp.Error)
+			}
+			if errResp.Error == "" {
+				t.Error("Expected an error message")
+			}
+		})
+	}
+}
+
+func TestServerListRenewRevoke(t *testing.T) {
+	ts, privateKey := newTestServer(t)
+
+	for _, body := range []string{
+		`{"id": "LIC-1", "customer_id": "ACME", "product_id": "APP", "serial_number": "SN-1", "days": 30}`,
+		`{"id": "LIC-2", "customer_id": "ACME", "product_id": "TOOL", "serial_number": "SN-2", "days": 365}`,
+		`{"id": "LIC-3", "customer_id": "Globex", "product_id": "APP", "serial_number": "SN-3", "days": 90}`,
+	} {
+		if resp := do(t, ts, http.MethodPost, "/v1/licenses", body, nil); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d", resp.StatusCode)
+		}
+	}
+
+	// List with filters, without license files
+	var list licserver.ListResponse
+	do(t, ts, http.MethodGet, "/v1/licenses?customer=acme", "", &list)
+	if len(list.Licenses) != 2 || list.Licenses[0].Data != nil {
+		t.Errorf("Expected 2 ACME licenses without data, got %+v", list.Licenses)
+	}
+	expiresBefore := time.Now().AddDate(0, 0, 100).Format(time.DateOnly)
+	do(t, ts, http.MethodGet, "/v1/licenses?expires_before="+expiresBefore, "", &list)
+	if len(list.Licenses) != 2 {
+		t.Errorf("Expected 2 licenses expiring within 100 days, got %d", len(list.Licenses))
+	}
+	if resp := do(t, ts, http.MethodGet, "/v1/licenses?expires_after=soon", "", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid date, got %d", resp.StatusCode)
+	}
+
+	// Renew
+	var renewed licledger.Entry
+	resp := do(t, ts, http.MethodPost, "/v1/licenses/LIC-1/renew", `{"id": "LIC-1R", "days": 365}`, &renewed)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	if renewed.ID != "LIC-1R" || renewed.PreviousID != "LIC-1" {
+		t.Errorf("Unexpected renewal: %s renewing %q", renewed.ID, renewed.PreviousID)
+	}
+	license, err := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey).ParseLicense(renewed.Data)
+	if err != nil {
+		t.Fatalf("Failed to parse renewed license: %v", err)
+	}
+	if days := time.Until(license.ExpiryDate()).Hours() / 24; days < 393 || days > 395 {
+		t.Errorf("Expected about 395 days validity, got %.1f", days)
+	}
+	if resp := do(t, ts, http.MethodPost, "/v1/licenses/LIC-1/renew", `{"id": "LIC-2", "days": 30}`, nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 for an existing ID, got %d", resp.StatusCode)
+	}
+
+	// Revoke
+	var revoked licledger.Entry
+	resp = do(t, ts, http.MethodPost, "/v1/licenses/LIC-2/revoke", `{"reason": "refunded"}`, &revoked)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if !revoked.Revoked() || revoked.Revocation.Reason != "refunded" {
+		t.Errorf("Unexpected revocation: %+v", revoked.Revocation)
+	}
+	for _, tc := range []struct {
+		path, body string
+		status     int
+	}{
+		{"/v1/licenses/LIC-2/revoke", `{}`, http.StatusConflict},
+		{"/v1/licenses/LIC-2/renew", `{"days": 30}`, http.StatusConflict},
+		{"/v1/licenses/LIC-9/revoke", `{}`, http.StatusNotFound},
+	} {
+		if resp := do(t, ts, http.MethodPost, tc.path, tc.body, nil); resp.StatusCode != tc.status {
+			t.Errorf("%s: expected %d, got %d", tc.path, tc.status, resp.StatusCode)
+		}
+	}
+
+	// Unsupported methods
+	if resp := do(t, ts, http.MethodDelete, "/v1/licenses/LIC-1", "", nil); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerCheckIn(t *testing.T) {
+	ts, privateKey := newTestServer(t, licserver.WithCheckIn(48*time.Hour))
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+
+	var issued licledger.Entry
+	body := `{"id": "LIC-1", "customer_id": "ACME", "product_id": "APP", "serial_number": "SN-1", "days": 30}`
+	if resp := do(t, ts, http.MethodPost, "/v1/licenses", body, &issued); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	license, err := verifier.ParseLicense(issued.Data)
+	if err != nil {
+		t.Fatalf("Failed to parse license: %v", err)
+	}
+
+	fingerprint := bytes.Repeat([]byte{0xab}, 32)
+	client, err := licverify.NewCheckInClient(verifier, ts.URL+"/v1/checkin",
+		licverify.WithHTTPClient(ts.Client()), licverify.WithMachineFingerprint(fingerprint))
+	if err != nil {
+		t.Fatalf("Failed to create check-in client: %v", err)
+	}
+
+	// Check-in needs no API token
+	lease, err := client.CheckIn(context.Background(), license)
+	if err != nil {
+		t.Fatalf("Check-in failed: %v", err)
+	}
+	if lease.Status != licverify.LeaseActive || lease.ExpiresAt.Sub(lease.IssuedAt) != 48*time.Hour {
+		t.Errorf("Unexpected lease: %+v", lease)
+	}
+
+	// Invalid requests
+	for _, body := range []string{
+		`{"license_id": "LIC-1", "fingerprint": "abc", "nonce": "n"}`,
+		`{"license_id": "LIC-1", "fingerprint": "` + strings.Repeat("ab", 32) + `", "nonce": ""}`,
+	} {
+		resp, err := ts.Client().Post(ts.URL+"/v1/checkin", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, resp.StatusCode)
+		}
+	}
+
+	// Revoked licenses get a revoked lease
+	if resp := do(t, ts, http.MethodPost, "/v1/licenses/LIC-1/revoke", `{}`, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if _, err := client.CheckIn(context.Background(), license); !errors.Is(err, licverify.ErrLicenseRevoked) {
+		t.Errorf("Expected ErrLicenseRevoked, got %v", err)
+	}
+
+	// Check-in is disabled by default
+	plain, _ := newTestServer(t)
+	resp, err := plain.Client().Post(plain.URL+"/v1/checkin", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with check-in disabled, got %d", resp.StatusCode)
+	}
+}
+
+// TestServerCheckInExpiry tests that expired and unknown licenses are
+// refused like revoked ones and that leases end with the license
+func TestServerCheckInExpiry(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	now := time.Now().UTC()
+	ledger := licledger.Open(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	for _, entry := range []*licledger.Entry{
+		{ID: "LIC-EXPIRED", IssueDate: now.Add(-48 * time.Hour), ExpiryDate: now.Add(-time.Hour)},
+		{ID: "LIC-ENDING", IssueDate: now.Add(-48 * time.Hour), ExpiryDate: now.Add(time.Hour)},
+	} {
+		if err := ledger.Append(licledger.Record{Event: licledger.EventIssued, License: entry}); err != nil {
+			t.Fatalf("Failed to append record: %v", err)
+		}
+	}
+	server, err := licserver.New(privateKey, ledger, []string{testToken}, licserver.WithCheckIn(48*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	checkIn := func(id string) (*http.Response, *licverify.SignedLease) {
+		body := `{"license_id": "` + id + `", "fingerprint": "` + strings.Repeat("ab", 32) + `", "nonce": "n"}`
+		resp, err := ts.Client().Post(ts.URL+"/v1/checkin", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var signed licverify.SignedLease
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&signed); err != nil {
+				t.Fatalf("Failed to decode lease: %v", err)
+			}
+		}
+		return resp, &signed
+	}
+
+	// Expired and unknown licenses get the same refusal as revoked ones
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+	for _, id := range []string{"LIC-EXPIRED", "LIC-UNKNOWN"} {
+		resp, signed := checkIn(id)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", id, resp.StatusCode)
+		}
+		lease, err := verifier.VerifyLease(signed)
+		if err != nil {
+			t.Fatalf("%s: failed to verify lease: %v", id, err)
+		}
+		if lease.Status != licverify.LeaseRevoked || lease.LicenseID != id || !lease.ExpiresAt.Equal(lease.IssuedAt.Add(48*time.Hour)) {
+			t.Errorf("%s: expected a generic revoked lease, got %+v", id, lease)
+		}
+	}
+
+	resp, signed := checkIn("LIC-ENDING")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	lease, err := verifier.VerifyLease(signed)
+	if err != nil {
+		t.Fatalf("Failed to verify lease: %v", err)
+	}
+	if lease.ExpiresAt.After(now.Add(time.Hour)) {
+		t.Errorf("Lease outlives the license: expires %v", lease.ExpiresAt)
+	}
+}
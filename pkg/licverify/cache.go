@@ -0,0 +1,119 @@
+package licverify
+
+import (
+	"sync"
+	"time"
+)
+
+// WithCache makes the verifier cache the hardware information used by
+// VerifyHardwareBinding and machine-bound licenses, and the result of
+// VerifySignature for each license, for ttl. Without it every hardware
+// check collects the hardware information again, which runs external
+// commands on some platforms. A cached verifier is safe for concurrent use;
+// concurrent callers share a single hardware collection.
+func WithCache(ttl time.Duration) Option {
+	return func(v *Verifier) {
+		if ttl > 0 {
+			v.cache = &verifierCache{ttl: ttl, signatures: make(map[*License]cachedResult)}
+		}
+	}
+}
+
+// ClearCache discards cached hardware information and verification
+// results, for example after a hardware change. It does nothing for
+// verifiers created without WithCache.
+func (v *Verifier) ClearCache() {
+	if v.cache == nil {
+		return
+	}
+	v.cache.collect.Lock()
+	defer v.cache.collect.Unlock()
+	v.cache.mu.Lock()
+	defer v.cache.mu.Unlock()
+
+	v.cache.hardware = hardwareResult{}
+	clear(v.cache.signatures)
+}
+
+// verifierCache holds the cached state of a verifier, see WithCache
+type verifierCache struct {
+	ttl time.Duration
+
+	// collect serializes hardware collection, so that callers arriving
+	// while the information is collected wait for the result
+	collect sync.Mutex
+
+	mu         sync.RWMutex
+	hardware   hardwareResult
+	signatures map[*License]cachedResult // Licenses are immutable
+}
+
+// hardwareResult is cached hardware information
+type hardwareResult struct {
+	info    *HardwareInfo
+	err     error
+	expires time.Time
+}
+
+// cachedResult is a cached verification result
+type cachedResult struct {
+	err     error
+	expires time.Time
+}
+
+// hardwareInfo returns the hardware information of this machine, from the
+// cache if the verifier has one
+func (v *Verifier) hardwareInfo() (*HardwareInfo, error) {
+	if v.hwInfo != nil {
+		return v.hwInfo, nil
+	}
+
+	c := v.cache
+	if c == nil {
+		return v.hardware.Collect()
+	}
+
+	if result, ok := c.cachedHardware(time.Now()); ok {
+		return result.info, result.err
+	}
+
+	c.collect.Lock()
+	defer c.collect.Unlock()
+	if result, ok := c.cachedHardware(time.Now()); ok {
+		return result.info, result.err
+	}
+
+	hwInfo, err := v.hardware.Collect()
+	c.mu.Lock()
+	c.hardware = hardwareResult{info: hwInfo, err: err, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return hwInfo, err
+}
+
+// cachedHardware returns the cached hardware information if it has not expired
+func (c *verifierCache) cachedHardware(now time.Time) (hardwareResult, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.hardware, now.Before(c.hardware.expires)
+}
+
+// cachedSignature returns the cached signature verification result of a license
+func (c *verifierCache) cachedSignature(license *License, now time.Time) (cachedResult, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result, ok := c.signatures[license]
+	return result, ok && now.Before(result.expires)
+}
+
+// storeSignature caches the signature verification result of a license
+// and drops expired results, so that discarded licenses are not retained
+func (c *verifierCache) storeSignature(license *License, err error, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for cached, result := range c.signatures {
+		if !now.Before(result.expires) {
+			delete(c.signatures, cached)
+		}
+	}
+	c.signatures[license] = cachedResult{err: err, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,133 @@
+package licverify_test
+
+import (
+	"crypto/rsa"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// newBoundLicense generates a license bound to the hostname of this machine
+func newBoundLicense(tb testing.TB) (*rsa.PrivateKey, []byte) {
+	tb.Helper()
+
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		tb.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		tb.Fatalf("Failed to parse private key: %v", err)
+	}
+	hwInfo, err := licverify.GetHardwareInfo()
+	if err != nil {
+		tb.Fatalf("Failed to get hardware info: %v", err)
+	}
+
+	data, err := licgen.GenerateLicense("LIC-1", "ACME", "APP", "SN-1", 24*time.Hour, []string{"basic"},
+		licverify.HardwareBinding{HostNames: []string{hwInfo.Hostname}}, privateKey)
+	if err != nil {
+		tb.Fatalf("Failed to generate license: %v", err)
+	}
+	return privateKey, data
+}
+
+func TestCachedVerifier(t *testing.T) {
+	privateKey, data := newBoundLicense(t)
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey, licverify.WithCache(time.Minute))
+	license, err := verifier.ParseLicense(data)
+	if err != nil {
+		t.Fatalf("Failed to parse license: %v", err)
+	}
+
+	// Concurrent verification shares the cache
+	var wg sync.WaitGroup
+	for range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				if err := license.IsValid(verifier); err != nil {
+					t.Errorf("License validation failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The cached path does not allocate
+	if allocs := testing.AllocsPerRun(100, func() { license.IsValid(verifier) }); allocs > 0 {
+		t.Errorf("Expected no allocations on the cached path, got %.1f", allocs)
+	}
+
+	// Failures are cached per license, other licenses are verified
+	otherKey, otherData := newBoundLicense(t)
+	foreign, err := licverify.NewVerifierFromPublicKey(&otherKey.PublicKey).ParseLicense(otherData)
+	if err != nil {
+		t.Fatalf("Failed to parse license: %v", err)
+	}
+	for range 2 {
+		if err := verifier.VerifySignature(foreign); err == nil {
+			t.Error("Expected signature error for a license signed by another key")
+		}
+	}
+	if err := verifier.VerifySignature(license); err != nil {
+		t.Errorf("Signature verification failed: %v", err)
+	}
+
+	verifier.ClearCache()
+	if err := license.IsValid(verifier); err != nil {
+		t.Errorf("License validation failed after clearing the cache: %v", err)
+	}
+}
+
+func BenchmarkIsValid(b *testing.B) {
+	privateKey, data := newBoundLicense(b)
+
+	for _, bc := range []struct {
+		name string
+		opts []licverify.Option
+	}{
+		{"Uncached", nil},
+		{"Cached", []licverify.Option{licverify.WithCache(time.Minute)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey, bc.opts...)
+			license, err := verifier.ParseLicense(data)
+			if err != nil {
+				b.Fatalf("Failed to parse license: %v", err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if err := license.IsValid(verifier); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkIsValidCachedParallel(b *testing.B) {
+	privateKey, data := newBoundLicense(b)
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey, licverify.WithCache(time.Minute))
+	license, err := verifier.ParseLicense(data)
+	if err != nil {
+		b.Fatalf("Failed to parse license: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := license.IsValid(verifier); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
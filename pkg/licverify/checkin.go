@@ -0,0 +1,435 @@
+package licverify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
+)
+
+// Online check-in complements the offline checks: a client periodically
+// sends the license ID and machine fingerprint to a server, which answers
+// with a lease signed by the license signing key. The latest lease is
+// cached so the application keeps working without network access until
+// the offline window has passed.
+
+// Errors returned by online check-in
+var (
+	// ErrLicenseRevoked is returned when the server refuses the license. The
+	// server does not say whether it is revoked, expired or unknown.
+	ErrLicenseRevoked = errors.New("license has been revoked or is unknown to the server")
+	// ErrCheckInRejected is returned when the server refuses the check-in
+	ErrCheckInRejected = errors.New("license check-in rejected")
+	// ErrCheckInRequired is returned when the server cannot be reached and
+	// no lease within the offline window is available
+	ErrCheckInRequired = errors.New("online license check-in required")
+)
+
+// leaseContext prefixes lease payloads so that a lease signature can never
+// be mistaken for a license signature
+const leaseContext = "go-license lease v1\n"
+
+// maxClockSkew is how far in the future a lease may be issued before the
+// local clock is considered to have been moved back
+const maxClockSkew = 5 * time.Minute
+
+// maxLeaseSize bounds the size of check-in responses and cached leases
+const maxLeaseSize = 64 << 10
+
+// LeaseStatus is the license status reported by the check-in server
+type LeaseStatus string
+
+const (
+	// LeaseActive allows the license to be used until the lease expires
+	LeaseActive LeaseStatus = "active"
+	// LeaseRevoked reports that the server refuses the license because it
+	// was revoked, has expired or is unknown
+	LeaseRevoked LeaseStatus = "revoked"
+)
+
+// Lease is the check-in server's answer for a license on one machine
+type Lease struct {
+	LicenseID   string      `json:"license_id"`
+	Fingerprint string      `json:"fingerprint"` // Hex encoded machine fingerprint
+	Nonce       string      `json:"nonce"`       // Echoed from the check-in request
+	Status      LeaseStatus `json:"status"`
+	IssuedAt    time.Time   `json:"issued_at"`
+	ExpiresAt   time.Time   `json:"expires_at"` // Latest time the lease may be used offline
+}
+
+// SignedLease is a lease as sent by the server and stored in the lease cache
+type SignedLease struct {
+	Payload   []byte `json:"payload"`   // Encoded by EncodeLease
+	Signature []byte `json:"signature"` // RSA-PSS SHA-256 signature over Payload
+}
+
+// CheckInRequest is the body of a check-in request
+type CheckInRequest struct {
+	LicenseID   string `json:"license_id"`
+	Fingerprint string `json:"fingerprint"`
+	Nonce       string `json:"nonce"`
+}
+
+// EncodeLease encodes a lease into the payload signed by the server
+func EncodeLease(lease *Lease) ([]byte, error) {
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lease: %v", err)
+	}
+	return append([]byte(leaseContext), data...), nil
+}
+
+// VerifyLease checks the signature of a signed lease and returns its contents
+func (v *Verifier) VerifyLease(signed *SignedLease) (*Lease, error) {
+	var lease Lease
+	if err := openSignedLease(signed, v.publicKey, leaseContext, &lease); err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// openSignedLease verifies the RSA-PSS signature of a lease and decodes its
+// payload, which must start with prefix
+func openSignedLease(signed *SignedLease, publicKey *rsa.PublicKey, prefix string, lease any) error {
+	hashed := sha256.Sum256(signed.Payload)
+	if err := verifyWithScheme(publicKey, licformat.SchemePSS, hashed[:], signed.Signature); err != nil {
+		return fmt.Errorf("invalid lease signature: %v", err)
+	}
+
+	data, ok := bytes.CutPrefix(signed.Payload, []byte(prefix))
+	if !ok {
+		return errors.New("invalid lease payload")
+	}
+	if err := json.Unmarshal(data, lease); err != nil {
+		return fmt.Errorf("invalid lease payload: %v", err)
+	}
+	return nil
+}
+
+// CheckInClient verifies licenses online with offline fallback.
+// It is safe for concurrent use.
+type CheckInClient struct {
+	verifier      *Verifier
+	endpoint      string
+	httpClient    *http.Client
+	cachePath     string
+	interval      time.Duration
+	offlineWindow time.Duration
+	fingerprint   []byte
+
+	mu    sync.Mutex
+	lease *Lease
+}
+
+// CheckInOption configures optional behaviour of a CheckInClient
+type CheckInOption func(*CheckInClient)
+
+// WithHTTPClient sets the HTTP client used for check-ins
+func WithHTTPClient(client *http.Client) CheckInOption {
+	return func(c *CheckInClient) {
+		c.httpClient = client
+	}
+}
+
+// WithLeaseCache stores the latest lease in a file, so the offline window
+// also covers application restarts
+func WithLeaseCache(path string) CheckInOption {
+	return func(c *CheckInClient) {
+		c.cachePath = path
+	}
+}
+
+// WithCheckInInterval sets how long a lease is used before checking in
+// again (default 24 hours)
+func WithCheckInInterval(interval time.Duration) CheckInOption {
+	return func(c *CheckInClient) {
+		c.interval = interval
+	}
+}
+
+// WithOfflineWindow sets how long after the last successful check-in the
+// license may be used while the server cannot be reached (default 7 days).
+// The lease expiry set by the server also applies.
+func WithOfflineWindow(window time.Duration) CheckInOption {
+	return func(c *CheckInClient) {
+		c.offlineWindow = window
+	}
+}
+
+// WithMachineFingerprint sets the fingerprint sent to the server instead of
+// the fingerprint of the current hardware
+func WithMachineFingerprint(fingerprint []byte) CheckInOption {
+	return func(c *CheckInClient) {
+		c.fingerprint = cloneBytes(fingerprint)
+	}
+}
+
+// NewCheckInClient creates a client that checks licenses in at endpoint.
+// Leases must be signed with the key that signs the licenses.
+func NewCheckInClient(verifier *Verifier, endpoint string, opts ...CheckInOption) (*CheckInClient, error) {
+	if verifier == nil {
+		return nil, errors.New("verifier cannot be nil")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid check-in endpoint: %q", endpoint)
+	}
+
+	c := &CheckInClient{
+		verifier:      verifier,
+		endpoint:      endpoint,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		interval:      24 * time.Hour,
+		offlineWindow: 7 * 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Lease returns a copy of the latest valid lease, or nil if there is none
+func (c *CheckInClient) Lease() *Lease {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lease == nil {
+		return nil
+	}
+	lease := *c.lease
+	return &lease
+}
+
+// CheckIn sends the license to the server and returns the verified lease.
+// Active and revoked leases are cached; a revoked lease is returned together
+// with ErrLicenseRevoked.
+func (c *CheckInClient) CheckIn(ctx context.Context, license *License) (*Lease, error) {
+	fingerprint, err := c.machineFingerprint()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(CheckInRequest{
+		LicenseID:   license.id,
+		Fingerprint: fingerprint,
+		Nonce:       nonce,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode check-in request: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create check-in request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("license check-in failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxLeaseSize))
+	if err != nil {
+		return nil, fmt.Errorf("license check-in failed: %v", err)
+	}
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &errResp) != nil || errResp.Error == "" {
+			errResp.Error = resp.Status
+		}
+		return nil, fmt.Errorf("%w: %s", ErrCheckInRejected, errResp.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("license check-in failed: server returned %s", resp.Status)
+	}
+
+	var signed SignedLease
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, fmt.Errorf("invalid check-in response: %v", err)
+	}
+	lease, err := c.verifyLease(&signed, license, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if lease.Nonce != nonce {
+		return nil, errors.New("invalid lease: nonce does not match the check-in request")
+	}
+
+	c.mu.Lock()
+	c.lease = lease
+	c.mu.Unlock()
+	if err := c.saveLease(&signed); err != nil {
+		return nil, err
+	}
+
+	if lease.Status == LeaseRevoked {
+		return lease, ErrLicenseRevoked
+	}
+	return lease, nil
+}
+
+// Verify performs the offline checks of License.IsValid and then makes sure
+// a current lease exists, checking in when the last lease is older than the
+// check-in interval. While the server cannot be reached, the latest lease
+// keeps the license usable until the offline window has passed. A revoked
+// lease is checked again after the interval as well, so that a reinstated
+// license recovers.
+func (c *CheckInClient) Verify(ctx context.Context, license *License) error {
+	if err := license.IsValid(c.verifier); err != nil {
+		return err
+	}
+
+	fingerprint, err := c.machineFingerprint()
+	if err != nil {
+		return err
+	}
+	lease := c.currentLease(license, fingerprint)
+	now := time.Now()
+	if lease != nil && now.Sub(lease.IssuedAt) < c.interval {
+		if lease.Status == LeaseRevoked {
+			return ErrLicenseRevoked
+		}
+		if c.usable(lease, now) == nil {
+			return nil
+		}
+	}
+
+	_, err = c.CheckIn(ctx, license)
+	if err == nil || errors.Is(err, ErrLicenseRevoked) || errors.Is(err, ErrCheckInRejected) {
+		return err
+	}
+
+	// The server could not be reached, fall back to the latest lease
+	if lease == nil {
+		return fmt.Errorf("%w: %v", ErrCheckInRequired, err)
+	}
+	if lease.Status == LeaseRevoked {
+		return ErrLicenseRevoked
+	}
+	if usableErr := c.usable(lease, now); usableErr != nil {
+		return fmt.Errorf("%w: %v (%v)", ErrCheckInRequired, usableErr, err)
+	}
+	return nil
+}
+
+// Run verifies the license immediately and then once per check-in interval,
+// passing each result to report, until ctx is cancelled
+func (c *CheckInClient) Run(ctx context.Context, license *License, report func(error)) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		report(c.Verify(ctx, license))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// usable reports why a lease cannot be used offline at the given time
+func (c *CheckInClient) usable(lease *Lease, now time.Time) error {
+	switch {
+	case lease.IssuedAt.After(now.Add(maxClockSkew)):
+		return errors.New("lease was issued in the future, check the system clock")
+	case !now.Before(lease.IssuedAt.Add(c.offlineWindow)):
+		return fmt.Errorf("last check-in on %s is outside the offline window", lease.IssuedAt.Format(time.RFC3339))
+	case !now.Before(lease.ExpiresAt):
+		return fmt.Errorf("lease expired on %s", lease.ExpiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// currentLease returns the lease for the license from memory or from the
+// lease cache. Invalid cached leases are ignored.
+func (c *CheckInClient) currentLease(license *License, fingerprint string) *Lease {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lease != nil && c.lease.LicenseID == license.id && c.lease.Fingerprint == fingerprint {
+		return c.lease
+	}
+	if c.cachePath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.cachePath)
+	if err != nil || len(data) > maxLeaseSize {
+		return nil
+	}
+	var signed SignedLease
+	if json.Unmarshal(data, &signed) != nil {
+		return nil
+	}
+	lease, err := c.verifyLease(&signed, license, fingerprint)
+	if err != nil {
+		return nil
+	}
+	c.lease = lease
+	return lease
+}
+
+// verifyLease checks that a signed lease was issued for the license on this machine
+func (c *CheckInClient) verifyLease(signed *SignedLease, license *License, fingerprint string) (*Lease, error) {
+	lease, err := c.verifier.VerifyLease(signed)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case lease.LicenseID != license.id:
+		return nil, errors.New("invalid lease: issued for a different license")
+	case lease.Fingerprint != fingerprint:
+		return nil, errors.New("invalid lease: issued for a different machine")
+	case lease.Status != LeaseActive && lease.Status != LeaseRevoked:
+		return nil, fmt.Errorf("invalid lease: unknown status %q", lease.Status)
+	}
+	return lease, nil
+}
+
+// saveLease writes the lease cache atomically
+func (c *CheckInClient) saveLease(signed *SignedLease) error {
+	if c.cachePath == "" {
+		return nil
+	}
+	data, err := json.Marshal(signed)
+	if err != nil {
+		return fmt.Errorf("failed to encode lease: %v", err)
+	}
+	if err := writeFileAtomic(c.cachePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write lease cache: %v", err)
+	}
+	return nil
+}
+
+// machineFingerprint returns the hex encoded fingerprint sent to the server
+func (c *CheckInClient) machineFingerprint() (string, error) {
+	if c.fingerprint != nil {
+		return hex.EncodeToString(c.fingerprint), nil
+	}
+	hwInfo, err := c.verifier.hardwareInfo()
+	if err != nil {
+		return "", fmt.Errorf("failed to get hardware info: %v", err)
+	}
+	return hex.EncodeToString(hwInfo.Fingerprint()), nil
+}
@@ -0,0 +1,293 @@
+package licverify_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// checkInServer is a test check-in endpoint whose answers can be changed
+type checkInServer struct {
+	privateKey *rsa.PrivateKey
+
+	mu       sync.Mutex
+	status   int                   // HTTP status of error answers, 0 answers with a lease
+	lease    licverify.LeaseStatus // Status of issued leases
+	nonce    string                // Overrides the nonce of issued leases
+	requests int
+}
+
+func (s *checkInServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unknown license"})
+		return
+	}
+
+	var req licverify.CheckInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if s.nonce != "" {
+		req.Nonce = s.nonce
+	}
+	now := time.Now()
+	signed, err := licgen.SignLease(&licverify.Lease{
+		LicenseID:   req.LicenseID,
+		Fingerprint: req.Fingerprint,
+		Nonce:       req.Nonce,
+		Status:      s.lease,
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(24 * time.Hour),
+	}, s.privateKey)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(signed)
+}
+
+func (s *checkInServer) set(status int, lease licverify.LeaseStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.lease = status, lease
+}
+
+func (s *checkInServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// TestCheckInClient tests online check-in with offline fallback
+func TestCheckInClient(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+
+	licenseData, err := licgen.GenerateLicense("LIC-1", "ACME", "APP", "SN-1", 30*24*time.Hour,
+		[]string{"basic"}, licverify.HardwareBinding{}, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to generate license: %v", err)
+	}
+	license, err := verifier.ParseLicense(licenseData)
+	if err != nil {
+		t.Fatalf("Failed to parse license: %v", err)
+	}
+
+	server := &checkInServer{privateKey: privateKey, lease: licverify.LeaseActive}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	fingerprint := []byte("0123456789abcdef0123456789abcdef")
+	cachePath := filepath.Join(t.TempDir(), "lease.json")
+	newClient := func(opts ...licverify.CheckInOption) *licverify.CheckInClient {
+		t.Helper()
+		opts = append([]licverify.CheckInOption{
+			licverify.WithLeaseCache(cachePath),
+			licverify.WithMachineFingerprint(fingerprint),
+			licverify.WithHTTPClient(ts.Client()),
+		}, opts...)
+		client, err := licverify.NewCheckInClient(verifier, ts.URL+"/v1/checkin", opts...)
+		if err != nil {
+			t.Fatalf("Failed to create check-in client: %v", err)
+		}
+		return client
+	}
+	ctx := context.Background()
+
+	if _, err := licverify.NewCheckInClient(verifier, "ftp://example.com"); err == nil {
+		t.Error("Expected error for invalid endpoint")
+	}
+
+	t.Run("online", func(t *testing.T) {
+		client := newClient()
+		if err := client.Verify(ctx, license); err != nil {
+			t.Fatalf("Verification failed: %v", err)
+		}
+		lease := client.Lease()
+		if lease == nil || lease.LicenseID != "LIC-1" || lease.Fingerprint != hex.EncodeToString(fingerprint) {
+			t.Fatalf("Unexpected lease: %+v", lease)
+		}
+		if _, err := os.Stat(cachePath); err != nil {
+			t.Errorf("Lease was not cached: %v", err)
+		}
+
+		// A fresh lease is used without checking in again
+		requests := server.requestCount()
+		if err := client.Verify(ctx, license); err != nil {
+			t.Errorf("Verification failed: %v", err)
+		}
+		if server.requestCount() != requests {
+			t.Error("Checked in again within the check-in interval")
+		}
+	})
+
+	t.Run("offline within window", func(t *testing.T) {
+		server.set(http.StatusServiceUnavailable, licverify.LeaseActive)
+		defer server.set(0, licverify.LeaseActive)
+
+		// A restarted client checks in and falls back to the cached lease
+		client := newClient(licverify.WithCheckInInterval(0))
+		if err := client.Verify(ctx, license); err != nil {
+			t.Errorf("Expected cached lease to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("offline outside window", func(t *testing.T) {
+		server.set(http.StatusServiceUnavailable, licverify.LeaseActive)
+		defer server.set(0, licverify.LeaseActive)
+
+		client := newClient(licverify.WithCheckInInterval(0), licverify.WithOfflineWindow(time.Nanosecond))
+		if err := client.Verify(ctx, license); !errors.Is(err, licverify.ErrCheckInRequired) {
+			t.Errorf("Expected ErrCheckInRequired, got %v", err)
+		}
+	})
+
+	t.Run("offline with tampered cache", func(t *testing.T) {
+		server.set(http.StatusServiceUnavailable, licverify.LeaseActive)
+		defer server.set(0, licverify.LeaseActive)
+
+		tampered := filepath.Join(t.TempDir(), "lease.json")
+		data, err := os.ReadFile(cachePath)
+		if err != nil {
+			t.Fatalf("Failed to read lease cache: %v", err)
+		}
+		var signed licverify.SignedLease
+		if err := json.Unmarshal(data, &signed); err != nil {
+			t.Fatalf("Failed to decode lease cache: %v", err)
+		}
+		signed.Payload[len(signed.Payload)-3] ^= 1
+		data, _ = json.Marshal(signed)
+		if err := os.WriteFile(tampered, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		client := newClient(licverify.WithLeaseCache(tampered))
+		if err := client.Verify(ctx, license); !errors.Is(err, licverify.ErrCheckInRequired) {
+			t.Errorf("Expected ErrCheckInRequired, got %v", err)
+		}
+	})
+
+	t.Run("lease from the future", func(t *testing.T) {
+		server.set(http.StatusServiceUnavailable, licverify.LeaseActive)
+		defer server.set(0, licverify.LeaseActive)
+
+		future := filepath.Join(t.TempDir(), "lease.json")
+		issued := time.Now().Add(48 * time.Hour)
+		signed, err := licgen.SignLease(&licverify.Lease{
+			LicenseID:   "LIC-1",
+			Fingerprint: hex.EncodeToString(fingerprint),
+			Status:      licverify.LeaseActive,
+			IssuedAt:    issued,
+			ExpiresAt:   issued.Add(24 * time.Hour),
+		}, privateKey)
+		if err != nil {
+			t.Fatalf("Failed to sign lease: %v", err)
+		}
+		data, _ := json.Marshal(signed)
+		if err := os.WriteFile(future, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		client := newClient(licverify.WithLeaseCache(future), licverify.WithCheckInInterval(0))
+		if err := client.Verify(ctx, license); !errors.Is(err, licverify.ErrCheckInRequired) {
+			t.Errorf("Expected ErrCheckInRequired, got %v", err)
+		}
+	})
+
+	t.Run("replayed lease", func(t *testing.T) {
+		client := newClient(licverify.WithLeaseCache(""))
+		server.mu.Lock()
+		server.nonce = "replayed"
+		server.mu.Unlock()
+		defer func() {
+			server.mu.Lock()
+			server.nonce = ""
+			server.mu.Unlock()
+		}()
+
+		if _, err := client.CheckIn(ctx, license); err == nil {
+			t.Error("Expected error for lease with a different nonce")
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		server.set(http.StatusNotFound, licverify.LeaseActive)
+		defer server.set(0, licverify.LeaseActive)
+
+		client := newClient(licverify.WithCheckInInterval(0))
+		if err := client.Verify(ctx, license); !errors.Is(err, licverify.ErrCheckInRejected) {
+			t.Errorf("Expected ErrCheckInRejected, got %v", err)
+		}
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		server.set(0, licverify.LeaseRevoked)
+		client := newClient(licverify.WithCheckInInterval(0))
+		if err := client.Verify(ctx, license); !errors.Is(err, licverify.ErrLicenseRevoked) {
+			t.Errorf("Expected ErrLicenseRevoked, got %v", err)
+		}
+
+		// The cached revocation applies while offline
+		server.set(http.StatusServiceUnavailable, licverify.LeaseActive)
+		restarted := newClient()
+		if err := restarted.Verify(ctx, license); !errors.Is(err, licverify.ErrLicenseRevoked) {
+			t.Errorf("Expected ErrLicenseRevoked from cache, got %v", err)
+		}
+		offline := newClient(licverify.WithCheckInInterval(0))
+		if err := offline.Verify(ctx, license); !errors.Is(err, licverify.ErrLicenseRevoked) {
+			t.Errorf("Expected ErrLicenseRevoked while offline, got %v", err)
+		}
+	})
+
+	t.Run("reinstated", func(t *testing.T) {
+		server.set(0, licverify.LeaseRevoked)
+		client := newClient(licverify.WithCheckInInterval(time.Hour))
+		if _, err := client.CheckIn(ctx, license); !errors.Is(err, licverify.ErrLicenseRevoked) {
+			t.Fatalf("Expected ErrLicenseRevoked, got %v", err)
+		}
+
+		// Within the interval the revoked lease is used without checking in
+		server.set(0, licverify.LeaseActive)
+		requests := server.requestCount()
+		if err := client.Verify(ctx, license); !errors.Is(err, licverify.ErrLicenseRevoked) {
+			t.Errorf("Expected ErrLicenseRevoked within the interval, got %v", err)
+		}
+		if server.requestCount() != requests {
+			t.Error("Expected no check-in within the interval")
+		}
+
+		// After the interval the client checks in again and recovers
+		restarted := newClient(licverify.WithCheckInInterval(0))
+		if err := restarted.Verify(ctx, license); err != nil {
+			t.Errorf("Expected the reinstated license to verify, got %v", err)
+		}
+		if lease := restarted.Lease(); lease == nil || lease.Status != licverify.LeaseActive {
+			t.Errorf("Expected an active lease, got %+v", lease)
+		}
+	})
+}
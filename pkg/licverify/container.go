@@ -0,0 +1,91 @@
+package licverify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+)
+
+// Container ID kinds. A container ID is "kind:value", for example
+// "k8s-cluster:6f1c2a4e-0b7d-4c55-9a63-2d8e1f0c3b9a". Container IDs bind
+// licenses to environments where MAC addresses, hostnames and disks are
+// ephemeral, see HardwareBinding.ContainerIDs.
+const (
+	// ContainerK8sCluster identifies a Kubernetes cluster by a UID, usually
+	// the UID of its kube-system namespace
+	ContainerK8sCluster = "k8s-cluster"
+	// ContainerK8sNamespace identifies a Kubernetes namespace by its UID
+	ContainerK8sNamespace = "k8s-namespace"
+	// ContainerIdentity identifies an identity file, such as a mounted
+	// secret, by the hex SHA-256 digest of its contents
+	ContainerIdentity = "identity"
+)
+
+// ContainerPaths are the files that container IDs are read from, relative
+// to the file system root of the hardware provider. Empty paths are
+// skipped, as are missing and empty files.
+type ContainerPaths struct {
+	// ClusterUID holds the Kubernetes cluster UID
+	ClusterUID string
+	// NamespaceUID holds the Kubernetes namespace UID
+	NamespaceUID string
+	// IdentityFile is an identity file whose contents are hashed
+	IdentityFile string
+}
+
+// DefaultContainerPaths returns the default container ID files, below
+// /etc/go-license where a ConfigMap or Secret can be mounted
+func DefaultContainerPaths() ContainerPaths {
+	return ContainerPaths{
+		ClusterUID:   "etc/go-license/k8s-cluster-uid",
+		NamespaceUID: "etc/go-license/k8s-namespace-uid",
+		IdentityFile: "etc/go-license/identity",
+	}
+}
+
+// WithContainerPaths sets the files container IDs are read from. The
+// default is DefaultContainerPaths().
+func WithContainerPaths(paths ContainerPaths) HardwareOption {
+	return func(p *HardwareProvider) {
+		p.containerPaths = paths
+	}
+}
+
+// ContainerID returns the container ID of the given kind and value
+func ContainerID(kind, value string) string {
+	return kind + ":" + value
+}
+
+// IdentityFileID returns the container ID of an identity file with the
+// given contents, for binding a license to the file before it is deployed
+func IdentityFileID(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return ContainerID(ContainerIdentity, hex.EncodeToString(sum[:]))
+}
+
+// containerIDs reads the container IDs of this environment
+func (p *HardwareProvider) containerIDs() []string {
+	var ids []string
+	for _, file := range []struct {
+		kind string
+		path string
+	}{
+		{ContainerK8sCluster, p.containerPaths.ClusterUID},
+		{ContainerK8sNamespace, p.containerPaths.NamespaceUID},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if value := p.readValue(file.path); value != "" {
+			ids = append(ids, ContainerID(file.kind, value))
+		}
+	}
+
+	if p.containerPaths.IdentityFile != "" {
+		contents, err := os.ReadFile(p.path(p.containerPaths.IdentityFile))
+		if err == nil && len(contents) > 0 {
+			ids = append(ids, IdentityFileID(contents))
+		}
+	}
+	return ids
+}
@@ -0,0 +1,96 @@
+package licverify_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+func TestContainerBinding(t *testing.T) {
+	const clusterUID = "6f1c2a4e-0b7d-4c55-9a63-2d8e1f0c3b9a"
+	identity := []byte("tenant: acme\ntoken: 3b9a6f1c\n")
+
+	// A ConfigMap with the cluster UID and a Secret with the identity file
+	root := t.TempDir()
+	for name, content := range map[string][]byte{
+		"etc/go-license/k8s-cluster-uid": []byte(clusterUID + "\n"),
+		"var/run/secrets/license/id":     identity,
+		"proc/sys/kernel/hostname":       []byte("pod-7d9f\n"),
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths := licverify.DefaultContainerPaths()
+	paths.IdentityFile = "var/run/secrets/license/id"
+	provider := licverify.NewHardwareProvider(licverify.WithFileSystemRoot(root), licverify.WithContainerPaths(paths))
+
+	hwInfo, err := provider.Collect()
+	if err != nil {
+		t.Fatalf("Failed to collect hardware info: %v", err)
+	}
+	want := []string{
+		licverify.ContainerID(licverify.ContainerK8sCluster, clusterUID),
+		licverify.IdentityFileID(identity),
+	}
+	if !reflect.DeepEqual(hwInfo.ContainerIDs, want) {
+		t.Fatalf("Expected container IDs %v, got %v", want, hwInfo.ContainerIDs)
+	}
+
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey, licverify.WithHardwareProvider(provider))
+
+	tests := []struct {
+		name         string
+		containerIDs []string
+		valid        bool
+	}{
+		{"Cluster", []string{"k8s-cluster:" + clusterUID}, true},
+		{"IdentityFile", []string{licverify.IdentityFileID(identity)}, true},
+		{"AnyOf", []string{"k8s-cluster:other", "k8s-namespace:other", licverify.IdentityFileID(identity)}, true},
+		{"OtherCluster", []string{"k8s-cluster:0b7d6f1c"}, false},
+		{"OtherNamespace", []string{"k8s-namespace:" + clusterUID}, false},
+		{"OtherIdentity", []string{licverify.IdentityFileID([]byte("tenant: other\n"))}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := licgen.GenerateLicense("LIC-1", "ACME", "APP", "SN-1", 24*time.Hour, []string{"basic"},
+				licverify.HardwareBinding{ContainerIDs: tt.containerIDs}, privateKey)
+			if err != nil {
+				t.Fatalf("Failed to generate license: %v", err)
+			}
+			license, err := verifier.ParseLicense(data)
+			if err != nil {
+				t.Fatalf("Failed to parse license: %v", err)
+			}
+			if !reflect.DeepEqual(license.HardwareIDs().ContainerIDs, tt.containerIDs) {
+				t.Errorf("Expected container IDs %v, got %v", tt.containerIDs, license.HardwareIDs().ContainerIDs)
+			}
+
+			err = license.IsValid(verifier)
+			if tt.valid && err != nil {
+				t.Errorf("License validation failed: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected container ID mismatch")
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package licverify
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrEmbeddedKeyTampered is returned by NewVerifierFromEmbedded when the
+// embedded public key does not match the pin passed by the caller, because
+// its shares were corrupted, patched or replaced
+var ErrEmbeddedKeyTampered = errors.New("embedded public key does not match its pin")
+
+// keyPinContext separates the key pin from other digests of the key
+const keyPinContext = "go-license public key pin v1\n"
+
+// EmbeddedKey is a public key embedded in the application source, usually
+// generated by licforge embed-key. The DER-encoded key is split into random
+// shares that are combined by XOR, so that neither the PEM block nor the
+// DER encoding appears in the binary, and the key cannot be replaced with
+// -ldflags -X. The generated file holds no checksum: the key is checked
+// against a pin that the caller keeps in its own code, see PublicKeyPin, so
+// replacing the key means patching both the shares and the caller. This
+// raises the effort of swapping the key; it cannot stop an attacker who
+// patches the verification code itself.
+type EmbeddedKey struct {
+	// Shares combine by XOR to the DER-encoded public key
+	Shares [][]byte
+}
+
+// PublicKeyPin returns the pin of a PEM-encoded RSA public key, the
+// hex-encoded SHA-256 digest of its DER encoding, for NewVerifierFromEmbedded
+func PublicKeyPin(publicKeyPEM string) (string, error) {
+	der, err := publicKeyDER(publicKeyPEM)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(keyPin(der)), nil
+}
+
+// NewEmbeddedKey splits a PEM-encoded RSA public key into the given number
+// of shares, at least two
+func NewEmbeddedKey(publicKeyPEM string, shares int) (*EmbeddedKey, error) {
+	if shares < 2 {
+		return nil, errors.New("an embedded key needs at least two shares")
+	}
+	der, err := publicKeyDER(publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &EmbeddedKey{}
+	last := append([]byte(nil), der...)
+	for range shares - 1 {
+		share := make([]byte, len(der))
+		if _, err := rand.Read(share); err != nil {
+			return nil, fmt.Errorf("failed to generate key share: %v", err)
+		}
+		subtle.XORBytes(last, last, share)
+		key.Shares = append(key.Shares, share)
+	}
+	key.Shares = append(key.Shares, last)
+	return key, nil
+}
+
+// NewVerifierFromEmbedded creates a verifier for an embedded public key. The
+// pin, as returned by PublicKeyPin or printed by licforge embed-key, belongs
+// in the caller's code rather than in the generated file. It returns
+// ErrEmbeddedKeyTampered if the combined key does not match the pin.
+func NewVerifierFromEmbedded(key *EmbeddedKey, pin string, opts ...Option) (*Verifier, error) {
+	want, err := hex.DecodeString(pin)
+	if err != nil || len(want) != sha256.Size {
+		return nil, fmt.Errorf("invalid public key pin %q", pin)
+	}
+	if len(key.Shares) < 2 {
+		return nil, fmt.Errorf("%w: missing key shares", ErrEmbeddedKeyTampered)
+	}
+	der := make([]byte, len(key.Shares[0]))
+	for _, share := range key.Shares {
+		if len(share) != len(der) {
+			return nil, fmt.Errorf("%w: key shares differ in length", ErrEmbeddedKeyTampered)
+		}
+		subtle.XORBytes(der, der, share)
+	}
+	if subtle.ConstantTimeCompare(keyPin(der), want) != 1 {
+		return nil, ErrEmbeddedKeyTampered
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse embedded public key: %v", err)
+	}
+	publicKey, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("embedded key is not an RSA public key")
+	}
+	return NewVerifierFromPublicKey(publicKey, opts...), nil
+}
+
+// publicKeyDER returns the DER encoding of a PEM-encoded RSA public key
+func publicKeyDER(publicKeyPEM string) ([]byte, error) {
+	verifier, err := NewVerifier(publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(verifier.publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %v", err)
+	}
+	return der, nil
+}
+
+// keyPin returns the pin of a DER-encoded public key
+func keyPin(der []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(keyPinContext))
+	h.Write(der)
+	return h.Sum(nil)
+}
@@ -0,0 +1,85 @@
+package licverify_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+func TestEmbeddedKey(t *testing.T) {
+	privateKeyPEM, publicKeyPEM, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	data, err := licgen.GenerateLicense("LIC-1", "ACME", "APP", "SN-1", 24*time.Hour, []string{"basic"},
+		licverify.HardwareBinding{}, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to generate license: %v", err)
+	}
+
+	if _, err := licverify.NewEmbeddedKey(publicKeyPEM, 1); err == nil {
+		t.Error("Expected an error for a single share")
+	}
+	key, err := licverify.NewEmbeddedKey(publicKeyPEM, 3)
+	if err != nil {
+		t.Fatalf("Failed to embed key: %v", err)
+	}
+	if len(key.Shares) != 3 {
+		t.Fatalf("Expected 3 shares, got %d", len(key.Shares))
+	}
+
+	pin, err := licverify.PublicKeyPin(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to compute key pin: %v", err)
+	}
+
+	verifier, err := licverify.NewVerifierFromEmbedded(key, pin)
+	if err != nil {
+		t.Fatalf("Failed to create verifier from embedded key: %v", err)
+	}
+	license, err := verifier.ParseLicense(data)
+	if err != nil {
+		t.Fatalf("Failed to parse license: %v", err)
+	}
+	if err := license.IsValid(verifier); err != nil {
+		t.Errorf("License validation failed: %v", err)
+	}
+
+	// Shares that were swapped, patched or cut do not match the pin
+	_, otherPublicKeyPEM, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	other, err := licverify.NewEmbeddedKey(otherPublicKeyPEM, 3)
+	if err != nil {
+		t.Fatalf("Failed to embed key: %v", err)
+	}
+
+	patched := &licverify.EmbeddedKey{Shares: [][]byte{append([]byte(nil), key.Shares[0]...), key.Shares[1], key.Shares[2]}}
+	patched.Shares[0][40] ^= 0x01
+	for name, tampered := range map[string]*licverify.EmbeddedKey{
+		"SwappedKey":   other,
+		"PatchedShare": patched,
+		"MissingShare": {Shares: key.Shares[:2]},
+		"ShortShare":   {Shares: [][]byte{key.Shares[0][:10], key.Shares[1][:10]}},
+		"SingleShare":  {Shares: key.Shares[:1]},
+	} {
+		_, err := licverify.NewVerifierFromEmbedded(tampered, pin)
+		if !errors.Is(err, licverify.ErrEmbeddedKeyTampered) {
+			t.Errorf("%s: expected ErrEmbeddedKeyTampered, got %v", name, err)
+		}
+	}
+
+	for _, invalid := range []string{"", "not hex", pin[:32]} {
+		if _, err := licverify.NewVerifierFromEmbedded(key, invalid); err == nil || errors.Is(err, licverify.ErrEmbeddedKeyTampered) {
+			t.Errorf("Expected an invalid pin error for %q, got %v", invalid, err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package licverify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FingerprintFormat selects the encoding of an exported fingerprint
+type FingerprintFormat int
+
+const (
+	// FingerprintJSON is indented JSON that customers can read before
+	// sending it
+	FingerprintJSON FingerprintFormat = iota
+	// FingerprintCompact is a single line code that can be pasted into an
+	// email or web form. It carries a checksum keyed with the product's
+	// public key, so that mistyped codes and codes for another product are
+	// rejected. It is not signed: anyone with the public key can compute the
+	// checksum, so it proves nothing about who created the code.
+	FingerprintCompact
+)
+
+// compactFingerprintPrefix starts compact fingerprint codes
+const compactFingerprintPrefix = "GLFP1."
+
+// fingerprintChecksumContext separates the checksum key of compact codes
+// from other keys derived from the public key
+const fingerprintChecksumContext = "go-license fingerprint request v1\n"
+
+// maxFingerprintSize bounds the size of fingerprints accepted by ParseFingerprint
+const maxFingerprintSize = 64 << 10
+
+// ErrInvalidFingerprint is returned for fingerprints that cannot be parsed
+// or whose checksum does not match
+var ErrInvalidFingerprint = errors.New("invalid fingerprint")
+
+// Fingerprint holds the identifiers of a machine that a license can be
+// bound to, exported on the customer's machine with ExportFingerprint and
+// read by the vendor with ParseFingerprint. MachineID is only used to bind
+// license contents to the machine, see HardwareInfo.Fingerprint.
+type Fingerprint struct {
+	MACAddresses []string  `json:"mac_addresses,omitempty"`
+	DiskIDs      []string  `json:"disk_ids,omitempty"`
+	Hostname     string    `json:"hostname,omitempty"`
+	MachineID    string    `json:"machine_id,omitempty"`
+	ContainerIDs []string  `json:"container_ids,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ExportFingerprint collects the hardware information of this machine with
+// the verifier's hardware provider and encodes it in the given format, so
+// that a license can be bound to the machine without running licforge on
+// it with the private key. The verifier may be nil for FingerprintJSON, in
+// which case the default hardware provider is used.
+func ExportFingerprint(verifier *Verifier, format FingerprintFormat) ([]byte, error) {
+	if verifier == nil && format == FingerprintCompact {
+		return nil, errors.New("compact fingerprints require a verifier to compute their checksum")
+	}
+
+	var hwInfo *HardwareInfo
+	var err error
+	if verifier != nil {
+		hwInfo, err = verifier.hardwareInfo()
+	} else {
+		hwInfo, err = GetHardwareInfo()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hardware info: %v", err)
+	}
+
+	fingerprint := &Fingerprint{
+		MACAddresses: hwInfo.MACAddresses,
+		DiskIDs:      hwInfo.DiskIDs,
+		Hostname:     hwInfo.Hostname,
+		MachineID:    hwInfo.MachineID,
+		ContainerIDs: hwInfo.ContainerIDs,
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+	}
+
+	switch format {
+	case FingerprintJSON:
+		data, err := json.MarshalIndent(fingerprint, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode fingerprint: %v", err)
+		}
+		return append(data, '\n'), nil
+	case FingerprintCompact:
+		payload, err := json.Marshal(fingerprint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode fingerprint: %v", err)
+		}
+		checksum, err := fingerprintChecksum(verifier, payload)
+		if err != nil {
+			return nil, err
+		}
+		code := compactFingerprintPrefix + base64.RawURLEncoding.EncodeToString(payload) +
+			"." + base64.RawURLEncoding.EncodeToString(checksum)
+		return []byte(code + "\n"), nil
+	default:
+		return nil, fmt.Errorf("unsupported fingerprint format: %d", format)
+	}
+}
+
+// ParseFingerprint reads a fingerprint in either format. The checksum of
+// compact codes is checked with the verifier's public key. It only detects
+// mistyped codes and codes for another product; a code that passes may
+// still have been created by anyone with the public key.
+func ParseFingerprint(verifier *Verifier, data []byte) (*Fingerprint, error) {
+	if len(data) > maxFingerprintSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds the maximum of %d", ErrInvalidFingerprint, len(data), maxFingerprintSize)
+	}
+	data = bytes.TrimSpace(data)
+
+	payload := data
+	if code, ok := strings.CutPrefix(string(data), compactFingerprintPrefix); ok {
+		if verifier == nil {
+			return nil, errors.New("compact fingerprints require a verifier to check their checksum")
+		}
+		encodedPayload, encodedChecksum, ok := strings.Cut(code, ".")
+		if !ok {
+			return nil, fmt.Errorf("%w: code has no checksum", ErrInvalidFingerprint)
+		}
+		var err error
+		payload, err = base64.RawURLEncoding.DecodeString(encodedPayload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFingerprint, err)
+		}
+		checksum, err := base64.RawURLEncoding.DecodeString(encodedChecksum)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFingerprint, err)
+		}
+		expected, err := fingerprintChecksum(verifier, payload)
+		if err != nil {
+			return nil, err
+		}
+		if !hmac.Equal(checksum, expected) {
+			return nil, fmt.Errorf("%w: checksum mismatch, the code is mistyped or for another product", ErrInvalidFingerprint)
+		}
+	}
+
+	var fingerprint Fingerprint
+	if err := json.Unmarshal(payload, &fingerprint); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFingerprint, err)
+	}
+	if len(fingerprint.MACAddresses) == 0 && len(fingerprint.DiskIDs) == 0 &&
+		fingerprint.Hostname == "" && fingerprint.MachineID == "" && len(fingerprint.ContainerIDs) == 0 {
+		return nil, fmt.Errorf("%w: no identifiers", ErrInvalidFingerprint)
+	}
+	return &fingerprint, nil
+}
+
+// HardwareBinding returns a hardware binding to all identifiers of the
+// fingerprint, as licforge genlicense -auto-hardware creates on the machine
+func (f *Fingerprint) HardwareBinding() HardwareBinding {
+	binding := HardwareBinding{
+		MACAddresses: cloneStrings(f.MACAddresses),
+		DiskIDs:      cloneStrings(f.DiskIDs),
+		ContainerIDs: cloneStrings(f.ContainerIDs),
+	}
+	if f.Hostname != "" {
+		binding.HostNames = []string{f.Hostname}
+	}
+	return binding
+}
+
+// HardwareInfo returns the hardware information of the fingerprint, for
+// example to bind license contents to the machine with
+// licgen.WithMachineBinding
+func (f *Fingerprint) HardwareInfo() *HardwareInfo {
+	return &HardwareInfo{
+		MACAddresses: cloneStrings(f.MACAddresses),
+		DiskIDs:      cloneStrings(f.DiskIDs),
+		Hostname:     f.Hostname,
+		MachineID:    f.MachineID,
+		ContainerIDs: cloneStrings(f.ContainerIDs),
+	}
+}
+
+// fingerprintChecksum computes the checksum of a compact fingerprint code
+// payload. It is an HMAC keyed from the public key, which anyone can derive,
+// so it detects corruption but does not authenticate the code.
+func fingerprintChecksum(verifier *Verifier, payload []byte) ([]byte, error) {
+	key, err := deriveStateKey(verifier, fingerprintChecksumContext, nil)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
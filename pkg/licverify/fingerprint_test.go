@@ -0,0 +1,99 @@
+package licverify_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+func TestExportFingerprint(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+	hwInfo, err := licverify.GetHardwareInfo()
+	if err != nil {
+		t.Fatalf("Failed to get hardware info: %v", err)
+	}
+
+	for _, format := range []licverify.FingerprintFormat{licverify.FingerprintJSON, licverify.FingerprintCompact} {
+		data, err := licverify.ExportFingerprint(verifier, format)
+		if err != nil {
+			t.Fatalf("Failed to export fingerprint: %v", err)
+		}
+		if format == licverify.FingerprintCompact && bytes.Count(bytes.TrimSpace(data), []byte("\n")) != 0 {
+			t.Errorf("Compact fingerprint spans several lines: %q", data)
+		}
+
+		// The vendor reads the fingerprint and binds a license to it
+		fingerprint, err := licverify.ParseFingerprint(verifier, data)
+		if err != nil {
+			t.Fatalf("Failed to parse fingerprint: %v", err)
+		}
+		if fingerprint.Hostname != hwInfo.Hostname || !reflect.DeepEqual(fingerprint.MACAddresses, hwInfo.MACAddresses) {
+			t.Errorf("Fingerprint %+v does not match hardware info %+v", fingerprint, hwInfo)
+		}
+
+		licenseData, err := licgen.GenerateLicense("LIC-1", "ACME", "APP", "SN-1", 24*time.Hour, []string{"basic"},
+			fingerprint.HardwareBinding(), privateKey, licgen.WithMachineBinding(fingerprint.HardwareInfo()))
+		if err != nil {
+			t.Fatalf("Failed to generate license: %v", err)
+		}
+		license, err := verifier.ParseLicense(licenseData)
+		if err != nil {
+			t.Fatalf("Failed to parse machine-bound license: %v", err)
+		}
+		if err := license.IsValid(verifier); err != nil {
+			t.Errorf("License bound to the fingerprint is not valid: %v", err)
+		}
+	}
+
+	// Compact codes carry a checksum for the product's public key
+	compact, err := licverify.ExportFingerprint(verifier, licverify.FingerprintCompact)
+	if err != nil {
+		t.Fatalf("Failed to export fingerprint: %v", err)
+	}
+	otherKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	otherKey, err := licgen.ParsePrivateKey(otherKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	if _, err := licverify.ParseFingerprint(licverify.NewVerifierFromPublicKey(&otherKey.PublicKey), compact); !errors.Is(err, licverify.ErrInvalidFingerprint) {
+		t.Errorf("Expected ErrInvalidFingerprint for another product, got %v", err)
+	}
+
+	// An edited code keeps the original checksum
+	payload, err := json.Marshal(licverify.Fingerprint{Hostname: "other-host"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum := compact[bytes.LastIndexByte(compact, '.'):]
+	edited := append([]byte("GLFP1."+base64.RawURLEncoding.EncodeToString(payload)), checksum...)
+
+	for _, data := range [][]byte{
+		edited,
+		[]byte("GLFP1.e30"),
+		[]byte("{}"),
+		[]byte("not a fingerprint"),
+		bytes.Repeat(payload, 10000),
+	} {
+		if _, err := licverify.ParseFingerprint(verifier, data); !errors.Is(err, licverify.ErrInvalidFingerprint) {
+			t.Errorf("Expected ErrInvalidFingerprint for %.40q, got %v", data, err)
+		}
+	}
+}
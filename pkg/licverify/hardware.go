@@ -2,12 +2,16 @@ package licverify
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 )
 
@@ -17,67 +21,285 @@ type HardwareInfo struct {
 	DiskIDs      []string
 	Hostname     string
 	CPUInfo      string
+
+	// MachineID is the operating system's machine ID: /etc/machine-id, the
+	// Windows MachineGuid or the macOS IOPlatformUUID. When it is set, it
+	// determines the fingerprint together with the disk IDs.
+	MachineID string
+
+	// ContainerIDs identify the container environment, see ContainerPaths.
+	// They are not part of the fingerprint.
+	ContainerIDs []string
+}
+
+// HardwareOption configures optional behaviour of a HardwareProvider
+type HardwareOption func(*HardwareProvider)
+
+// WithFileSystemRoot reads Linux hardware information below root instead
+// of "/", for example from a fixture tree in tests or from the host file
+// system mounted into a container
+func WithFileSystemRoot(root string) HardwareOption {
+	return func(p *HardwareProvider) {
+		p.root = root
+	}
+}
+
+// HardwareProvider collects hardware information. On Linux it reads sysfs,
+// procfs and the udev database directly instead of running commands. The
+// DMI product UUID (/sys/class/dmi/id/product_uuid) is deliberately not
+// collected: it is only readable by root, so verification would depend on
+// the user the application runs as. /etc/machine-id identifies the machine
+// instead.
+type HardwareProvider struct {
+	root           string
+	goos           string
+	macFilter      MACFilter
+	containerPaths ContainerPaths
+	interfaces     func() ([]net.Interface, error)
+}
+
+// NewHardwareProvider creates a hardware provider for the running system
+func NewHardwareProvider(opts ...HardwareOption) *HardwareProvider {
+	p := &HardwareProvider{
+		root:           "/",
+		goos:           runtime.GOOS,
+		macFilter:      DefaultMACFilter(),
+		containerPaths: DefaultContainerPaths(),
+		interfaces:     net.Interfaces,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // GetHardwareInfo collects hardware information from the system
 func GetHardwareInfo() (*HardwareInfo, error) {
-	macs, err := getMACAddresses()
+	return NewHardwareProvider().Collect()
+}
+
+// Collect collects hardware information
+func (p *HardwareProvider) Collect() (*HardwareInfo, error) {
+	macs, err := p.macAddresses()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get MAC addresses: %v", err)
 	}
 
-	diskIDs, err := getDiskIDs()
+	diskIDs, err := p.diskIDs()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get disk IDs: %v", err)
 	}
 
-	hostname, err := os.Hostname()
+	hostname, err := p.hostname()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get hostname: %v", err)
 	}
 
-	cpuInfo, err := getCPUInfo()
+	cpuInfo, err := p.cpuInfo()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get CPU info: %v", err)
 	}
 
-	return &HardwareInfo{
+	info := &HardwareInfo{
 		MACAddresses: macs,
 		DiskIDs:      diskIDs,
 		Hostname:     hostname,
 		CPUInfo:      cpuInfo,
-	}, nil
+		ContainerIDs: p.containerIDs(),
+	}
+	switch p.goos {
+	case "linux":
+		info.MachineID = p.readValue("etc/machine-id")
+		if info.MachineID == "" {
+			info.MachineID = p.readValue("var/lib/dbus/machine-id")
+		}
+	case "windows":
+		info.MachineID = getWindowsMachineID()
+	case "darwin":
+		info.MachineID = getMacOSMachineID()
+	}
+	return info, nil
 }
 
-// getMACAddresses returns all non-loopback MAC addresses
-func getMACAddresses() ([]string, error) {
-	interfaces, err := net.Interfaces()
+// fingerprintVersion is the version of the Fingerprint input. It changes
+// whenever the identifiers that go into the fingerprint change.
+const fingerprintVersion byte = 2
+
+// Fingerprint returns a stable digest of the machine identifiers used to
+// bind license contents to this machine. It is derived from the operating
+// system's machine ID (/etc/machine-id on Linux, MachineGuid on Windows,
+// IOPlatformUUID on macOS) and the fixed disk IDs, so that clones of one
+// VM or container image, which share the machine ID, differ when their
+// disks have different serial numbers. Without a machine ID it falls back
+// to the MAC addresses and disk IDs. The hostname is not used. Reinstalling
+// the operating system or replacing a disk changes the fingerprint, and
+// machine-bound licenses must then be reissued.
+//
+// None of the identifiers are secret: the machine ID and disk serials are
+// readable by local users, and the fallback identifiers are often visible
+// from outside the machine. The fingerprint keeps a copied license from
+// being read elsewhere, not from someone who knows the machine.
+func (hw *HardwareInfo) Fingerprint() []byte {
+	h := sha256.New()
+	h.Write([]byte("go-license machine fingerprint\n"))
+	h.Write([]byte{fingerprintVersion})
+	if machineID := strings.ToLower(strings.TrimSpace(hw.MachineID)); machineID != "" {
+		fmt.Fprintf(h, "machine-id=%s\n", machineID)
+		writeFingerprintParts(h, []fingerprintPart{{"disk", hw.DiskIDs}})
+		return h.Sum(nil)
+	}
+	writeFingerprintParts(h, []fingerprintPart{
+		{"mac", lowerStrings(hw.MACAddresses)},
+		{"disk", hw.DiskIDs},
+	})
+	return h.Sum(nil)
+}
+
+// fingerprintPart is a labelled list of fingerprint identifiers
+type fingerprintPart struct {
+	label  string
+	values []string
+}
+
+// writeFingerprintParts writes identifiers in sorted order, so that their
+// order does not matter
+func writeFingerprintParts(w io.Writer, parts []fingerprintPart) {
+	for _, part := range parts {
+		values := append([]string(nil), part.values...)
+		sort.Strings(values)
+		for _, value := range values {
+			fmt.Fprintf(w, "%s=%s\n", part.label, strings.TrimSpace(value))
+		}
+	}
+}
+
+// lowerStrings returns a lower case copy of values
+func lowerStrings(values []string) []string {
+	lower := make([]string, 0, len(values))
+	for _, value := range values {
+		lower = append(lower, strings.ToLower(value))
+	}
+	return lower
+}
+
+// MACFilter selects the network interfaces whose MAC addresses identify
+// the machine. Start from DefaultMACFilter and adjust it.
+type MACFilter struct {
+	// IgnorePrefixes are interface name prefixes of virtual and ephemeral
+	// interfaces that are never used
+	IgnorePrefixes []string
+	// PhysicalOnly skips interfaces that sysfs reports as virtual. It only
+	// applies on Linux.
+	PhysicalOnly bool
+	// IncludeDown includes interfaces that are down when sysfs reports them
+	// as physical, so that disabling a NIC or Wi-Fi does not change the
+	// MAC addresses. Elsewhere only interfaces that are up are used.
+	IncludeDown bool
+	// IgnoreLocallyAdministered skips MAC addresses with the locally
+	// administered bit set, such as randomized Wi-Fi addresses. Many virtual
+	// machines only have such addresses, so it is off by default.
+	IgnoreLocallyAdministered bool
+}
+
+// DefaultMACFilter returns the default filter, which uses physical NICs,
+// up or down, and ignores container, VM, VPN and tunnel interfaces
+func DefaultMACFilter() MACFilter {
+	return MACFilter{
+		IgnorePrefixes: []string{
+			"docker", "br-", "veth", "virbr", "vnet", "lxcbr", "lxdbr", // Containers and VMs
+			"cni", "flannel", "cali", "weave", "kube-", "cilium", // Kubernetes
+			"vboxnet", "vmnet", "vEthernet", // Hypervisor host adapters
+			"tun", "tap", "utun", "wg", "tailscale", "zt", "ppp", "ipsec", // VPNs and tunnels
+			"awdl", "llw", "anpi", "bridge", "ap", "gif", "stf", // macOS virtual interfaces
+		},
+		PhysicalOnly: true,
+		IncludeDown:  true,
+	}
+}
+
+// WithMACFilter sets the filter that selects the MAC addresses used. The
+// default is DefaultMACFilter().
+func WithMACFilter(filter MACFilter) HardwareOption {
+	return func(p *HardwareProvider) {
+		p.macFilter = filter
+	}
+}
+
+// macAddresses returns the MAC addresses of the interfaces selected by the
+// MAC filter, sorted. It is not an error when no interface qualifies.
+func (p *HardwareProvider) macAddresses() ([]string, error) {
+	interfaces, err := p.interfaces()
 	if err != nil {
 		return nil, err
 	}
 
 	var macAddresses []string
 	for _, iface := range interfaces {
-		// Only include up, non-loopback interfaces with a valid hardware address
-		if iface.Flags&net.FlagUp != 0 &&
-			iface.Flags&net.FlagLoopback == 0 &&
-			len(iface.HardwareAddr.String()) > 0 {
-			macAddresses = append(macAddresses, iface.HardwareAddr.String())
+		if !p.useInterface(iface) {
+			continue
+		}
+		mac := iface.HardwareAddr.String()
+		if !contains(macAddresses, mac) {
+			macAddresses = append(macAddresses, mac)
+		}
+	}
+	sort.Strings(macAddresses)
+	return macAddresses, nil
+}
+
+// useInterface reports whether the MAC filter selects an interface
+func (p *HardwareProvider) useInterface(iface net.Interface) bool {
+	filter := p.macFilter
+	if iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) == 0 || allZero(iface.HardwareAddr) {
+		return false
+	}
+	for _, prefix := range filter.IgnorePrefixes {
+		if strings.HasPrefix(iface.Name, prefix) {
+			return false
 		}
 	}
+	if filter.IgnoreLocallyAdministered && iface.HardwareAddr[0]&0x02 != 0 {
+		return false
+	}
 
-	if len(macAddresses) == 0 {
-		return nil, errors.New("no valid MAC addresses found")
+	physical, known := p.physicalInterface(iface.Name)
+	if known && !physical && filter.PhysicalOnly {
+		return false
+	}
+	if iface.Flags&net.FlagUp == 0 {
+		return known && physical && filter.IncludeDown
 	}
+	return true
+}
 
-	return macAddresses, nil
+// physicalInterface reports whether sysfs shows a network interface to be
+// backed by a device, and whether sysfs knows the interface at all
+func (p *HardwareProvider) physicalInterface(name string) (physical, known bool) {
+	if p.goos != "linux" {
+		return false, false
+	}
+	if _, err := os.Stat(p.path("sys/class/net", name)); err != nil {
+		return false, false
+	}
+	_, err := os.Stat(p.path("sys/class/net", name, "device"))
+	return err == nil, true
 }
 
-// getDiskIDs returns disk identifiers based on the current OS
-func getDiskIDs() ([]string, error) {
-	switch runtime.GOOS {
+// allZero reports whether a hardware address is all zeros
+func allZero(addr net.HardwareAddr) bool {
+	for _, b := range addr {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// diskIDs returns disk identifiers based on the current OS
+func (p *HardwareProvider) diskIDs() ([]string, error) {
+	switch p.goos {
 	case "linux":
-		return getLinuxDiskIDs()
+		return p.linuxDiskIDs(), nil
 	case "windows":
 		return getWindowsDiskIDs()
 	case "darwin":
@@ -87,45 +309,111 @@ func getDiskIDs() ([]string, error) {
 	}
 }
 
-// getLinuxDiskIDs gets disk serial numbers on Linux
-func getLinuxDiskIDs() ([]string, error) {
-	// Try to get disk serial numbers using lsblk
-	cmd := exec.Command("lsblk", "-no", "SERIAL", "-d")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	err := cmd.Run()
-
+// linuxDiskIDs returns the serial numbers of the whole disks in
+// /sys/block, looked up like lsblk does: the udev database first, then
+// the serial attributes in sysfs. Disks without a serial number, such as
+// loop devices, and removable or USB disks are skipped.
+func (p *HardwareProvider) linuxDiskIDs() []string {
+	entries, err := os.ReadDir(p.path("sys/block"))
 	if err != nil {
-		// Fallback to disk by-id
-		cmd = exec.Command("ls", "-la", "/dev/disk/by-id/")
-		cmd.Stdout = &out
-		err = cmd.Run()
-		if err != nil {
-			return []string{"linux-disk-id-fallback"}, nil
-		}
+		return []string{"linux-disk-id-fallback"}
 	}
 
-	lines := strings.Split(out.String(), "\n")
 	var diskIDs []string
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			diskIDs = append(diskIDs, line)
+	for _, entry := range entries {
+		if p.removableDisk(entry.Name()) {
+			continue
+		}
+		serial := p.udevSerial(entry.Name())
+		if serial == "" {
+			serial = p.readValue("sys/block", entry.Name(), "device", "serial")
+		}
+		if serial == "" {
+			serial = p.readValue("sys/block", entry.Name(), "serial")
+		}
+		if serial != "" && !contains(diskIDs, serial) {
+			diskIDs = append(diskIDs, serial)
 		}
 	}
 
 	if len(diskIDs) == 0 {
-		return []string{"linux-disk-id-fallback"}, nil
+		return []string{"linux-disk-id-fallback"}
 	}
+	return diskIDs
+}
 
-	return diskIDs, nil
+// removableDisk reports whether a block device is removable media or is
+// attached through USB, such as a USB stick or an external drive
+func (p *HardwareProvider) removableDisk(device string) bool {
+	if p.readValue("sys/block", device, "removable") == "1" {
+		return true
+	}
+	resolved, err := filepath.EvalSymlinks(p.path("sys/block", device))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(filepath.ToSlash(resolved), "/usb")
+}
+
+// udevSerial returns the serial number recorded by udev for a block device
+func (p *HardwareProvider) udevSerial(device string) string {
+	number := p.readValue("sys/block", device, "dev")
+	if number == "" {
+		return ""
+	}
+	data, err := os.ReadFile(p.path("run/udev/data", "b"+number))
+	if err != nil {
+		return ""
+	}
+
+	properties := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		// Properties are stored as E:KEY=value
+		property, ok := strings.CutPrefix(line, "E:")
+		if !ok {
+			continue
+		}
+		if key, value, ok := strings.Cut(property, "="); ok {
+			properties[key] = strings.TrimSpace(value)
+		}
+	}
+	if serial := properties["ID_SCSI_SERIAL"]; serial != "" {
+		return serial
+	}
+	return properties["ID_SERIAL_SHORT"]
+}
+
+// hostname returns the hostname, read below the file system root on Linux
+// when a root other than "/" is configured
+func (p *HardwareProvider) hostname() (string, error) {
+	if p.goos == "linux" && p.root != "/" {
+		if hostname := p.readValue("proc/sys/kernel/hostname"); hostname != "" {
+			return hostname, nil
+		}
+		return "", errors.New("hostname not found below " + p.root)
+	}
+	return os.Hostname()
+}
+
+// path returns the path of a file below the file system root
+func (p *HardwareProvider) path(elem ...string) string {
+	return filepath.Join(append([]string{p.root}, elem...)...)
+}
+
+// readValue reads a single value file below the file system root,
+// returning an empty string if it is missing or unreadable
+func (p *HardwareProvider) readValue(elem ...string) string {
+	data, err := os.ReadFile(p.path(elem...))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
 }
 
 // getWindowsDiskIDs gets disk serial numbers on Windows
 func getWindowsDiskIDs() ([]string, error) {
-	// Use wmic to get disk serial numbers
-	cmd := exec.Command("wmic", "diskdrive", "get", "SerialNumber")
+	// Use wmic to get disk serial numbers, skipping USB drives
+	cmd := exec.Command("wmic", "diskdrive", "where", "InterfaceType!='USB'", "get", "SerialNumber")
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	err := cmd.Run()
@@ -190,11 +478,48 @@ func getMacOSDiskIDs() ([]string, error) {
 	return diskIDs, nil
 }
 
-// getCPUInfo gets CPU information
-func getCPUInfo() (string, error) {
-	switch runtime.GOOS {
+// getWindowsMachineID returns the MachineGuid set when Windows is installed
+func getWindowsMachineID() string {
+	cmd := exec.Command("reg", "query", `HKLM\SOFTWARE\Microsoft\Cryptography`, "/v", "MachineGuid")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+
+	// The value line is "MachineGuid    REG_SZ    <guid>"
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "MachineGuid" {
+			return fields[2]
+		}
+	}
+	return ""
+}
+
+// getMacOSMachineID returns the IOPlatformUUID of the Mac
+func getMacOSMachineID() string {
+	cmd := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+
+	// The value line is "IOPlatformUUID" = "<uuid>"
+	for _, line := range strings.Split(out.String(), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok && strings.TrimSpace(key) == `"IOPlatformUUID"` {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
+
+// cpuInfo gets CPU information
+func (p *HardwareProvider) cpuInfo() (string, error) {
+	switch p.goos {
 	case "linux":
-		return getLinuxCPUInfo()
+		return p.linuxCPUInfo()
 	case "windows":
 		return getWindowsCPUInfo()
 	case "darwin":
@@ -204,21 +529,26 @@ func getCPUInfo() (string, error) {
 	}
 }
 
-// getLinuxCPUInfo gets CPU information on Linux
-func getLinuxCPUInfo() (string, error) {
+// linuxCPUInfo gets CPU information on Linux
+func (p *HardwareProvider) linuxCPUInfo() (string, error) {
 	// Read CPU info from /proc/cpuinfo
-	data, err := os.ReadFile("/proc/cpuinfo")
+	data, err := os.ReadFile(p.path("proc/cpuinfo"))
 	if err != nil {
 		return "linux-cpu-fallback", nil
 	}
 
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "model name") || strings.HasPrefix(line, "processor") {
-			parts := strings.Split(line, ":")
-			if len(parts) >= 2 {
-				return strings.TrimSpace(parts[1]), nil
-			}
+	// x86 reports the model name, ARM only the hardware name
+	values := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if ok && value != "" && values[key] == "" {
+			values[key] = value
+		}
+	}
+	for _, key := range []string{"model name", "Hardware"} {
+		if value := values[key]; value != "" {
+			return value, nil
 		}
 	}
 
@@ -0,0 +1,447 @@
+package licverify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
+)
+
+// TestHardwareFingerprint tests that the fingerprint ignores identifier order and MAC case
+func TestHardwareFingerprint(t *testing.T) {
+	hw := &HardwareInfo{
+		MACAddresses: []string{"00:11:22:33:44:55", "aa:bb:cc:dd:ee:ff"},
+		DiskIDs:      []string{"disk-1", "disk-2"},
+		Hostname:     "host1",
+	}
+	reordered := &HardwareInfo{
+		MACAddresses: []string{"AA:BB:CC:DD:EE:FF", "00:11:22:33:44:55"},
+		DiskIDs:      []string{"disk-2", "disk-1"},
+		Hostname:     "host2",
+		CPUInfo:      "ignored",
+	}
+	if !bytes.Equal(hw.Fingerprint(), reordered.Fingerprint()) {
+		t.Errorf("Fingerprint depends on identifier order, MAC case or hostname")
+	}
+
+	other := &HardwareInfo{
+		MACAddresses: hw.MACAddresses,
+		DiskIDs:      []string{"disk-1", "disk-2", "disk-3"},
+	}
+	if bytes.Equal(hw.Fingerprint(), other.Fingerprint()) {
+		t.Errorf("Fingerprint does not depend on the disk IDs without a machine ID")
+	}
+}
+
+// TestMachineIDFingerprint tests that the machine ID and the disk IDs
+// determine the fingerprint when a machine ID is available, so network
+// changes keep it stable and clones of one image with other disks differ
+func TestMachineIDFingerprint(t *testing.T) {
+	hw := &HardwareInfo{
+		MACAddresses: []string{"00:11:22:33:44:55"},
+		DiskIDs:      []string{"disk-1"},
+		Hostname:     "host1",
+		MachineID:    "fed6b2924c424cf1b9a322f606b4de6d",
+	}
+	changed := &HardwareInfo{
+		MACAddresses: []string{"00:11:22:33:44:55", "aa:bb:cc:dd:ee:ff"},
+		DiskIDs:      []string{"disk-1"},
+		Hostname:     "host2",
+		MachineID:    "FED6B2924C424CF1B9A322F606B4DE6D\n",
+	}
+	if !bytes.Equal(hw.Fingerprint(), changed.Fingerprint()) {
+		t.Errorf("Fingerprint changes with the network interfaces or hostname when a machine ID is available")
+	}
+
+	for name, other := range map[string]*HardwareInfo{
+		"Reinstalled": {MACAddresses: hw.MACAddresses, DiskIDs: hw.DiskIDs, Hostname: hw.Hostname, MachineID: "0b7d6f1c3b9a4c559a632d8e1f0c3b9a"},
+		"Clone":       {MACAddresses: hw.MACAddresses, DiskIDs: []string{"disk-2"}, Hostname: hw.Hostname, MachineID: hw.MachineID},
+		"AddedDisk":   {MACAddresses: hw.MACAddresses, DiskIDs: []string{"disk-1", "disk-2"}, Hostname: hw.Hostname, MachineID: hw.MachineID},
+	} {
+		if bytes.Equal(hw.Fingerprint(), other.Fingerprint()) {
+			t.Errorf("%s: fingerprint does not depend on the machine ID and disk IDs", name)
+		}
+	}
+}
+
+// TestFingerprintBinding tests that licenses bound to the fingerprint of
+// the machine open and licenses bound to another machine do not
+func TestFingerprintBinding(t *testing.T) {
+	provider := NewHardwareProvider(WithFileSystemRoot(writeTree(t, map[string]string{
+		"sys/block/vda/serial":     "virtio-disk\n",
+		"etc/machine-id":           "fed6b2924c424cf1b9a322f606b4de6d\n",
+		"proc/sys/kernel/hostname": "host1\n",
+	})))
+	provider.goos = "linux"
+	provider.interfaces = func() ([]net.Interface, error) { return nil, nil }
+	hw, err := provider.Collect()
+	if err != nil {
+		t.Fatalf("Failed to collect hardware info: %v", err)
+	}
+	v := &Verifier{hardware: provider}
+
+	encoded, err := licformat.EncodeLicenseData(&licformat.LicenseData{ID: "LIC-1", Scheme: licformat.SchemePSS})
+	if err != nil {
+		t.Fatalf("Failed to encode license data: %v", err)
+	}
+	sealed, err := licformat.SealLicenseWithFingerprint(encoded, hw.Fingerprint())
+	if err != nil {
+		t.Fatalf("Failed to seal license: %v", err)
+	}
+	opened, err := v.openLicense(sealed)
+	if err != nil {
+		t.Fatalf("Failed to open license: %v", err)
+	}
+	if !bytes.Equal(opened, encoded) {
+		t.Errorf("Opened contents differ from the sealed encoding")
+	}
+
+	sealed, err = licformat.SealLicenseWithFingerprint(encoded, (&HardwareInfo{MachineID: "other"}).Fingerprint())
+	if err != nil {
+		t.Fatalf("Failed to seal license: %v", err)
+	}
+	if _, err := v.openLicense(sealed); !errors.Is(err, ErrMachineBinding) {
+		t.Errorf("Expected ErrMachineBinding, got %v", err)
+	}
+}
+
+// writeTree creates a file system fixture from paths and their contents
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// TestLinuxHardwareProvider tests collection from a sysfs fixture tree
+func TestLinuxHardwareProvider(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		// NVMe exposes the serial number in sysfs, padded with spaces
+		"sys/block/nvme0n1/dev":           "259:0\n",
+		"sys/block/nvme0n1/device/serial": "S4EWNX0R123456      \n",
+		// SATA serial numbers come from the udev database
+		"sys/block/sda/dev":  "8:0\n",
+		"run/udev/data/b8:0": "S:disk/by-id/ata-WDC_WD10-WD-123\nE:ID_SERIAL=WDC_WD10-WD-123\nE:ID_SERIAL_SHORT=WD-123\n",
+		// virtio-blk has the serial number on the block device
+		"sys/block/vda/dev":    "254:0\n",
+		"sys/block/vda/serial": "virtio-disk\n",
+		// Loop devices have no serial number
+		"sys/block/loop0/dev": "7:0\n",
+		// Removable media is skipped
+		"sys/block/sdb/dev":       "8:16\n",
+		"sys/block/sdb/removable": "1\n",
+		"sys/block/sdb/serial":    "usb-stick\n",
+
+		"etc/machine-id":           "fed6b2924c424cf1b9a322f606b4de6d\n",
+		"proc/sys/kernel/hostname": "build-host\n",
+		"proc/cpuinfo":             "processor\t: 0\nvendor_id\t: GenuineIntel\nmodel name\t: Example CPU @ 3.00GHz\n\nprocessor\t: 1\nmodel name\t: Example CPU @ 3.00GHz\n",
+	})
+
+	p := NewHardwareProvider(WithFileSystemRoot(root))
+	p.goos = "linux"
+	info, err := p.Collect()
+	if err != nil {
+		t.Fatalf("Failed to collect hardware info: %v", err)
+	}
+
+	if expected := []string{"S4EWNX0R123456", "WD-123", "virtio-disk"}; !reflect.DeepEqual(info.DiskIDs, expected) {
+		t.Errorf("Expected disk IDs %v, got %v", expected, info.DiskIDs)
+	}
+	if info.Hostname != "build-host" {
+		t.Errorf("Expected hostname build-host, got %q", info.Hostname)
+	}
+	if info.CPUInfo != "Example CPU @ 3.00GHz" {
+		t.Errorf("Expected the CPU model name, got %q", info.CPUInfo)
+	}
+	if info.MachineID != "fed6b2924c424cf1b9a322f606b4de6d" {
+		t.Errorf("Unexpected machine ID %q", info.MachineID)
+	}
+
+	// Missing sources fall back like before
+	empty := NewHardwareProvider(WithFileSystemRoot(writeTree(t, map[string]string{
+		"proc/sys/kernel/hostname": "empty-host",
+		"var/lib/dbus/machine-id":  "dbus-machine-id",
+	})))
+	empty.goos = "linux"
+	info, err = empty.Collect()
+	if err != nil {
+		t.Fatalf("Failed to collect hardware info: %v", err)
+	}
+	if !reflect.DeepEqual(info.DiskIDs, []string{"linux-disk-id-fallback"}) || info.CPUInfo != "linux-cpu-fallback" {
+		t.Errorf("Unexpected fallbacks: %v %q", info.DiskIDs, info.CPUInfo)
+	}
+	if info.MachineID != "dbus-machine-id" {
+		t.Errorf("Unexpected machine ID %q", info.MachineID)
+	}
+
+	// ARM kernels report the hardware instead of a model name
+	arm := NewHardwareProvider(WithFileSystemRoot(writeTree(t, map[string]string{
+		"proc/sys/kernel/hostname": "arm-host",
+		"proc/cpuinfo":             "processor\t: 0\nBogoMIPS\t: 108.00\n\nHardware\t: BCM2835\nRevision\t: c03111\n",
+	})))
+	arm.goos = "linux"
+	if cpu, err := arm.linuxCPUInfo(); err != nil || cpu != "BCM2835" {
+		t.Errorf("Expected the ARM hardware name, got %q, %v", cpu, err)
+	}
+}
+
+// TestRemovableDisks tests that removable media and USB drives are not
+// collected as disk IDs, so plugging in a drive keeps the disk IDs
+func TestRemovableDisks(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"sys/block/sda/dev":           "8:0\n",
+		"sys/block/sda/device/serial": "WD-123\n",
+		// A card reader reports removable media
+		"sys/block/mmcblk0/removable": "1\n",
+		"sys/block/mmcblk0/serial":    "0x1234abcd\n",
+		// An external drive is not removable but hangs off a USB controller
+		"sys/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0/block/sdb/device/serial": "EXT-456\n",
+		// An internal SATA disk below the PCI bus is kept
+		"sys/devices/pci0000:00/0000:00:17.0/ata2/host1/target1:0:0/1:0:0:0/block/sdc/device/serial": "SATA-789\n",
+	})
+	for name, target := range map[string]string{
+		"sdb": "../devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0/block/sdb",
+		"sdc": "../devices/pci0000:00/0000:00:17.0/ata2/host1/target1:0:0/1:0:0:0/block/sdc",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, "sys/block", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := NewHardwareProvider(WithFileSystemRoot(root))
+	p.goos = "linux"
+	if diskIDs, expected := p.linuxDiskIDs(), []string{"WD-123", "SATA-789"}; !reflect.DeepEqual(diskIDs, expected) {
+		t.Errorf("Expected disk IDs %v, got %v", expected, diskIDs)
+	}
+}
+
+// TestLinuxFingerprintStability tests that the fingerprint of a Linux
+// machine survives changes that do not replace its hardware, and pins the
+// fingerprint of a fixture so that changes to hardware collection that
+// would break machine-bound licenses are caught
+func TestLinuxFingerprintStability(t *testing.T) {
+	base := map[string]string{
+		"sys/block/nvme0n1/dev":            "259:0\n",
+		"sys/block/nvme0n1/device/serial":  "S4EWNX0R123456      \n",
+		"sys/block/sda/dev":                "8:0\n",
+		"run/udev/data/b8:0":               "E:ID_SERIAL=WDC_WD10-WD-123\nE:ID_SERIAL_SHORT=WD-123\n",
+		"sys/class/net/eth0/device/vendor": "0x8086\n",
+		"proc/sys/kernel/hostname":         "build-host\n",
+		"proc/cpuinfo":                     "processor\t: 0\nmodel name\t: Example CPU @ 3.00GHz\n",
+	}
+	eth0, err := net.ParseMAC("00:1b:21:00:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collect := func(t *testing.T, changes map[string]string) *HardwareInfo {
+		t.Helper()
+		files := make(map[string]string)
+		for name, content := range base {
+			files[name] = content
+		}
+		for name, content := range changes {
+			if content == "" {
+				delete(files, name)
+			} else {
+				files[name] = content
+			}
+		}
+		p := NewHardwareProvider(WithFileSystemRoot(writeTree(t, files)))
+		p.goos = "linux"
+		p.interfaces = func() ([]net.Interface, error) {
+			return []net.Interface{{Name: "eth0", HardwareAddr: eth0, Flags: net.FlagUp}}, nil
+		}
+		info, err := p.Collect()
+		if err != nil {
+			t.Fatalf("Failed to collect hardware info: %v", err)
+		}
+		return info
+	}
+
+	// Pinned value: a change here breaks licenses bound to existing machines
+	info := collect(t, nil)
+	if got := fmt.Sprintf("%x", info.Fingerprint()); got != "cf35c1296442d1b3cd4d3ecc50077fb63189de94cd4b4616b6b94005496ffd00" {
+		t.Errorf("Fingerprint of the fixture changed to %s", got)
+	}
+
+	for name, changes := range map[string]map[string]string{
+		"Hostname":     {"proc/sys/kernel/hostname": "renamed-host\n"},
+		"CPU":          {"proc/cpuinfo": "processor\t: 0\nmodel name\t: Other CPU\n"},
+		"USBStick":     {"sys/block/sdb/dev": "8:16\n", "sys/block/sdb/removable": "1\n", "sys/block/sdb/serial": "usb-stick\n"},
+		"LoopDevice":   {"sys/block/loop0/dev": "7:0\n"},
+		"UdevSerial":   {"run/udev/data/b259:0": "E:ID_SERIAL_SHORT=S4EWNX0R123456\n"},
+		"NoUdevData":   {"run/udev/data/b8:0": "", "sys/block/sda/device/serial": "WD-123\n"},
+		"ContainerIDs": {"var/run/secrets/kubernetes.io/serviceaccount/namespace": "default\n"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if changed := collect(t, changes); !bytes.Equal(changed.Fingerprint(), info.Fingerprint()) {
+				t.Errorf("Fingerprint changed: disks %v, MACs %v", changed.DiskIDs, changed.MACAddresses)
+			}
+		})
+	}
+
+	// A clone of the image shares the machine ID but not the disk serials
+	withID := collect(t, map[string]string{"etc/machine-id": "fed6b2924c424cf1b9a322f606b4de6d\n"})
+	clone := collect(t, map[string]string{
+		"etc/machine-id":     "fed6b2924c424cf1b9a322f606b4de6d\n",
+		"run/udev/data/b8:0": "E:ID_SERIAL_SHORT=WD-456\n",
+	})
+	if bytes.Equal(withID.Fingerprint(), clone.Fingerprint()) {
+		t.Error("Fingerprint of a clone with other disks matches the original")
+	}
+	if bytes.Equal(withID.Fingerprint(), info.Fingerprint()) {
+		t.Error("Fingerprint does not depend on the machine ID")
+	}
+}
+
+// TestMACAddressSelection tests that virtual interfaces are ignored and
+// physical interfaces are used even when they are down
+func TestMACAddressSelection(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"sys/class/net/eth0/device/vendor":      "0x8086\n",
+		"sys/class/net/wlan0/device/vendor":     "0x168c\n",
+		"sys/class/net/bond0/address":           "02:00:00:00:00:0b\n",
+		"sys/class/net/docker0/address":         "02:42:ac:11:00:01\n",
+		"sys/class/net/veth1234/address":        "0a:58:0a:f4:00:01\n",
+		"sys/class/net/enx00e04c/device/uevent": "",
+	})
+	mac := func(s string) net.HardwareAddr {
+		addr, err := net.ParseMAC(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr
+	}
+	interfaces := []net.Interface{
+		{Name: "lo", Flags: net.FlagUp | net.FlagLoopback},
+		{Name: "wlan0", HardwareAddr: mac("DC:A6:32:00:00:02")}, // Down but physical
+		{Name: "eth0", HardwareAddr: mac("00:1B:21:00:00:01"), Flags: net.FlagUp},
+		{Name: "bond0", HardwareAddr: mac("02:00:00:00:00:0b"), Flags: net.FlagUp},     // Virtual in sysfs
+		{Name: "docker0", HardwareAddr: mac("02:42:ac:11:00:01"), Flags: net.FlagUp},   // Ignored prefix
+		{Name: "veth1234", HardwareAddr: mac("0a:58:0a:f4:00:01"), Flags: net.FlagUp},  // Ignored prefix
+		{Name: "enx00e04c", HardwareAddr: mac("00:1b:21:00:00:01"), Flags: net.FlagUp}, // Duplicate
+		{Name: "tun0", Flags: net.FlagUp},
+	}
+
+	tests := []struct {
+		name   string
+		filter MACFilter
+		want   []string
+	}{
+		{"Default", DefaultMACFilter(), []string{"00:1b:21:00:00:01", "dc:a6:32:00:00:02"}},
+		{"UpOnly", MACFilter{IgnorePrefixes: DefaultMACFilter().IgnorePrefixes, PhysicalOnly: true}, []string{"00:1b:21:00:00:01"}},
+		{"AllInterfaces", MACFilter{IncludeDown: true}, []string{"00:1b:21:00:00:01", "02:00:00:00:00:0b", "02:42:ac:11:00:01", "0a:58:0a:f4:00:01", "dc:a6:32:00:00:02"}},
+		{"GloballyAdministered", MACFilter{IncludeDown: true, IgnoreLocallyAdministered: true}, []string{"00:1b:21:00:00:01", "dc:a6:32:00:00:02"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewHardwareProvider(WithFileSystemRoot(root), WithMACFilter(tt.filter))
+			p.goos = "linux"
+			p.interfaces = func() ([]net.Interface, error) { return interfaces, nil }
+
+			macs, err := p.macAddresses()
+			if err != nil {
+				t.Fatalf("Failed to get MAC addresses: %v", err)
+			}
+			if !reflect.DeepEqual(macs, tt.want) {
+				t.Errorf("Expected MAC addresses %v, got %v", tt.want, macs)
+			}
+		})
+	}
+
+	// No qualifying interface is not an error
+	p := NewHardwareProvider(WithFileSystemRoot(root))
+	p.goos = "linux"
+	p.interfaces = func() ([]net.Interface, error) { return interfaces[:1], nil }
+	if macs, err := p.macAddresses(); err != nil || len(macs) != 0 {
+		t.Errorf("Expected no MAC addresses and no error, got %v, %v", macs, err)
+	}
+}
+
+// TestMACFingerprintStability tests that interfaces going up or down and
+// virtual interfaces coming and going keep the fingerprint of a machine
+// without a machine ID
+func TestMACFingerprintStability(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"sys/block/sda/dev":                 "8:0\n",
+		"sys/block/sda/device/serial":       "WD-123\n",
+		"sys/class/net/eth0/device/vendor":  "0x8086\n",
+		"sys/class/net/wlan0/device/vendor": "0x168c\n",
+		"sys/class/net/bond0/address":       "02:00:00:00:00:0b\n",
+		"proc/sys/kernel/hostname":          "build-host\n",
+		"proc/cpuinfo":                      "model name\t: Example CPU\n",
+	})
+	mac := func(s string) net.HardwareAddr {
+		addr, err := net.ParseMAC(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr
+	}
+	eth0 := net.Interface{Name: "eth0", HardwareAddr: mac("00:1b:21:00:00:01"), Flags: net.FlagUp}
+	wlan0 := net.Interface{Name: "wlan0", HardwareAddr: mac("dc:a6:32:00:00:02"), Flags: net.FlagUp}
+	down := func(iface net.Interface) net.Interface {
+		iface.Flags &^= net.FlagUp
+		return iface
+	}
+	bond0 := net.Interface{Name: "bond0", HardwareAddr: mac("02:00:00:00:00:0b"), Flags: net.FlagUp}
+	docker0 := net.Interface{Name: "docker0", HardwareAddr: mac("02:42:ac:11:00:01"), Flags: net.FlagUp}
+	veth := net.Interface{Name: "veth1234", HardwareAddr: mac("0a:58:0a:f4:00:01"), Flags: net.FlagUp}
+	wg0 := net.Interface{Name: "wg0", Flags: net.FlagUp}
+
+	collect := func(t *testing.T, filter MACFilter, interfaces ...net.Interface) *HardwareInfo {
+		t.Helper()
+		p := NewHardwareProvider(WithFileSystemRoot(root), WithMACFilter(filter))
+		p.goos = "linux"
+		p.interfaces = func() ([]net.Interface, error) { return interfaces, nil }
+		info, err := p.Collect()
+		if err != nil {
+			t.Fatalf("Failed to collect hardware info: %v", err)
+		}
+		return info
+	}
+
+	info := collect(t, DefaultMACFilter(), eth0, wlan0)
+	if got := fmt.Sprintf("%x", info.Fingerprint()); got != "be0457aabaf04c7505ccb959150a83c157a800fffbee049b914cfeb841d4c351" {
+		t.Errorf("Fingerprint of the fixture changed to %s", got)
+	}
+
+	for name, interfaces := range map[string][]net.Interface{
+		"WiFiDown":          {eth0, down(wlan0)},
+		"AllDown":           {down(eth0), down(wlan0)},
+		"Reordered":         {wlan0, eth0},
+		"Docker":            {eth0, wlan0, docker0, veth},
+		"VPN":               {eth0, wlan0, wg0},
+		"Bond":              {eth0, wlan0, bond0},
+		"DownWithVirtual":   {down(eth0), wlan0, docker0, veth, bond0, wg0},
+		"VirtualInterfaces": {eth0, down(wlan0), down(docker0), down(veth), down(bond0)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if changed := collect(t, DefaultMACFilter(), interfaces...); !bytes.Equal(changed.Fingerprint(), info.Fingerprint()) {
+				t.Errorf("Fingerprint changed: MACs %v", changed.MACAddresses)
+			}
+		})
+	}
+
+	// Without IncludeDown, disabling Wi-Fi changes the fingerprint
+	upOnly := DefaultMACFilter()
+	upOnly.IncludeDown = false
+	if bytes.Equal(collect(t, upOnly, eth0, down(wlan0)).Fingerprint(), collect(t, upOnly, eth0, wlan0).Fingerprint()) {
+		t.Error("Expected the fingerprint to change with a down interface when IncludeDown is off")
+	}
+}
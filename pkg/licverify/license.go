@@ -1,7 +1,9 @@
 package licverify
 
 import (
+	"bytes"
 	"crypto"
+	"crypto/ecdh"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
@@ -9,28 +11,55 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/luhtfiimanal/go-license/v2/pkg/licformat"
 )
 
-// License represents a software license with hardware binding and expiration
+// License represents a software license with hardware binding and expiration.
+// A License can only be obtained from a Verifier and is immutable: its fields
+// are exposed through getters that return copies, and the signature is checked
+// against the exact bytes the license was loaded from.
 type License struct {
 	// Core license data
-	ID           string    `json:"id"`
-	CustomerID   string    `json:"customer_id"`
-	ProductID    string    `json:"product_id"`
-	SerialNumber string    `json:"serial_number"`
-	IssueDate    time.Time `json:"issue_date"`
-	ExpiryDate   time.Time `json:"expiry_date"`
-	Features     []string  `json:"features"`
+	id           string
+	customerID   string
+	productID    string
+	serialNumber string
+	issueDate    time.Time
+	expiryDate   time.Time
+	features     []string
 
 	// Hardware binding data
-	HardwareIDs HardwareBinding `json:"hardware_ids"`
+	hardwareIDs HardwareBinding
 
-	// Signature is stored separately and not included in the JSON for signature verification
-	Signature []byte `json:"-"`
+	// previousID is the ID of the license this one renews, if any
+	previousID string
+
+	// seats is the number of concurrent users of a floating license
+	seats uint32
+	// seatServerKey is the hash of the public key of the seat server
+	// allowed to serve a floating license
+	seatServerKey []byte
+
+	// trialDuration is the length of a trial counted from the first run
+	trialDuration time.Duration
+
+	// quotas maps metered features to the units the license allows
+	quotas map[string]uint64
+
+	// signedData holds the exact bytes covered by the signature
+	signedData []byte
+	signature  []byte
+	scheme     licformat.SignatureScheme
+
+	// legacyJSON is set when the license was parsed from the v1.x JSON format
+	legacyJSON bool
+	// encrypted is set when the license contents were encrypted
+	encrypted bool
 }
 
 // HardwareBinding contains hardware identifiers for license binding
@@ -39,15 +68,122 @@ type HardwareBinding struct {
 	DiskIDs      []string `json:"disk_ids,omitempty"`
 	HostNames    []string `json:"host_names,omitempty"`
 	CustomIDs    []string `json:"custom_ids,omitempty"`
+	// ContainerIDs bind the license to container environments, such as a
+	// Kubernetes cluster, see ContainerID
+	ContainerIDs []string `json:"container_ids,omitempty"`
+}
+
+// legacyLicense is the v1.x JSON license layout
+type legacyLicense struct {
+	ID           string          `json:"id"`
+	CustomerID   string          `json:"customer_id"`
+	ProductID    string          `json:"product_id"`
+	SerialNumber string          `json:"serial_number"`
+	IssueDate    time.Time       `json:"issue_date"`
+	ExpiryDate   time.Time       `json:"expiry_date"`
+	Features     []string        `json:"features"`
+	HardwareIDs  HardwareBinding `json:"hardware_ids"`
+}
+
+// ID returns the license ID
+func (license *License) ID() string { return license.id }
+
+// CustomerID returns the customer ID
+func (license *License) CustomerID() string { return license.customerID }
+
+// ProductID returns the product ID
+func (license *License) ProductID() string { return license.productID }
+
+// SerialNumber returns the serial number
+func (license *License) SerialNumber() string { return license.serialNumber }
+
+// IssueDate returns the date the license was issued
+func (license *License) IssueDate() time.Time { return license.issueDate }
+
+// ExpiryDate returns the date the license expires
+func (license *License) ExpiryDate() time.Time { return license.expiryDate }
+
+// Features returns a copy of the licensed features
+func (license *License) Features() []string { return cloneStrings(license.features) }
+
+// HasFeature reports whether the license grants the named feature
+func (license *License) HasFeature(feature string) bool {
+	return contains(license.features, feature)
+}
+
+// HardwareIDs returns a copy of the hardware binding
+func (license *License) HardwareIDs() HardwareBinding {
+	return HardwareBinding{
+		MACAddresses: cloneStrings(license.hardwareIDs.MACAddresses),
+		DiskIDs:      cloneStrings(license.hardwareIDs.DiskIDs),
+		HostNames:    cloneStrings(license.hardwareIDs.HostNames),
+		CustomIDs:    cloneStrings(license.hardwareIDs.CustomIDs),
+		ContainerIDs: cloneStrings(license.hardwareIDs.ContainerIDs),
+	}
+}
+
+// PreviousID returns the ID of the license this one renews or replaces,
+// or an empty string for an original license
+func (license *License) PreviousID() string { return license.previousID }
+
+// Seats returns the number of concurrent users of a floating license,
+// or zero if the license is not floating
+func (license *License) Seats() uint32 { return license.seats }
+
+// SeatServerKey returns a copy of the hash of the public key of the seat
+// server allowed to serve a floating license, see SeatServerKeyID
+func (license *License) SeatServerKey() []byte { return bytes.Clone(license.seatServerKey) }
+
+// IsTrial reports whether the license is a trial that starts on first run,
+// see TrialTracker
+func (license *License) IsTrial() bool { return license.trialDuration > 0 }
+
+// TrialDuration returns the length of the trial counted from the first run,
+// or zero if the license is not a trial
+func (license *License) TrialDuration() time.Duration { return license.trialDuration }
+
+// Quotas returns a copy of the usage quotas, mapping each metered feature
+// to the number of units the license allows it to consume, see UsageMeter
+func (license *License) Quotas() map[string]uint64 { return maps.Clone(license.quotas) }
+
+// Quota returns the usage quota of a feature and whether the feature is metered
+func (license *License) Quota(feature string) (uint64, bool) {
+	limit, ok := license.quotas[feature]
+	return limit, ok
+}
+
+// Signature returns a copy of the license signature
+func (license *License) Signature() []byte { return cloneBytes(license.signature) }
+
+// SignatureScheme returns the scheme declared in the license header
+// (legacy JSON licenses use PKCS#1 v1.5)
+func (license *License) SignatureScheme() licformat.SignatureScheme { return license.scheme }
+
+// SignedData returns a copy of the exact bytes covered by the signature
+func (license *License) SignedData() []byte { return cloneBytes(license.signedData) }
+
+// IsLegacyJSON reports whether the license was loaded from the legacy v1.x JSON format
+func (license *License) IsLegacyJSON() bool {
+	return license.legacyJSON
+}
+
+// IsEncrypted reports whether the license contents were encrypted
+func (license *License) IsEncrypted() bool {
+	return license.encrypted
 }
 
 // Verifier handles license verification
 type Verifier struct {
-	publicKey *rsa.PublicKey
+	publicKey     *rsa.PublicKey
+	legacyJSON    bool
+	decryptionKey *ecdh.PrivateKey
+	cache         *verifierCache
+	hardware      *HardwareProvider
+	hwInfo        *HardwareInfo
 }
 
 // NewVerifier creates a new license verifier with the provided public key
-func NewVerifier(publicKeyPEM string) (*Verifier, error) {
+func NewVerifier(publicKeyPEM string, opts ...Option) (*Verifier, error) {
 	if publicKeyPEM == "" {
 		return nil, errors.New("public key cannot be empty")
 	}
@@ -67,9 +203,19 @@ func NewVerifier(publicKeyPEM string) (*Verifier, error) {
 		return nil, errors.New("not an RSA public key")
 	}
 
-	return &Verifier{
-		publicKey: rsaPub,
-	}, nil
+	return NewVerifierFromPublicKey(rsaPub, opts...), nil
+}
+
+// NewVerifierFromPublicKey creates a new license verifier from a parsed RSA public key
+func NewVerifierFromPublicKey(publicKey *rsa.PublicKey, opts ...Option) *Verifier {
+	v := &Verifier{
+		publicKey: publicKey,
+		hardware:  NewHardwareProvider(),
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
 }
 
 // LoadLicense loads a license from the specified file path
@@ -79,151 +225,228 @@ func (v *Verifier) LoadLicense(filePath string) (*License, error) {
 		return nil, fmt.Errorf("failed to read license file: %v", err)
 	}
 
+	return v.ParseLicense(data)
+}
+
+// ParseLicense parses license file contents (license data followed by signature)
+func (v *Verifier) ParseLicense(data []byte) (*License, error) {
 	// License file format: Binary data followed by signature
-	// The last 256 bytes (for RSA-2048) are the signature
-	if len(data) < 256 {
+	// The signature is as long as the RSA modulus (256 bytes for RSA-2048)
+	signatureSize := v.publicKey.Size()
+	if len(data) <= signatureSize {
 		return nil, errors.New("license file too small")
 	}
 
-	licenseData := data[:len(data)-256]
-	signature := data[len(data)-256:]
+	// Keep private copies so the caller cannot modify what gets verified
+	licenseData := cloneBytes(data[:len(data)-signatureSize])
+	signature := cloneBytes(data[len(data)-signatureSize:])
+
+	// Decrypt encrypted licenses, the signature covers the encrypted data
+	encodedData := licenseData
+	if licformat.IsEncrypted(licenseData) {
+		var err error
+		encodedData, err = v.openLicense(licenseData)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	// Try binary format first (v2.0.0+)
-	importedLicense, err := licformat.DecodeLicense(licenseData)
+	// Binary format (v2.0.0+)
+	importedLicense, err := licformat.DecodeLicense(encodedData)
 	if err != nil {
-		// Fallback to legacy JSON format (v1.x)
-		var license License
-		if jsonErr := json.Unmarshal(licenseData, &license); jsonErr != nil {
+		if !v.legacyJSON {
+			return nil, fmt.Errorf("failed to parse license data: %v", err)
+		}
+
+		// Legacy JSON format (v1.x), only when explicitly enabled
+		var legacy legacyLicense
+		if jsonErr := json.Unmarshal(licenseData, &legacy); jsonErr != nil {
 			return nil, fmt.Errorf("failed to parse license data: %v (binary format error: %v)", jsonErr, err)
 		}
-		// Successfully parsed as legacy JSON format
-		license.Signature = signature
-		return &license, nil
+		return &License{
+			id:           legacy.ID,
+			customerID:   legacy.CustomerID,
+			productID:    legacy.ProductID,
+			serialNumber: legacy.SerialNumber,
+			issueDate:    legacy.IssueDate,
+			expiryDate:   legacy.ExpiryDate,
+			features:     legacy.Features,
+			hardwareIDs:  legacy.HardwareIDs,
+			signedData:   licenseData,
+			signature:    signature,
+			scheme:       licformat.SchemePKCS1v15,
+			legacyJSON:   true,
+		}, nil
 	}
 
 	// Convert the imported license to our internal format
-	license := License{
-		ID:           importedLicense.ID,
-		CustomerID:   importedLicense.CustomerID,
-		ProductID:    importedLicense.ProductID,
-		SerialNumber: importedLicense.SerialNumber,
-		IssueDate:    importedLicense.IssueDate,
-		ExpiryDate:   importedLicense.ExpiryDate,
-		Features:     importedLicense.Features,
-		HardwareIDs: HardwareBinding{
+	return &License{
+		id:           importedLicense.ID,
+		customerID:   importedLicense.CustomerID,
+		productID:    importedLicense.ProductID,
+		serialNumber: importedLicense.SerialNumber,
+		issueDate:    importedLicense.IssueDate,
+		expiryDate:   importedLicense.ExpiryDate,
+		features:     importedLicense.Features,
+		hardwareIDs: HardwareBinding{
 			MACAddresses: importedLicense.HardwareIDs.MACAddresses,
 			DiskIDs:      importedLicense.HardwareIDs.DiskIDs,
 			HostNames:    importedLicense.HardwareIDs.HostNames,
 			CustomIDs:    importedLicense.HardwareIDs.CustomIDs,
+			ContainerIDs: importedLicense.HardwareIDs.ContainerIDs,
 		},
+		previousID:    importedLicense.PreviousID,
+		seats:         importedLicense.Seats,
+		seatServerKey: importedLicense.SeatServerKey,
+		trialDuration: importedLicense.TrialDuration,
+		quotas:        importedLicense.Quotas,
+		signedData:    licenseData,
+		signature:     signature,
+		scheme:        importedLicense.Scheme,
+		encrypted:     licformat.IsEncrypted(licenseData),
+	}, nil
+}
+
+// openLicense decrypts the contents of an encrypted license
+func (v *Verifier) openLicense(licenseData []byte) ([]byte, error) {
+	mode, err := licformat.EncryptedMode(licenseData)
+	if err != nil {
+		return nil, err
 	}
 
-	license.Signature = signature
-	return &license, nil
+	switch mode {
+	case licformat.EncryptionX25519:
+		if v.decryptionKey == nil {
+			return nil, fmt.Errorf("%w: no decryption key configured", licformat.ErrEncrypted)
+		}
+		return licformat.OpenLicense(licenseData, v.decryptionKey)
+	case licformat.EncryptionFingerprint:
+		hwInfo, err := v.hardwareInfo()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hardware info: %v", err)
+		}
+		encodedData, err := licformat.OpenLicenseWithFingerprint(licenseData, hwInfo.Fingerprint())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMachineBinding, err)
+		}
+		return encodedData, nil
+	default:
+		return nil, fmt.Errorf("unsupported license encryption mode: %d", mode)
+	}
 }
 
-// VerifySignature verifies the digital signature of the license
+// VerifySignature verifies the digital signature of the license over the
+// exact bytes it was loaded from
 func (v *Verifier) VerifySignature(license *License) error {
-	// Create a copy of the license without the signature for verification
-	licenseCopy := *license
-	licenseCopy.Signature = nil
-
-	// Convert to binary format for verification
-	licenseFormatObj := licformat.License{
-		ID:           licenseCopy.ID,
-		CustomerID:   licenseCopy.CustomerID,
-		ProductID:    licenseCopy.ProductID,
-		SerialNumber: licenseCopy.SerialNumber,
-		IssueDate:    licenseCopy.IssueDate,
-		ExpiryDate:   licenseCopy.ExpiryDate,
-		Features:     licenseCopy.Features,
-		HardwareIDs: licformat.HardwareBinding{
-			MACAddresses: licenseCopy.HardwareIDs.MACAddresses,
-			DiskIDs:      licenseCopy.HardwareIDs.DiskIDs,
-			HostNames:    licenseCopy.HardwareIDs.HostNames,
-			CustomIDs:    licenseCopy.HardwareIDs.CustomIDs,
-		},
+	if v.cache == nil {
+		return v.verifySignature(license)
 	}
 
-	// Try binary format first
-	licenseData, err := licformat.EncodeLicense(&licenseFormatObj)
-	if err != nil {
-		return fmt.Errorf("failed to encode license data: %v", err)
+	now := time.Now()
+	if result, ok := v.cache.cachedSignature(license, now); ok {
+		return result.err
 	}
+	err := v.verifySignature(license)
+	v.cache.storeSignature(license, err, now)
+	return err
+}
 
-	// Calculate the hash of the license data
-	hashed := sha256.Sum256(licenseData)
-
-	// Verify the signature
-	err = rsa.VerifyPKCS1v15(v.publicKey, crypto.SHA256, hashed[:], license.Signature)
-	if err != nil {
-		// Try JSON format for backward compatibility
-		jsonData, jsonErr := json.Marshal(licenseCopy)
-		if jsonErr != nil {
-			return fmt.Errorf("failed to marshal license data: %v", jsonErr)
-		}
+// verifySignature verifies the signature of the license without caching
+func (v *Verifier) verifySignature(license *License) error {
+	if license.legacyJSON && !v.legacyJSON {
+		return ErrLegacyJSONDisabled
+	}
+	if len(license.signedData) == 0 {
+		return errors.New("license has no signed data")
+	}
 
-		// Calculate the hash of the JSON data
-		hashedJSON := sha256.Sum256(jsonData)
+	// Calculate the hash of the license data
+	hashed := sha256.Sum256(license.signedData)
 
-		// Verify the signature with JSON data
-		jsonVerifyErr := rsa.VerifyPKCS1v15(v.publicKey, crypto.SHA256, hashedJSON[:], license.Signature)
-		if jsonVerifyErr != nil {
-			return fmt.Errorf("invalid license signature (tried both binary and JSON formats): %v", err)
-		}
+	// Verify the signature using the scheme declared in the header
+	if err := verifyWithScheme(v.publicKey, license.scheme, hashed[:], license.signature); err != nil {
+		return fmt.Errorf("invalid license signature: %v", err)
 	}
 
 	return nil
 }
 
+// verifyWithScheme checks an RSA signature over a SHA-256 digest using the given scheme
+func verifyWithScheme(publicKey *rsa.PublicKey, scheme licformat.SignatureScheme, hashed, signature []byte) error {
+	switch scheme {
+	case licformat.SchemePKCS1v15:
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hashed, signature)
+	case licformat.SchemePSS:
+		return rsa.VerifyPSS(publicKey, crypto.SHA256, hashed, signature, &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+		})
+	default:
+		return fmt.Errorf("unsupported signature scheme: %v", scheme)
+	}
+}
+
 // VerifyHardwareBinding verifies that the license is bound to the current hardware
 func (v *Verifier) VerifyHardwareBinding(license *License) error {
 	// Get hardware info
-	hwInfo, err := GetHardwareInfo()
+	hwInfo, err := v.hardwareInfo()
 	if err != nil {
 		return fmt.Errorf("failed to get hardware info: %v", err)
 	}
 
 	// Verify MAC addresses
-	if len(license.HardwareIDs.MACAddresses) > 0 {
-		if !containsAny(hwInfo.MACAddresses, license.HardwareIDs.MACAddresses) {
+	if len(license.hardwareIDs.MACAddresses) > 0 {
+		if !containsAny(hwInfo.MACAddresses, license.hardwareIDs.MACAddresses) {
 			return errors.New("license is not valid for this hardware (MAC address mismatch)")
 		}
 	}
 
 	// Verify disk IDs if present
-	if len(license.HardwareIDs.DiskIDs) > 0 {
-		if !containsAny(hwInfo.DiskIDs, license.HardwareIDs.DiskIDs) {
+	if len(license.hardwareIDs.DiskIDs) > 0 {
+		if !containsAny(hwInfo.DiskIDs, license.hardwareIDs.DiskIDs) {
 			return errors.New("license is not valid for this hardware (disk ID mismatch)")
 		}
 	}
 
 	// Verify hostname if present
-	if len(license.HardwareIDs.HostNames) > 0 {
-		if !contains(license.HardwareIDs.HostNames, hwInfo.Hostname) {
+	if len(license.hardwareIDs.HostNames) > 0 {
+		if !contains(license.hardwareIDs.HostNames, hwInfo.Hostname) {
 			return errors.New("license is not valid for this hardware (hostname mismatch)")
 		}
 	}
 
+	// Verify container IDs if present
+	if len(license.hardwareIDs.ContainerIDs) > 0 {
+		if !containsAny(hwInfo.ContainerIDs, license.hardwareIDs.ContainerIDs) {
+			return errors.New("license is not valid for this environment (container ID mismatch)")
+		}
+	}
+
 	return nil
 }
 
 // VerifyExpiry checks if the license has expired
 func (v *Verifier) VerifyExpiry(license *License) error {
 	now := time.Now()
-	if now.After(license.ExpiryDate) {
-		return fmt.Errorf("license expired on %s", license.ExpiryDate.Format(time.RFC3339))
+	if now.After(license.expiryDate) {
+		return fmt.Errorf("license expired on %s", license.expiryDate.Format(time.RFC3339))
 	}
 	return nil
 }
 
-// IsValid performs all verification checks on the license
+// IsValid performs all verification checks on the license. Trial licenses
+// are refused with ErrTrialNotTracked: their trial period is only enforced
+// by TrialTracker.Check.
 func (license *License) IsValid(verifier *Verifier) error {
 	// Verify signature
 	if err := verifier.VerifySignature(license); err != nil {
 		return err
 	}
 
+	// A trial license is only valid for the trial period from its first run
+	if license.IsTrial() {
+		return ErrTrialNotTracked
+	}
+
 	// Verify hardware binding
 	if err := verifier.VerifyHardwareBinding(license); err != nil {
 		return err
@@ -260,3 +483,42 @@ func containsAny(slice1, slice2 []string) bool {
 	}
 	return false
 }
+
+// cloneStrings returns a copy of a string slice, preserving nil
+func cloneStrings(slice []string) []string {
+	if slice == nil {
+		return nil
+	}
+	return append([]string(nil), slice...)
+}
+
+// writeFileAtomic replaces the file at path with data, so that readers
+// never see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// cloneBytes returns a copy of a byte slice, preserving nil
+func cloneBytes(data []byte) []byte {
+	if data == nil {
+		return nil
+	}
+	return append([]byte(nil), data...)
+}
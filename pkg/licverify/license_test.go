@@ -32,7 +32,7 @@ func TestVerifyLicense(t *testing.T) {
 	}
 
 	// Create a test license
-	license := licverify.License{
+	license := legacyLicense{
 		ID:         "TEST-LICENSE-001",
 		CustomerID: "CUSTOMER-001",
 		ProductID:  "PRODUCT-001",
@@ -74,8 +74,8 @@ func TestVerifyLicense(t *testing.T) {
 		t.Fatalf("Failed to write license file: %v", err)
 	}
 
-	// Create a verifier with the public key
-	verifier, err := licverify.NewVerifier(publicKeyPEM)
+	// Create a verifier with the public key, accepting legacy JSON licenses
+	verifier, err := licverify.NewVerifier(publicKeyPEM, licverify.WithLegacyJSON())
 	if err != nil {
 		t.Fatalf("Failed to create verifier: %v", err)
 	}
@@ -136,7 +136,7 @@ func TestExpiredLicense(t *testing.T) {
 	}
 
 	// Create an expired license
-	license := licverify.License{
+	license := legacyLicense{
 		ID:         "TEST-LICENSE-002",
 		CustomerID: "CUSTOMER-001",
 		ProductID:  "PRODUCT-001",
@@ -177,8 +177,8 @@ func TestExpiredLicense(t *testing.T) {
 		t.Fatalf("Failed to write license file: %v", err)
 	}
 
-	// Create a verifier with the public key
-	verifier, err := licverify.NewVerifier(publicKeyPEM)
+	// Create a verifier with the public key, accepting legacy JSON licenses
+	verifier, err := licverify.NewVerifier(publicKeyPEM, licverify.WithLegacyJSON())
 	if err != nil {
 		t.Fatalf("Failed to create verifier: %v", err)
 	}
@@ -207,3 +207,135 @@ func TestExpiredLicense(t *testing.T) {
 		t.Errorf("Expected expired license to fail expiry check")
 	}
 }
+
+// TestLegacyJSONRequiresOptIn tests that legacy JSON licenses are rejected unless enabled
+func TestLegacyJSONRequiresOptIn(t *testing.T) {
+	// Generate a key pair for testing
+	privateKeyPEM, publicKeyPEM, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+
+	// Parse the private key for signing
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	// Create a signed legacy JSON license
+	licenseData, err := json.Marshal(legacyLicense{
+		ID:         "TEST-LICENSE-003",
+		CustomerID: "CUSTOMER-001",
+		ProductID:  "PRODUCT-001",
+		IssueDate:  time.Now(),
+		ExpiryDate: time.Now().AddDate(1, 0, 0),
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal license: %v", err)
+	}
+	signature, err := licgen.SignData(licenseData, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to sign license: %v", err)
+	}
+	licenseFileData := append(licenseData, signature...)
+
+	// The default verifier only accepts the binary format
+	verifier, err := licverify.NewVerifier(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	if _, err := verifier.ParseLicense(licenseFileData); err == nil {
+		t.Fatalf("Expected legacy JSON license to be rejected by default")
+	}
+
+	// A license parsed by a legacy-enabled verifier is still rejected by the default one
+	legacyVerifier, err := licverify.NewVerifier(publicKeyPEM, licverify.WithLegacyJSON())
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	license, err := legacyVerifier.ParseLicense(licenseFileData)
+	if err != nil {
+		t.Fatalf("Failed to parse legacy license: %v", err)
+	}
+	if !license.IsLegacyJSON() {
+		t.Errorf("Expected license to be reported as legacy JSON")
+	}
+	if err := legacyVerifier.VerifySignature(license); err != nil {
+		t.Errorf("Legacy signature verification failed: %v", err)
+	}
+	if err := verifier.VerifySignature(license); err != licverify.ErrLegacyJSONDisabled {
+		t.Errorf("Expected ErrLegacyJSONDisabled, got %v", err)
+	}
+}
+
+// legacyLicense mirrors the v1.x JSON license layout
+type legacyLicense struct {
+	ID           string                    `json:"id"`
+	CustomerID   string                    `json:"customer_id"`
+	ProductID    string                    `json:"product_id"`
+	SerialNumber string                    `json:"serial_number"`
+	IssueDate    time.Time                 `json:"issue_date"`
+	ExpiryDate   time.Time                 `json:"expiry_date"`
+	Features     []string                  `json:"features"`
+	HardwareIDs  licverify.HardwareBinding `json:"hardware_ids"`
+}
+
+// TestLicenseImmutable tests that a loaded license cannot be modified through its getters
+func TestLicenseImmutable(t *testing.T) {
+	// Generate a key pair for testing
+	privateKeyPEM, publicKeyPEM, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+
+	// Parse the private key for signing
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	licenseFileData, err := licgen.GenerateLicense(
+		"TEST-LICENSE-004",
+		"CUSTOMER-001",
+		"PRODUCT-001",
+		"SERIAL-001",
+		24*time.Hour,
+		[]string{"feature1", "feature2"},
+		licverify.HardwareBinding{MACAddresses: []string{"00:11:22:33:44:55"}},
+		privateKey,
+	)
+	if err != nil {
+		t.Fatalf("Failed to generate license: %v", err)
+	}
+
+	verifier, err := licverify.NewVerifier(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	license, err := verifier.ParseLicense(licenseFileData)
+	if err != nil {
+		t.Fatalf("Failed to parse license: %v", err)
+	}
+
+	// Modifying the input or the returned values must not affect the license
+	for i := range licenseFileData {
+		licenseFileData[i] = 0
+	}
+	license.Features()[0] = "enterprise"
+	license.HardwareIDs().MACAddresses[0] = "AA:BB:CC:DD:EE:FF"
+	license.SignedData()[0] = 0xFF
+	license.Signature()[0] ^= 0xFF
+
+	if err := verifier.VerifySignature(license); err != nil {
+		t.Errorf("Signature verification failed: %v", err)
+	}
+	if license.Features()[0] != "feature1" {
+		t.Errorf("Features were modified: %v", license.Features())
+	}
+	if license.HardwareIDs().MACAddresses[0] != "00:11:22:33:44:55" {
+		t.Errorf("Hardware binding was modified: %v", license.HardwareIDs().MACAddresses)
+	}
+	if !license.HasFeature("feature2") || license.HasFeature("enterprise") {
+		t.Errorf("HasFeature returned unexpected results for %v", license.Features())
+	}
+}
@@ -0,0 +1,76 @@
+package licverify
+
+import (
+	"crypto/ecdh"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// ErrLegacyJSONDisabled is returned when verifying a legacy JSON license
+// with a verifier that was not created with WithLegacyJSON
+var ErrLegacyJSONDisabled = errors.New("legacy JSON licenses are not accepted (use WithLegacyJSON)")
+
+// ErrMachineBinding is returned when a machine-bound license cannot be
+// decrypted with the fingerprint of the current machine
+var ErrMachineBinding = errors.New("license contents are bound to a different machine")
+
+// Option configures optional behaviour of a Verifier
+type Option func(*Verifier)
+
+// WithLegacyJSON enables loading and verifying legacy v1.x JSON licenses.
+// JSON marshaling is not canonical, so this should only be enabled while
+// migrating existing licenses with `licforge migrate`.
+func WithLegacyJSON() Option {
+	return func(v *Verifier) {
+		v.legacyJSON = true
+	}
+}
+
+// WithDecryptionKey sets the X25519 private key used to decrypt licenses
+// generated with licgen.WithEncryption
+func WithDecryptionKey(key *ecdh.PrivateKey) Option {
+	return func(v *Verifier) {
+		v.decryptionKey = key
+	}
+}
+
+// WithHardwareProvider sets the provider of the hardware information used
+// by VerifyHardwareBinding and machine-bound licenses. The default is
+// NewHardwareProvider().
+func WithHardwareProvider(provider *HardwareProvider) Option {
+	return func(v *Verifier) {
+		v.hardware = provider
+	}
+}
+
+// WithHardwareInfo uses fixed hardware information instead of collecting it
+// from this machine, for example to read a machine-bound license on behalf
+// of the machine it was issued for. It takes precedence over
+// WithHardwareProvider.
+func WithHardwareInfo(hwInfo *HardwareInfo) Option {
+	return func(v *Verifier) {
+		v.hwInfo = hwInfo
+	}
+}
+
+// ParseDecryptionKey parses a PEM-encoded X25519 private key
+func ParseDecryptionKey(privateKeyPEM string) (*ecdh.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, errors.New("failed to parse PEM block containing the private key")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+
+	ecdhKey, ok := key.(*ecdh.PrivateKey)
+	if !ok || ecdhKey.Curve() != ecdh.X25519() {
+		return nil, errors.New("not an X25519 private key")
+	}
+
+	return ecdhKey, nil
+}
@@ -0,0 +1,443 @@
+package licverify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Floating licenses are shared by a limited number of concurrent users.
+// A seat server (see package licfloat) holds the pool license and hands
+// out seat leases signed with its own key. The pool license binds the hash
+// of that key, so only the seat server chosen by the vendor can serve it.
+// Clients keep a seat by sending heartbeats before the lease expires and
+// release it when they are done.
+//
+// Requests and responses are single lines of JSON over TCP, one request
+// per connection.
+
+// Errors returned by the seat client
+var (
+	// ErrNoSeats is returned when all seats of the floating license are in use
+	ErrNoSeats = errors.New("no floating license seats available")
+	// ErrSeatLost is returned when a seat lease expired or the server no
+	// longer knows the seat
+	ErrSeatLost = errors.New("floating license seat lost")
+	// ErrNoSeat is returned by heartbeats and releases without an acquired seat
+	ErrNoSeat = errors.New("no floating license seat acquired")
+)
+
+// seatLeaseContext prefixes seat lease payloads
+const seatLeaseContext = "go-license seat lease v1\n"
+
+// MaxSeatMessageSize bounds the size of seat protocol messages
+const MaxSeatMessageSize = 1 << 20
+
+// SeatOp is the operation of a seat request
+type SeatOp string
+
+const (
+	// SeatAcquire requests a new seat
+	SeatAcquire SeatOp = "acquire"
+	// SeatHeartbeat extends the lease of a seat
+	SeatHeartbeat SeatOp = "heartbeat"
+	// SeatRelease returns a seat to the pool
+	SeatRelease SeatOp = "release"
+)
+
+// Error codes of seat responses
+const (
+	SeatErrorNoSeats     = "no_seats"     // All seats are in use
+	SeatErrorUnknownSeat = "unknown_seat" // The seat expired or was released
+	SeatErrorInvalid     = "invalid"      // The request is malformed
+	SeatErrorUnavailable = "unavailable"  // The pool license cannot be used
+)
+
+// SeatRequest is a request to the seat server
+type SeatRequest struct {
+	Op     SeatOp `json:"op"`
+	Client string `json:"client,omitempty"`  // Describes the client, e.g. its hostname
+	SeatID string `json:"seat_id,omitempty"` // Heartbeat and release only
+	Nonce  string `json:"nonce,omitempty"`   // Echoed in the lease
+}
+
+// SeatResponse is the answer of the seat server
+type SeatResponse struct {
+	Lease     *SignedLease `json:"lease,omitempty"`
+	License   []byte       `json:"license,omitempty"` // The pool license file, on acquire
+	Error     string       `json:"error,omitempty"`
+	ErrorCode string       `json:"error_code,omitempty"`
+}
+
+// SeatLease grants one seat of a floating license until it expires
+type SeatLease struct {
+	LicenseID string    `json:"license_id"`
+	SeatID    string    `json:"seat_id"`
+	Client    string    `json:"client"`
+	Nonce     string    `json:"nonce"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EncodeSeatLease encodes a seat lease into the payload signed by the seat server
+func EncodeSeatLease(lease *SeatLease) ([]byte, error) {
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode seat lease: %v", err)
+	}
+	return append([]byte(seatLeaseContext), data...), nil
+}
+
+// VerifySeatLease checks the signature of a seat lease with the seat server's
+// public key and returns its contents
+func VerifySeatLease(signed *SignedLease, serverKey *rsa.PublicKey) (*SeatLease, error) {
+	var lease SeatLease
+	if err := openSignedLease(signed, serverKey, seatLeaseContext, &lease); err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// SeatServerKeyID returns the hash of a seat server public key that a
+// floating license binds, see licgen.WithSeatServer
+func SeatServerKeyID(serverKey *rsa.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(serverKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode seat server key: %v", err)
+	}
+	sum := sha256.Sum256(der)
+	return sum[:], nil
+}
+
+// SeatClient acquires and holds a seat of a floating license.
+// It is safe for concurrent use.
+type SeatClient struct {
+	verifier  *Verifier
+	addr      string
+	serverKey *rsa.PublicKey
+	serverID  []byte
+	client    string
+	timeout   time.Duration
+
+	mu      sync.Mutex
+	license *License
+	lease   *SeatLease
+	// acquiring is set while an Acquire request is in flight
+	acquiring bool
+}
+
+// SeatOption configures optional behaviour of a SeatClient
+type SeatOption func(*SeatClient)
+
+// WithClientName sets the client description sent to the seat server
+// (default the hostname)
+func WithClientName(name string) SeatOption {
+	return func(c *SeatClient) {
+		c.client = name
+	}
+}
+
+// WithSeatTimeout bounds each request to the seat server (default 10 seconds)
+func WithSeatTimeout(timeout time.Duration) SeatOption {
+	return func(c *SeatClient) {
+		c.timeout = timeout
+	}
+}
+
+// NewSeatClient creates a client for the seat server at addr. The pool
+// license is verified with verifier and must bind serverKey, which verifies
+// the seat leases.
+func NewSeatClient(verifier *Verifier, addr string, serverKey *rsa.PublicKey, opts ...SeatOption) (*SeatClient, error) {
+	if verifier == nil || serverKey == nil {
+		return nil, errors.New("verifier and seat server key are required")
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid seat server address: %v", err)
+	}
+	serverID, err := SeatServerKeyID(serverKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &SeatClient{
+		verifier:  verifier,
+		addr:      addr,
+		serverKey: serverKey,
+		serverID:  serverID,
+		timeout:   10 * time.Second,
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		c.client = hostname
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// License returns the pool license received with the seat, or nil before
+// a seat was acquired
+func (c *SeatClient) License() *License {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.license
+}
+
+// Lease returns a copy of the current seat lease, or nil if no seat is held
+func (c *SeatClient) Lease() *SeatLease {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lease == nil {
+		return nil
+	}
+	lease := *c.lease
+	return &lease
+}
+
+// Acquire requests a seat. The pool license sent by the server must carry a
+// valid signature, be unexpired and be a floating license bound to the seat
+// server key of the client.
+func (c *SeatClient) Acquire(ctx context.Context) (*SeatLease, error) {
+	c.mu.Lock()
+	switch {
+	case c.lease != nil:
+		c.mu.Unlock()
+		return nil, errors.New("a floating license seat is already acquired")
+	case c.acquiring:
+		c.mu.Unlock()
+		return nil, errors.New("a floating license seat is already being acquired")
+	}
+	c.acquiring = true
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.acquiring = false
+		c.mu.Unlock()
+	}()
+
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.roundTrip(ctx, &SeatRequest{Op: SeatAcquire, Client: c.client, Nonce: nonce})
+	if err != nil {
+		return nil, err
+	}
+
+	license, err := c.verifier.ParseLicense(resp.License)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool license: %w", err)
+	}
+	if err := c.verifier.VerifySignature(license); err != nil {
+		return nil, fmt.Errorf("invalid pool license: %w", err)
+	}
+	if err := c.verifier.VerifyExpiry(license); err != nil {
+		return nil, fmt.Errorf("invalid pool license: %w", err)
+	}
+	if license.Seats() == 0 {
+		return nil, errors.New("invalid pool license: not a floating license")
+	}
+	if !bytes.Equal(license.SeatServerKey(), c.serverID) {
+		return nil, errors.New("invalid pool license: not bound to this seat server key")
+	}
+
+	lease, err := c.verifyLease(resp, license.ID(), nonce, "")
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.license, c.lease = license, lease
+	return lease, nil
+}
+
+// Heartbeat extends the lease of the acquired seat. The seat is dropped
+// when the server reports it as unknown.
+func (c *SeatClient) Heartbeat(ctx context.Context) (*SeatLease, error) {
+	c.mu.Lock()
+	current, license := c.lease, c.license
+	c.mu.Unlock()
+	if current == nil {
+		return nil, ErrNoSeat
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.roundTrip(ctx, &SeatRequest{Op: SeatHeartbeat, Client: c.client, SeatID: current.SeatID, Nonce: nonce})
+	if errors.Is(err, ErrSeatLost) {
+		c.dropLease(current)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lease, err := c.verifyLease(resp, license.ID(), nonce, current.SeatID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lease == current {
+		c.lease = lease
+	}
+	return lease, nil
+}
+
+// Release returns the seat to the pool. The seat is dropped locally even
+// when the server cannot be reached, it then becomes free when its lease expires.
+func (c *SeatClient) Release(ctx context.Context) error {
+	c.mu.Lock()
+	current := c.lease
+	c.lease = nil
+	c.mu.Unlock()
+	if current == nil {
+		return ErrNoSeat
+	}
+
+	_, err := c.roundTrip(ctx, &SeatRequest{Op: SeatRelease, Client: c.client, SeatID: current.SeatID})
+	if errors.Is(err, ErrSeatLost) {
+		return nil
+	}
+	return err
+}
+
+// Hold keeps the acquired seat by sending heartbeats until ctx is cancelled,
+// then releases it and returns ctx.Err(). Failed heartbeats are retried
+// until the lease expires; Hold returns an ErrSeatLost error when the seat
+// cannot be kept.
+func (c *SeatClient) Hold(ctx context.Context) error {
+	for {
+		lease := c.Lease()
+		if lease == nil {
+			return ErrNoSeat
+		}
+
+		select {
+		case <-ctx.Done():
+			releaseCtx, cancel := context.WithTimeout(context.Background(), c.timeout)
+			defer cancel()
+			c.Release(releaseCtx)
+			return ctx.Err()
+		case <-time.After(lease.ExpiresAt.Sub(lease.IssuedAt) / 3):
+		}
+
+		if _, err := c.Heartbeat(ctx); err != nil && ctx.Err() == nil {
+			if errors.Is(err, ErrSeatLost) {
+				return err
+			}
+			if !time.Now().Before(lease.ExpiresAt) {
+				c.dropLease(lease)
+				return fmt.Errorf("%w: %v", ErrSeatLost, err)
+			}
+		}
+	}
+}
+
+// dropLease forgets the seat if lease is still the current lease
+func (c *SeatClient) dropLease(lease *SeatLease) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lease == lease {
+		c.lease = nil
+	}
+}
+
+// verifyLease checks a seat lease returned for a request
+func (c *SeatClient) verifyLease(resp *SeatResponse, licenseID, nonce, seatID string) (*SeatLease, error) {
+	if resp.Lease == nil {
+		return nil, errors.New("invalid seat response: missing lease")
+	}
+	lease, err := VerifySeatLease(resp.Lease, c.serverKey)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case lease.LicenseID != licenseID:
+		return nil, errors.New("invalid seat lease: issued for a different license")
+	case lease.Nonce != nonce:
+		return nil, errors.New("invalid seat lease: nonce does not match the request")
+	case lease.SeatID == "" || (seatID != "" && lease.SeatID != seatID):
+		return nil, errors.New("invalid seat lease: unexpected seat ID")
+	case !time.Now().Before(lease.ExpiresAt):
+		return nil, errors.New("invalid seat lease: already expired")
+	}
+	return lease, nil
+}
+
+// roundTrip sends one request on a new connection and reads the response
+func (c *SeatClient) roundTrip(ctx context.Context, req *SeatRequest) (*SeatResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to seat server: %v", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	line, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode seat request: %v", err)
+	}
+	if _, err := conn.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to send seat request: %v", err)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), MaxSeatMessageSize)
+	if !scanner.Scan() {
+		err := scanner.Err()
+		if err == nil {
+			err = errors.New("connection closed")
+		}
+		return nil, fmt.Errorf("failed to read seat response: %v", err)
+	}
+	var resp SeatResponse
+	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("invalid seat response: %v", err)
+	}
+
+	switch resp.ErrorCode {
+	case "":
+		if resp.Error != "" {
+			return nil, fmt.Errorf("seat server error: %s", resp.Error)
+		}
+		return &resp, nil
+	case SeatErrorNoSeats:
+		return nil, ErrNoSeats
+	case SeatErrorUnknownSeat:
+		return nil, fmt.Errorf("%w: %s", ErrSeatLost, resp.Error)
+	default:
+		return nil, fmt.Errorf("seat server error: %s", resp.Error)
+	}
+}
+
+// newNonce returns a random hex encoded nonce
+func newNonce() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return hex.EncodeToString(nonce), nil
+}
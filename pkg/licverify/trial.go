@@ -0,0 +1,234 @@
+package licverify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Trial licenses are generic licenses whose trial period starts on the
+// first run on each machine. TrialTracker records the first run in local
+// state files protected by an HMAC, so that edited files are detected.
+// License.IsValid refuses trial licenses, so that a trial is never accepted
+// as a full license for its whole availability period; check them with
+// TrialTracker.Check instead.
+// Deleting every state file restarts the trial; keeping copies in several
+// locations makes that harder.
+
+// Errors returned by trial tracking
+var (
+	// ErrNotTrial is returned when tracking a license that is not a trial
+	ErrNotTrial = errors.New("license is not a trial license")
+	// ErrTrialNotTracked is returned by License.IsValid for trial licenses,
+	// which must be checked with a TrialTracker
+	ErrTrialNotTracked = errors.New("trial licenses must be checked with a trial tracker")
+	// ErrTrialExpired is returned when the trial period has ended
+	ErrTrialExpired = errors.New("trial period has ended")
+	// ErrTrialTampered is returned when the trial state was modified or the
+	// system clock was set back
+	ErrTrialTampered = errors.New("trial state has been tampered with")
+)
+
+// trialStateContext separates trial state keys from other uses of the inputs
+const trialStateContext = "go-license trial state v1\n"
+
+// maxTrialStateSize bounds the size of trial state files
+const maxTrialStateSize = 4 << 10
+
+// TrialStatus is the state of a trial
+type TrialStatus string
+
+const (
+	// TrialActive means the trial period is running
+	TrialActive TrialStatus = "active"
+	// TrialExpired means the trial period has ended
+	TrialExpired TrialStatus = "expired"
+)
+
+// TrialInfo describes the trial of a license on this machine
+type TrialInfo struct {
+	Status    TrialStatus
+	FirstRun  time.Time
+	EndsAt    time.Time     // The end of the trial, at the latest the license expiry
+	Remaining time.Duration // Zero once the trial has ended
+}
+
+// trialState is the contents of a trial state file
+type trialState struct {
+	LicenseID string `json:"license_id"`
+	FirstRun  int64  `json:"first_run"` // Unix seconds
+	LastSeen  int64  `json:"last_seen"` // Unix seconds, detects clocks set back
+	MAC       []byte `json:"mac"`
+}
+
+// TrialTracker enforces trial periods counted from the first run.
+// It is safe for concurrent use.
+type TrialTracker struct {
+	verifier *Verifier
+	key      []byte
+	paths    []string
+	mu       sync.Mutex
+}
+
+// NewTrialTracker creates a tracker that keeps the trial state in every
+// one of paths. The state is authenticated with a key derived from secret
+// and the verifier's public key; secret should be unique to the application.
+func NewTrialTracker(verifier *Verifier, secret []byte, paths ...string) (*TrialTracker, error) {
+	if verifier == nil {
+		return nil, errors.New("verifier cannot be nil")
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("at least one trial state path is required")
+	}
+
+	key, err := deriveStateKey(verifier, trialStateContext, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrialTracker{
+		verifier: verifier,
+		key:      key,
+		paths:    append([]string(nil), paths...),
+	}, nil
+}
+
+// Check verifies the trial license signature and hardware binding, records
+// the first run if this is the first check on this machine and reports the
+// trial status. An expired trial returns its TrialInfo together with
+// ErrTrialExpired. The state files are only rewritten when a copy is
+// missing or the last run was recorded in an earlier second, so Check can
+// be called on every request.
+func (t *TrialTracker) Check(license *License) (*TrialInfo, error) {
+	if !license.IsTrial() {
+		return nil, ErrNotTrial
+	}
+	if err := t.verifier.VerifySignature(license); err != nil {
+		return nil, err
+	}
+	if err := t.verifier.VerifyHardwareBinding(license); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now().Unix()
+	firstRun, lastSeen, found := now, now, false
+	var states []*trialState
+	for _, path := range t.paths {
+		state, err := t.readState(path)
+		if err != nil {
+			return nil, err
+		}
+		if state == nil || state.LicenseID != license.id {
+			continue
+		}
+		states = append(states, state)
+		if !found || state.FirstRun < firstRun {
+			firstRun = state.FirstRun
+		}
+		if !found || state.LastSeen > lastSeen {
+			lastSeen = state.LastSeen
+		}
+		found = true
+	}
+
+	if now < lastSeen-int64(maxClockSkew/time.Second) {
+		return nil, fmt.Errorf("%w: system clock is earlier than the last recorded run", ErrTrialTampered)
+	}
+	lastSeen = max(lastSeen, now)
+
+	// Rewrite every copy, restoring deleted ones, unless all are current
+	current := len(states) == len(t.paths)
+	for _, state := range states {
+		current = current && state.FirstRun == firstRun && state.LastSeen == lastSeen
+	}
+	if !current {
+		state := &trialState{LicenseID: license.id, FirstRun: firstRun, LastSeen: lastSeen}
+		state.MAC = t.mac(state)
+		for _, path := range t.paths {
+			if err := t.writeState(path, state); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	info := &TrialInfo{
+		Status:   TrialActive,
+		FirstRun: time.Unix(firstRun, 0),
+		EndsAt:   time.Unix(firstRun, 0).Add(license.trialDuration),
+	}
+	if license.expiryDate.Before(info.EndsAt) {
+		info.EndsAt = license.expiryDate
+	}
+	info.Remaining = time.Until(info.EndsAt)
+	if info.Remaining <= 0 {
+		info.Status = TrialExpired
+		info.Remaining = 0
+		return info, ErrTrialExpired
+	}
+	return info, nil
+}
+
+// deriveStateKey derives the key that authenticates local state files from
+// an application secret and the verifier's public key, separated by context
+func deriveStateKey(verifier *Verifier, context string, secret []byte) ([]byte, error) {
+	publicKey, err := x509.MarshalPKIXPublicKey(verifier.publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %v", err)
+	}
+	h := sha256.New()
+	h.Write([]byte(context))
+	h.Write(secret)
+	h.Write(publicKey)
+	return h.Sum(nil), nil
+}
+
+// readState reads a trial state file. Missing files have no state.
+func (t *TrialTracker) readState(path string) (*trialState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trial state: %v", err)
+	}
+	if len(data) > maxTrialStateSize {
+		return nil, fmt.Errorf("%w: %s is too large", ErrTrialTampered, path)
+	}
+
+	var state trialState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%w: %s is not valid", ErrTrialTampered, path)
+	}
+	if !hmac.Equal(state.MAC, t.mac(&state)) || state.FirstRun > state.LastSeen {
+		return nil, fmt.Errorf("%w: %s", ErrTrialTampered, path)
+	}
+	return &state, nil
+}
+
+// writeState writes a trial state file
+func (t *TrialTracker) writeState(path string, state *trialState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode trial state: %v", err)
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write trial state: %v", err)
+	}
+	return nil
+}
+
+// mac authenticates the fields of a trial state
+func (t *TrialTracker) mac(state *trialState) []byte {
+	h := hmac.New(sha256.New, t.key)
+	fmt.Fprintf(h, "%q\n%d\n%d\n", state.LicenseID, state.FirstRun, state.LastSeen)
+	return h.Sum(nil)
+}
@@ -0,0 +1,158 @@
+package licverify_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// TestTrialTracker tests first-run trial periods and tamper detection
+func TestTrialTracker(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+
+	newTrial := func(duration time.Duration) *licverify.License {
+		t.Helper()
+		data, err := licgen.GenerateTrialLicense("TRIAL-1", "APP", duration, 365*24*time.Hour, []string{"basic"}, privateKey)
+		if err != nil {
+			t.Fatalf("Failed to generate trial license: %v", err)
+		}
+		license, err := verifier.ParseLicense(data)
+		if err != nil {
+			t.Fatalf("Failed to parse trial license: %v", err)
+		}
+		return license
+	}
+	secret := []byte("test application")
+
+	t.Run("first run", func(t *testing.T) {
+		license := newTrial(30 * 24 * time.Hour)
+		if !license.IsTrial() || license.TrialDuration() != 30*24*time.Hour || license.CustomerID() != "" {
+			t.Fatalf("Unexpected trial license: %v %s %q", license.IsTrial(), license.TrialDuration(), license.CustomerID())
+		}
+		if err := license.IsValid(verifier); !errors.Is(err, licverify.ErrTrialNotTracked) {
+			t.Errorf("Expected ErrTrialNotTracked from IsValid, got %v", err)
+		}
+
+		dir := t.TempDir()
+		paths := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+		tracker, err := licverify.NewTrialTracker(verifier, secret, paths...)
+		if err != nil {
+			t.Fatalf("Failed to create trial tracker: %v", err)
+		}
+
+		info, err := tracker.Check(license)
+		if err != nil {
+			t.Fatalf("Trial check failed: %v", err)
+		}
+		if info.Status != licverify.TrialActive || info.Remaining < 29*24*time.Hour || info.Remaining > 30*24*time.Hour {
+			t.Errorf("Unexpected trial info: %+v", info)
+		}
+		for _, path := range paths {
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("Trial state not written: %v", err)
+			}
+		}
+
+		// Later runs keep the first run, deleted copies are restored
+		if err := os.Remove(paths[0]); err != nil {
+			t.Fatal(err)
+		}
+		restarted, err := licverify.NewTrialTracker(verifier, secret, paths...)
+		if err != nil {
+			t.Fatalf("Failed to create trial tracker: %v", err)
+		}
+		again, err := restarted.Check(license)
+		if err != nil {
+			t.Fatalf("Trial check failed: %v", err)
+		}
+		if !again.FirstRun.Equal(info.FirstRun) {
+			t.Errorf("First run changed from %s to %s", info.FirstRun, again.FirstRun)
+		}
+		if _, err := os.Stat(paths[0]); err != nil {
+			t.Errorf("Deleted trial state not restored: %v", err)
+		}
+
+		// Edited state is detected
+		data, err := os.ReadFile(paths[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var state map[string]any
+		if err := json.Unmarshal(data, &state); err != nil {
+			t.Fatal(err)
+		}
+		state["first_run"] = time.Now().Unix() + 3600
+		data, _ = json.Marshal(state)
+		if err := os.WriteFile(paths[1], data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := restarted.Check(license); !errors.Is(err, licverify.ErrTrialTampered) {
+			t.Errorf("Expected ErrTrialTampered, got %v", err)
+		}
+
+		// State written for another application is rejected
+		other, err := licverify.NewTrialTracker(verifier, []byte("other application"), paths[0])
+		if err != nil {
+			t.Fatalf("Failed to create trial tracker: %v", err)
+		}
+		if _, err := other.Check(license); !errors.Is(err, licverify.ErrTrialTampered) {
+			t.Errorf("Expected ErrTrialTampered, got %v", err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		license := newTrial(time.Second)
+		tracker, err := licverify.NewTrialTracker(verifier, secret, filepath.Join(t.TempDir(), "trial.json"))
+		if err != nil {
+			t.Fatalf("Failed to create trial tracker: %v", err)
+		}
+		if _, err := tracker.Check(license); err != nil {
+			t.Fatalf("Trial check failed: %v", err)
+		}
+
+		time.Sleep(1500 * time.Millisecond)
+		info, err := tracker.Check(license)
+		if !errors.Is(err, licverify.ErrTrialExpired) {
+			t.Fatalf("Expected ErrTrialExpired, got %v", err)
+		}
+		if info.Status != licverify.TrialExpired || info.Remaining != 0 {
+			t.Errorf("Unexpected trial info: %+v", info)
+		}
+	})
+
+	t.Run("not a trial", func(t *testing.T) {
+		data, err := licgen.GenerateLicense("LIC-1", "ACME", "APP", "SN-1", 24*time.Hour, nil, licverify.HardwareBinding{}, privateKey)
+		if err != nil {
+			t.Fatalf("Failed to generate license: %v", err)
+		}
+		license, err := verifier.ParseLicense(data)
+		if err != nil {
+			t.Fatalf("Failed to parse license: %v", err)
+		}
+		tracker, err := licverify.NewTrialTracker(verifier, secret, filepath.Join(t.TempDir(), "trial.json"))
+		if err != nil {
+			t.Fatalf("Failed to create trial tracker: %v", err)
+		}
+		if _, err := tracker.Check(license); !errors.Is(err, licverify.ErrNotTrial) {
+			t.Errorf("Expected ErrNotTrial, got %v", err)
+		}
+	})
+
+	if _, err := licgen.GenerateTrialLicense("TRIAL-2", "APP", 30*24*time.Hour, 24*time.Hour, nil, privateKey); err == nil {
+		t.Error("Expected error for availability shorter than the trial")
+	}
+}
@@ -0,0 +1,334 @@
+package licverify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Usage-metered licenses carry signed quotas, such as a number of runs or
+// processed documents. UsageMeter records consumption in a local journal
+// protected by an HMAC and kept in every one of several paths. Every write
+// increments the journal sequence, so a copy that was restored from an
+// older version or deleted is detected against the other copies, and
+// against the last sequence seen by the meter while it is running. Rolling
+// back or deleting every copy between runs is only detected with a
+// UsageAnchor, which keeps the latest sequence outside the journal files.
+
+// Errors returned by usage metering
+var (
+	// ErrNotMetered is returned for a feature without a usage quota
+	ErrNotMetered = errors.New("feature has no usage quota")
+	// ErrQuotaExceeded is returned when consumption would exceed the quota
+	ErrQuotaExceeded = errors.New("usage quota exceeded")
+	// ErrUsageTampered is returned when the usage journal was modified,
+	// rolled back or deleted
+	ErrUsageTampered = errors.New("usage journal has been tampered with")
+)
+
+// usageJournalContext separates usage journal keys from other uses of the inputs
+const usageJournalContext = "go-license usage journal v1\n"
+
+// maxUsageJournalSize bounds the size of usage journal files
+const maxUsageJournalSize = 64 << 10
+
+// usageJournal is the contents of a usage journal file
+type usageJournal struct {
+	LicenseID string            `json:"license_id"`
+	Sequence  uint64            `json:"sequence"` // Incremented by every write
+	Used      map[string]uint64 `json:"used"`
+	MAC       []byte            `json:"mac"`
+}
+
+// UsageAnchor stores the latest journal sequence of a license somewhere
+// that removing the journal files does not reset, for example a file in
+// another location (FileUsageAnchor), the Windows registry, the OS keychain
+// or a license server. A meter with an anchor
+// reports a journal older than the anchored sequence, including a journal
+// whose copies were all deleted, with ErrUsageTampered.
+type UsageAnchor interface {
+	// LoadSequence returns the anchored sequence, zero if none was stored
+	LoadSequence(licenseID string) (uint64, error)
+	// StoreSequence anchors the sequence of the journal just written
+	StoreSequence(licenseID string, sequence uint64) error
+}
+
+// FileUsageAnchor is a UsageAnchor that keeps the journal sequences of all
+// licenses in one file. It only helps if the file lives somewhere that is
+// not removed together with the journal, for example a system-wide
+// directory when the journal is kept in the user's profile, or the other
+// way around. It is safe for concurrent use within one process.
+type FileUsageAnchor struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileUsageAnchor returns an anchor stored in the file at path. The file
+// is created on the first write.
+func NewFileUsageAnchor(path string) *FileUsageAnchor {
+	return &FileUsageAnchor{path: path}
+}
+
+// LoadSequence implements UsageAnchor
+func (a *FileUsageAnchor) LoadSequence(licenseID string) (uint64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sequences, err := a.read()
+	if err != nil {
+		return 0, err
+	}
+	return sequences[licenseID], nil
+}
+
+// StoreSequence implements UsageAnchor
+func (a *FileUsageAnchor) StoreSequence(licenseID string, sequence uint64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sequences, err := a.read()
+	if err != nil {
+		return err
+	}
+	sequences[licenseID] = sequence
+	data, err := json.Marshal(sequences)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage anchor: %v", err)
+	}
+	return writeFileAtomic(a.path, data, 0600)
+}
+
+// read returns the anchored sequences by license ID
+func (a *FileUsageAnchor) read() (map[string]uint64, error) {
+	sequences := make(map[string]uint64)
+	data, err := os.ReadFile(a.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sequences, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxUsageJournalSize {
+		return nil, fmt.Errorf("%w: %s is too large", ErrUsageTampered, a.path)
+	}
+	if err := json.Unmarshal(data, &sequences); err != nil {
+		return nil, fmt.Errorf("%w: %s is not valid", ErrUsageTampered, a.path)
+	}
+	return sequences, nil
+}
+
+// UsageMeter enforces the usage quotas of a license. It is safe for
+// concurrent use, but not for use by several processes at once.
+type UsageMeter struct {
+	license  *License
+	key      []byte
+	paths    []string
+	anchor   UsageAnchor
+	sequence uint64 // The latest journal sequence read or written
+	mu       sync.Mutex
+}
+
+// NewUsageMeter verifies the license signature and creates a meter that
+// keeps the usage journal in every one of paths. The journal is
+// authenticated with a key derived from secret and the verifier's public
+// key; secret should be unique to the application. The existing journal is
+// checked for tampering.
+func NewUsageMeter(verifier *Verifier, license *License, secret []byte, paths ...string) (*UsageMeter, error) {
+	return NewAnchoredUsageMeter(verifier, license, secret, nil, paths...)
+}
+
+// NewAnchoredUsageMeter is like NewUsageMeter, but also keeps the journal
+// sequence in anchor, so that a journal deleted or rolled back between runs
+// is detected. A nil anchor disables anchoring.
+func NewAnchoredUsageMeter(verifier *Verifier, license *License, secret []byte, anchor UsageAnchor, paths ...string) (*UsageMeter, error) {
+	if verifier == nil {
+		return nil, errors.New("verifier cannot be nil")
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("at least one usage journal path is required")
+	}
+	if len(license.quotas) == 0 {
+		return nil, errors.New("license has no usage quotas")
+	}
+	if err := verifier.VerifySignature(license); err != nil {
+		return nil, err
+	}
+
+	key, err := deriveStateKey(verifier, usageJournalContext, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &UsageMeter{
+		license: license,
+		key:     key,
+		paths:   append([]string(nil), paths...),
+		anchor:  anchor,
+	}
+	if _, err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Consume records n units of usage of a metered feature and returns the
+// remaining quota. Nothing is recorded if the quota would be exceeded.
+func (m *UsageMeter) Consume(feature string, n uint64) (uint64, error) {
+	limit, ok := m.license.Quota(feature)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrNotMetered, feature)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	journal, err := m.load()
+	if err != nil {
+		return 0, err
+	}
+	remaining := limit - min(journal.Used[feature], limit)
+	if n > remaining {
+		return remaining, fmt.Errorf("%w: %s has %d of %d remaining", ErrQuotaExceeded, feature, remaining, limit)
+	}
+	if n == 0 {
+		return remaining, nil
+	}
+
+	journal.Used[feature] += n
+	journal.Sequence++
+	journal.MAC = m.mac(journal)
+	for _, path := range m.paths {
+		if err := m.writeJournal(path, journal); err != nil {
+			return 0, err
+		}
+	}
+	m.sequence = journal.Sequence
+
+	// Anchor after writing, so that a failed write never leaves the
+	// journal behind its anchor
+	if m.anchor != nil {
+		if err := m.anchor.StoreSequence(m.license.id, journal.Sequence); err != nil {
+			return 0, fmt.Errorf("failed to anchor usage journal: %v", err)
+		}
+	}
+	return remaining - n, nil
+}
+
+// Remaining returns the unused quota of a metered feature
+func (m *UsageMeter) Remaining(feature string) (uint64, error) {
+	limit, ok := m.license.Quota(feature)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrNotMetered, feature)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	journal, err := m.load()
+	if err != nil {
+		return 0, err
+	}
+	return limit - min(journal.Used[feature], limit), nil
+}
+
+// load reads every copy of the journal and returns the latest one.
+// Copies may lag the latest by one write, which an interrupted write can
+// cause; anything older, and a journal older than one already seen or
+// anchored, has been rolled back or deleted. Journals of other licenses
+// are ignored.
+func (m *UsageMeter) load() (*usageJournal, error) {
+	sequences := make([]uint64, len(m.paths))
+	latest := &usageJournal{LicenseID: m.license.id, Used: make(map[string]uint64)}
+	for i, path := range m.paths {
+		journal, err := m.readJournal(path)
+		if err != nil {
+			return nil, err
+		}
+		if journal == nil || journal.LicenseID != m.license.id {
+			continue
+		}
+		sequences[i] = journal.Sequence
+		if journal.Sequence > latest.Sequence {
+			latest = journal
+		}
+	}
+
+	if latest.Sequence < m.sequence {
+		return nil, fmt.Errorf("%w: journal is older than the last recorded usage", ErrUsageTampered)
+	}
+	if m.anchor != nil {
+		anchored, err := m.anchor.LoadSequence(m.license.id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read usage anchor: %v", err)
+		}
+		if latest.Sequence < anchored {
+			return nil, fmt.Errorf("%w: journal is missing or older than the anchored usage", ErrUsageTampered)
+		}
+	}
+	for i, sequence := range sequences {
+		if sequence+1 < latest.Sequence {
+			return nil, fmt.Errorf("%w: %s was rolled back or deleted", ErrUsageTampered, m.paths[i])
+		}
+	}
+	m.sequence = latest.Sequence
+	return latest, nil
+}
+
+// readJournal reads a usage journal file. Missing files have no journal.
+func (m *UsageMeter) readJournal(path string) (*usageJournal, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage journal: %v", err)
+	}
+	if len(data) > maxUsageJournalSize {
+		return nil, fmt.Errorf("%w: %s is too large", ErrUsageTampered, path)
+	}
+
+	var journal usageJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, fmt.Errorf("%w: %s is not valid", ErrUsageTampered, path)
+	}
+	if !hmac.Equal(journal.MAC, m.mac(&journal)) {
+		return nil, fmt.Errorf("%w: %s", ErrUsageTampered, path)
+	}
+	if journal.Used == nil {
+		journal.Used = make(map[string]uint64)
+	}
+	return &journal, nil
+}
+
+// writeJournal writes a usage journal file
+func (m *UsageMeter) writeJournal(path string, journal *usageJournal) error {
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage journal: %v", err)
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write usage journal: %v", err)
+	}
+	return nil
+}
+
+// mac authenticates the fields of a usage journal
+func (m *UsageMeter) mac(journal *usageJournal) []byte {
+	features := make([]string, 0, len(journal.Used))
+	for feature := range journal.Used {
+		features = append(features, feature)
+	}
+	slices.Sort(features)
+
+	h := hmac.New(sha256.New, m.key)
+	fmt.Fprintf(h, "%q\n%d\n", journal.LicenseID, journal.Sequence)
+	for _, feature := range features {
+		fmt.Fprintf(h, "%q %d\n", feature, journal.Used[feature])
+	}
+	return h.Sum(nil)
+}
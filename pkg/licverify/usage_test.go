@@ -0,0 +1,260 @@
+package licverify_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// TestUsageMeter tests quota enforcement and journal tamper detection
+func TestUsageMeter(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+
+	data, err := licgen.GenerateLicense("LIC-1", "ACME", "APP", "SN-1", 24*time.Hour,
+		[]string{"basic"}, licverify.HardwareBinding{}, privateKey,
+		licgen.WithQuota("runs", 3), licgen.WithQuota("documents", 1000))
+	if err != nil {
+		t.Fatalf("Failed to generate license: %v", err)
+	}
+	license, err := verifier.ParseLicense(data)
+	if err != nil {
+		t.Fatalf("Failed to parse license: %v", err)
+	}
+	if limit, ok := license.Quota("runs"); !ok || limit != 3 || len(license.Quotas()) != 2 {
+		t.Fatalf("Unexpected quotas: %v", license.Quotas())
+	}
+	secret := []byte("test application")
+
+	newMeter := func(t *testing.T, paths ...string) *licverify.UsageMeter {
+		t.Helper()
+		meter, err := licverify.NewUsageMeter(verifier, license, secret, paths...)
+		if err != nil {
+			t.Fatalf("Failed to create usage meter: %v", err)
+		}
+		return meter
+	}
+
+	t.Run("consume", func(t *testing.T) {
+		dir := t.TempDir()
+		paths := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+		meter := newMeter(t, paths...)
+
+		for _, want := range []uint64{2, 1, 0} {
+			remaining, err := meter.Consume("runs", 1)
+			if err != nil {
+				t.Fatalf("Consume failed: %v", err)
+			}
+			if remaining != want {
+				t.Errorf("Expected %d runs remaining, got %d", want, remaining)
+			}
+		}
+		if _, err := meter.Consume("runs", 1); !errors.Is(err, licverify.ErrQuotaExceeded) {
+			t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+		}
+		if _, err := meter.Consume("documents", 1001); !errors.Is(err, licverify.ErrQuotaExceeded) {
+			t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+		}
+		if _, err := meter.Consume("exports", 1); !errors.Is(err, licverify.ErrNotMetered) {
+			t.Errorf("Expected ErrNotMetered, got %v", err)
+		}
+		if _, err := meter.Consume("documents", 250); err != nil {
+			t.Fatalf("Consume failed: %v", err)
+		}
+
+		// Usage persists across meters
+		restarted := newMeter(t, paths...)
+		if remaining, err := restarted.Remaining("documents"); err != nil || remaining != 750 {
+			t.Errorf("Expected 750 documents remaining, got %d (%v)", remaining, err)
+		}
+		if remaining, err := restarted.Remaining("runs"); err != nil || remaining != 0 {
+			t.Errorf("Expected no runs remaining, got %d (%v)", remaining, err)
+		}
+
+		// Another application secret cannot read the journal
+		if _, err := licverify.NewUsageMeter(verifier, license, []byte("other"), paths...); !errors.Is(err, licverify.ErrUsageTampered) {
+			t.Errorf("Expected ErrUsageTampered, got %v", err)
+		}
+	})
+
+	t.Run("edited journal", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "usage.json")
+		meter := newMeter(t, path)
+		if _, err := meter.Consume("runs", 1); err != nil {
+			t.Fatalf("Consume failed: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		edited := []byte(string(data[:len(data)-1]) + `,"used":{"runs":0}}`)
+		if err := os.WriteFile(path, edited, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := meter.Remaining("runs"); !errors.Is(err, licverify.ErrUsageTampered) {
+			t.Errorf("Expected ErrUsageTampered, got %v", err)
+		}
+	})
+
+	t.Run("rolled back copy", func(t *testing.T) {
+		dir := t.TempDir()
+		paths := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+		meter := newMeter(t, paths...)
+		if _, err := meter.Consume("documents", 1); err != nil {
+			t.Fatalf("Consume failed: %v", err)
+		}
+		saved, err := os.ReadFile(paths[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range 2 {
+			if _, err := meter.Consume("documents", 100); err != nil {
+				t.Fatalf("Consume failed: %v", err)
+			}
+		}
+
+		// Restore one copy from a backup taken two writes earlier
+		if err := os.WriteFile(paths[0], saved, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := licverify.NewUsageMeter(verifier, license, secret, paths...); !errors.Is(err, licverify.ErrUsageTampered) {
+			t.Errorf("Expected ErrUsageTampered, got %v", err)
+		}
+	})
+
+	t.Run("deleted journal", func(t *testing.T) {
+		dir := t.TempDir()
+		paths := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+		meter := newMeter(t, paths...)
+		for range 2 {
+			if _, err := meter.Consume("documents", 10); err != nil {
+				t.Fatalf("Consume failed: %v", err)
+			}
+		}
+
+		// Deleting one copy is detected from the other
+		if err := os.Remove(paths[1]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := licverify.NewUsageMeter(verifier, license, secret, paths...); !errors.Is(err, licverify.ErrUsageTampered) {
+			t.Errorf("Expected ErrUsageTampered, got %v", err)
+		}
+
+		// Deleting every copy is detected by a running meter
+		if err := os.Remove(paths[0]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := meter.Consume("documents", 10); !errors.Is(err, licverify.ErrUsageTampered) {
+			t.Errorf("Expected ErrUsageTampered, got %v", err)
+		}
+	})
+
+	t.Run("anchored journal", func(t *testing.T) {
+		dir := t.TempDir()
+		paths := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+		anchor := memoryAnchor{}
+		meter, err := licverify.NewAnchoredUsageMeter(verifier, license, secret, anchor, paths...)
+		if err != nil {
+			t.Fatalf("Failed to create usage meter: %v", err)
+		}
+		for range 2 {
+			if _, err := meter.Consume("runs", 1); err != nil {
+				t.Fatalf("Consume failed: %v", err)
+			}
+		}
+		if anchor["LIC-1"] != 2 {
+			t.Errorf("Expected anchored sequence 2, got %d", anchor["LIC-1"])
+		}
+
+		// Deleting every copy between runs is detected from the anchor
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := licverify.NewAnchoredUsageMeter(verifier, license, secret, anchor, paths...); !errors.Is(err, licverify.ErrUsageTampered) {
+			t.Errorf("Expected ErrUsageTampered, got %v", err)
+		}
+
+		// Without the anchor the reset goes unnoticed
+		if remaining, err := newMeter(t, paths...).Remaining("runs"); err != nil || remaining != 3 {
+			t.Errorf("Expected an unanchored meter to start over, got %d, %v", remaining, err)
+		}
+	})
+
+	t.Run("file anchor", func(t *testing.T) {
+		journalDir, anchorDir := t.TempDir(), t.TempDir()
+		paths := []string{filepath.Join(journalDir, "a.json"), filepath.Join(journalDir, "b.json")}
+		anchorPath := filepath.Join(anchorDir, "usage-anchor.json")
+		meter, err := licverify.NewAnchoredUsageMeter(verifier, license, secret, licverify.NewFileUsageAnchor(anchorPath), paths...)
+		if err != nil {
+			t.Fatalf("Failed to create usage meter: %v", err)
+		}
+		if _, err := meter.Consume("runs", 1); err != nil {
+			t.Fatalf("Consume failed: %v", err)
+		}
+
+		// The anchor survives the process and detects a deleted journal
+		anchor := licverify.NewFileUsageAnchor(anchorPath)
+		if sequence, err := anchor.LoadSequence("LIC-1"); err != nil || sequence != 1 {
+			t.Errorf("Expected anchored sequence 1, got %d, %v", sequence, err)
+		}
+		if sequence, err := anchor.LoadSequence("LIC-OTHER"); err != nil || sequence != 0 {
+			t.Errorf("Expected no sequence for another license, got %d, %v", sequence, err)
+		}
+		if err := os.RemoveAll(journalDir); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := licverify.NewAnchoredUsageMeter(verifier, license, secret, anchor, paths...); !errors.Is(err, licverify.ErrUsageTampered) {
+			t.Errorf("Expected ErrUsageTampered, got %v", err)
+		}
+
+		// A corrupted anchor is reported rather than treated as empty
+		if err := os.WriteFile(anchorPath, []byte("{"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := anchor.LoadSequence("LIC-1"); !errors.Is(err, licverify.ErrUsageTampered) {
+			t.Errorf("Expected ErrUsageTampered for a corrupted anchor, got %v", err)
+		}
+	})
+
+	t.Run("not metered", func(t *testing.T) {
+		data, err := licgen.GenerateLicense("LIC-2", "ACME", "APP", "SN-2", 24*time.Hour,
+			nil, licverify.HardwareBinding{}, privateKey)
+		if err != nil {
+			t.Fatalf("Failed to generate license: %v", err)
+		}
+		plain, err := verifier.ParseLicense(data)
+		if err != nil {
+			t.Fatalf("Failed to parse license: %v", err)
+		}
+		if _, err := licverify.NewUsageMeter(verifier, plain, secret, filepath.Join(t.TempDir(), "usage.json")); err == nil {
+			t.Error("Expected error for a license without quotas")
+		}
+	})
+}
+
+// memoryAnchor is a UsageAnchor kept in memory
+type memoryAnchor map[string]uint64
+
+func (a memoryAnchor) LoadSequence(licenseID string) (uint64, error) {
+	return a[licenseID], nil
+}
+
+func (a memoryAnchor) StoreSequence(licenseID string, sequence uint64) error {
+	a[licenseID] = sequence
+	return nil
+}
@@ -0,0 +1,277 @@
+package licverify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Watcher keeps a long-running service up to date with its license file.
+// It polls the file, which works on every platform without cgo or file
+// system notifications, re-verifies the current license on every poll and
+// reports changes through callbacks. Callbacks run on the goroutine that
+// called Run, one at a time.
+
+// WatcherOption configures optional behaviour of a Watcher
+type WatcherOption func(*Watcher)
+
+// WithPollInterval sets how often the license file is read and the current
+// license re-verified. The default is one minute.
+func WithPollInterval(interval time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.interval = interval
+	}
+}
+
+// WithExpiryWarning sets how long before expiry OnExpiringSoon is called.
+// The default is seven days.
+func WithExpiryWarning(warning time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.warning = warning
+	}
+}
+
+// WithTrialTracker accepts trial licenses, which are checked with tracker.
+// Their trial end takes the place of the expiry date. Without a tracker
+// trial licenses are refused, see License.IsValid.
+func WithTrialTracker(tracker *TrialTracker) WatcherOption {
+	return func(w *Watcher) {
+		w.trials = tracker
+	}
+}
+
+// OnExpiringSoon is called once per license when it enters the expiry
+// warning period, with the time left until it expires
+func OnExpiringSoon(fn func(license *License, remaining time.Duration)) WatcherOption {
+	return func(w *Watcher) {
+		w.onExpiringSoon = fn
+	}
+}
+
+// OnExpired is called once per license when it expires
+func OnExpired(fn func(license *License)) WatcherOption {
+	return func(w *Watcher) {
+		w.onExpired = fn
+	}
+}
+
+// OnReplaced is called when the license file was replaced by a valid
+// license, which becomes the current license
+func OnReplaced(fn func(previous, current *License)) WatcherOption {
+	return func(w *Watcher) {
+		w.onReplaced = fn
+	}
+}
+
+// OnInvalid is called when the license file cannot be read or holds a
+// license that does not verify, and when the current license stops
+// verifying, for example after a hardware change. It is called once per
+// change; the current license is kept.
+func OnInvalid(fn func(err error)) WatcherOption {
+	return func(w *Watcher) {
+		w.onInvalid = fn
+	}
+}
+
+// Watcher watches a license file, see NewWatcher
+type Watcher struct {
+	verifier *Verifier
+	path     string
+	interval time.Duration
+	warning  time.Duration
+	trials   *TrialTracker
+
+	onExpiringSoon func(*License, time.Duration)
+	onExpired      func(*License)
+	onReplaced     func(previous, current *License)
+	onInvalid      func(error)
+
+	mu      sync.Mutex
+	current *License
+
+	// State of the Run goroutine
+	data        []byte // The file contents last read
+	currentData []byte // The file contents of the current license
+	warned      bool   // OnExpiringSoon was called for the current license
+	expired     bool   // OnExpired was called for the current license
+	fileErr     error  // The error last reported for reading the file
+	validErr    error  // The error last reported for the current license
+}
+
+// NewWatcher loads the license file at path and verifies its signature,
+// hardware binding and expiry. Trial licenses are refused unless
+// WithTrialTracker is passed. Call Run to start watching.
+func NewWatcher(verifier *Verifier, path string, opts ...WatcherOption) (*Watcher, error) {
+	if verifier == nil {
+		return nil, errors.New("verifier cannot be nil")
+	}
+
+	w := &Watcher{
+		verifier: verifier,
+		path:     path,
+		interval: time.Minute,
+		warning:  7 * 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.interval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read license file: %v", err)
+	}
+	license, err := verifier.ParseLicense(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.validate(license); err != nil {
+		return nil, err
+	}
+
+	w.current = license
+	w.data, w.currentData = data, data
+	return w, nil
+}
+
+// Current returns the current license, the last one that verified. It may
+// have expired or stopped verifying since, as reported by the callbacks.
+// It is safe to call from any goroutine.
+func (w *Watcher) Current() *License {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Run polls the license file until ctx is cancelled and returns ctx.Err().
+// It must not be called more than once at a time.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.poll()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reloads a changed license file and re-verifies the current license
+func (w *Watcher) poll() {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		if w.fileErr == nil || w.fileErr.Error() != err.Error() {
+			w.fileErr = err
+			w.invalid(fmt.Errorf("failed to read license file: %v", err))
+		}
+	} else {
+		w.fileErr = nil
+		// Changed contents are checked once, restored contents are not a replacement
+		if !bytes.Equal(data, w.data) {
+			w.data = data
+			if !bytes.Equal(data, w.currentData) {
+				w.reload(data)
+			}
+		}
+	}
+
+	license := w.Current()
+	expiry, err := w.verify(license)
+	if err != nil {
+		if w.validErr == nil || w.validErr.Error() != err.Error() {
+			w.validErr = err
+			w.invalid(err)
+		}
+	} else {
+		w.validErr = nil
+	}
+
+	remaining := time.Until(expiry)
+	switch {
+	case remaining <= 0:
+		if !w.expired {
+			w.expired = true
+			if w.onExpired != nil {
+				w.onExpired(license)
+			}
+		}
+	case remaining <= w.warning:
+		if !w.warned {
+			w.warned = true
+			if w.onExpiringSoon != nil {
+				w.onExpiringSoon(license, remaining)
+			}
+		}
+	}
+}
+
+// reload replaces the current license with the license in data if it verifies
+func (w *Watcher) reload(data []byte) {
+	license, err := w.verifier.ParseLicense(data)
+	if err == nil {
+		err = w.validate(license)
+	}
+	if err != nil {
+		w.invalid(fmt.Errorf("license file was replaced by an invalid license: %w", err))
+		return
+	}
+
+	w.mu.Lock()
+	previous := w.current
+	w.current = license
+	w.mu.Unlock()
+
+	w.currentData = data
+	w.validErr = nil
+	w.warned, w.expired = false, false
+	if w.onReplaced != nil {
+		w.onReplaced(previous, license)
+	}
+}
+
+// validate performs all checks of a new license. Trial licenses are checked
+// with the trial tracker.
+func (w *Watcher) validate(license *License) error {
+	if license.IsTrial() && w.trials != nil {
+		_, err := w.trials.Check(license)
+		return err
+	}
+	return license.IsValid(w.verifier)
+}
+
+// verify checks the signature and hardware binding of the current license
+// and returns when it expires, the end of the trial for trial licenses.
+// Expiry is reported separately.
+func (w *Watcher) verify(license *License) (time.Time, error) {
+	if license.IsTrial() {
+		info, err := w.trials.Check(license)
+		if errors.Is(err, ErrTrialExpired) {
+			return info.EndsAt, nil
+		}
+		if err != nil {
+			return license.expiryDate, err
+		}
+		return info.EndsAt, nil
+	}
+	if err := w.verifier.VerifySignature(license); err != nil {
+		return license.expiryDate, err
+	}
+	return license.expiryDate, w.verifier.VerifyHardwareBinding(license)
+}
+
+// invalid calls OnInvalid
+func (w *Watcher) invalid(err error) {
+	if w.onInvalid != nil {
+		w.onInvalid(err)
+	}
+}
@@ -0,0 +1,201 @@
+package licverify_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luhtfiimanal/go-license/v2/pkg/licgen"
+	"github.com/luhtfiimanal/go-license/v2/pkg/licverify"
+)
+
+// TestWatcher tests hot reload and the watcher callbacks
+func TestWatcher(t *testing.T) {
+	privateKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+	privateKey, err := licgen.ParsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	verifier := licverify.NewVerifierFromPublicKey(&privateKey.PublicKey)
+
+	writeLicense := func(path, id string, validity time.Duration, key *rsa.PrivateKey) {
+		t.Helper()
+		data, err := licgen.GenerateLicense(id, "ACME", "APP", "SN-1", validity,
+			[]string{"basic"}, licverify.HardwareBinding{}, key)
+		if err != nil {
+			t.Fatalf("Failed to generate license: %v", err)
+		}
+		// Replace the file atomically, as a partial write would be reported as invalid
+		if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// receive waits for a callback
+	receive := func(t *testing.T, ch <-chan string, want string) {
+		t.Helper()
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Fatalf("Expected %s, got %s", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for %s", want)
+		}
+	}
+
+	t.Run("replaced and invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "license.lic")
+		writeLicense(path, "LIC-1", 30*24*time.Hour, privateKey)
+
+		events := make(chan string, 10)
+		watcher, err := licverify.NewWatcher(verifier, path,
+			licverify.WithPollInterval(10*time.Millisecond),
+			licverify.OnReplaced(func(previous, current *licverify.License) {
+				events <- "replaced " + previous.ID() + " " + current.ID()
+			}),
+			licverify.OnInvalid(func(err error) { events <- "invalid" }),
+			licverify.OnExpiringSoon(func(*licverify.License, time.Duration) { events <- "expiring" }),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create watcher: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- watcher.Run(ctx) }()
+
+		writeLicense(path, "LIC-2", 60*24*time.Hour, privateKey)
+		receive(t, events, "replaced LIC-1 LIC-2")
+		if watcher.Current().ID() != "LIC-2" {
+			t.Errorf("Expected current license LIC-2, got %s", watcher.Current().ID())
+		}
+
+		// A license signed by another key is reported once and not loaded
+		otherKeyPEM, _, err := licgen.GenerateKeyPair(2048)
+		if err != nil {
+			t.Fatalf("Failed to generate key pair: %v", err)
+		}
+		otherKey, err := licgen.ParsePrivateKey(otherKeyPEM)
+		if err != nil {
+			t.Fatalf("Failed to parse private key: %v", err)
+		}
+		writeLicense(path, "LIC-3", 60*24*time.Hour, otherKey)
+		receive(t, events, "invalid")
+
+		// A removed file is reported once
+		if err := os.Remove(path); err != nil {
+			t.Fatal(err)
+		}
+		receive(t, events, "invalid")
+		time.Sleep(50 * time.Millisecond)
+		if watcher.Current().ID() != "LIC-2" {
+			t.Errorf("Expected current license LIC-2, got %s", watcher.Current().ID())
+		}
+
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if len(events) != 0 {
+			t.Errorf("Unexpected callback: %s", <-events)
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "license.lic")
+		writeLicense(path, "LIC-1", 2*time.Second, privateKey)
+
+		events := make(chan string, 10)
+		watcher, err := licverify.NewWatcher(verifier, path,
+			licverify.WithPollInterval(10*time.Millisecond),
+			licverify.WithExpiryWarning(time.Hour),
+			licverify.OnExpiringSoon(func(license *licverify.License, remaining time.Duration) {
+				if license.ID() == "LIC-1" && remaining > 2*time.Second {
+					t.Errorf("Unexpected remaining time %s", remaining)
+				}
+				events <- "expiring " + license.ID()
+			}),
+			licverify.OnExpired(func(license *licverify.License) { events <- "expired " + license.ID() }),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create watcher: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- watcher.Run(ctx) }()
+		defer func() {
+			cancel()
+			<-done
+		}()
+
+		receive(t, events, "expiring LIC-1")
+		receive(t, events, "expired LIC-1")
+
+		// A renewed license resets the expiry callbacks
+		writeLicense(path, "LIC-2", 30*time.Minute, privateKey)
+		receive(t, events, "expiring LIC-2")
+	})
+
+	t.Run("trial", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "license.lic")
+		data, err := licgen.GenerateTrialLicense("TRIAL-1", "APP", time.Second, 365*24*time.Hour, []string{"basic"}, privateKey)
+		if err != nil {
+			t.Fatalf("Failed to generate trial license: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		// Trials are not accepted as full licenses
+		if _, err := licverify.NewWatcher(verifier, path); !errors.Is(err, licverify.ErrTrialNotTracked) {
+			t.Fatalf("Expected ErrTrialNotTracked, got %v", err)
+		}
+
+		// With a tracker the trial expires at the end of the trial period
+		tracker, err := licverify.NewTrialTracker(verifier, []byte("test application"), filepath.Join(t.TempDir(), "trial.json"))
+		if err != nil {
+			t.Fatalf("Failed to create trial tracker: %v", err)
+		}
+		events := make(chan string, 10)
+		watcher, err := licverify.NewWatcher(verifier, path,
+			licverify.WithTrialTracker(tracker),
+			licverify.WithPollInterval(10*time.Millisecond),
+			licverify.WithExpiryWarning(time.Hour),
+			licverify.OnExpiringSoon(func(license *licverify.License, remaining time.Duration) {
+				events <- "expiring " + license.ID()
+			}),
+			licverify.OnExpired(func(license *licverify.License) { events <- "expired " + license.ID() }),
+			licverify.OnInvalid(func(err error) { events <- "invalid: " + err.Error() }),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create watcher: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- watcher.Run(ctx) }()
+		defer func() {
+			cancel()
+			<-done
+		}()
+
+		receive(t, events, "expiring TRIAL-1")
+		receive(t, events, "expired TRIAL-1")
+	})
+
+	t.Run("invalid at start", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "license.lic")
+		if _, err := licverify.NewWatcher(verifier, path); err == nil {
+			t.Error("Expected error for a missing license file")
+		}
+	})
+}